@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/iteasy-ops-dev/syseng-agent/internal/agent"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/config"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +24,14 @@ var agentQueryCmd = &cobra.Command{
 		providerID, _ := cmd.Flags().GetString("provider")
 		interactive, _ := cmd.Flags().GetBool("interactive")
 
-		ag := agent.New(mcpManager, llmManager)
+		p, err := resolveProfile(cmd)
+		if err != nil {
+			fmt.Printf("Error loading profile: %v\n", err)
+			return
+		}
+
+		ag := newAgent()
+		ag.SetProfile(p)
 
 		response, err := ag.ProcessRequestWithUI(args[0], mcpServerID, providerID, interactive)
 		if err != nil {
@@ -44,7 +53,7 @@ var agentServeCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		port, _ := cmd.Flags().GetString("port")
 
-		ag := agent.New(mcpManager, llmManager)
+		ag := newAgent()
 
 		fmt.Printf("Starting agent server on port %s...\n", port)
 		if err := ag.StartServer(port); err != nil {
@@ -53,6 +62,40 @@ var agentServeCmd = &cobra.Command{
 	},
 }
 
+// newAgent creates an agent configured from the loaded config file
+func newAgent() *agent.Agent {
+	ag := agent.New(mcpManager, llmManager)
+
+	// A chain set with 'llm fallback set' takes precedence over the config file
+	fallback := llmManager.FallbackChain()
+
+	cfg, err := config.Current()
+	if err != nil {
+		fmt.Printf("Warning: failed to load config, using defaults: %v\n", err)
+		ag.SetFallbackProviders(fallback)
+		return ag
+	}
+
+	if len(fallback) == 0 {
+		fallback = cfg.Agent.Fallback
+	}
+	ag.SetFallbackProviders(fallback)
+
+	ag.SetToolOutputLimits(agent.ToolOutputLimits{
+		MaxBytes:  cfg.ToolOutput.MaxBytes,
+		HeadBytes: cfg.ToolOutput.HeadBytes,
+		TailBytes: cfg.ToolOutput.TailBytes,
+		PerTool:   cfg.ToolOutput.Limits,
+	})
+	ag.SetPromptOverrides(agent.PromptOverrides{
+		System:             cfg.Prompt.System,
+		ServerInstructions: cfg.Prompt.ServerInstructions,
+	})
+	ag.SetPrices(llm.NewPriceTable(cfg.Usage.Prices))
+
+	return ag
+}
+
 func init() {
 	rootCmd.AddCommand(agentCmd)
 	agentCmd.AddCommand(agentQueryCmd)
@@ -61,6 +104,7 @@ func init() {
 	agentQueryCmd.Flags().String("mcp-server", "", "MCP server ID to use")
 	agentQueryCmd.Flags().String("provider", "", "LLM provider ID to use")
 	agentQueryCmd.Flags().BoolP("interactive", "i", false, "Enable interactive mode for tool execution approval")
+	agentQueryCmd.Flags().String("profile", "", "Profile to use (default: active profile)")
 
 	agentServeCmd.Flags().String("port", "8080", "Port to serve on")
 }
@@ -1,9 +1,12 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
@@ -29,23 +32,37 @@ Use 'exit', 'quit', or Ctrl+C to end the session.`,
 		interactive, _ := cmd.Flags().GetBool("interactive")
 		tui, _ := cmd.Flags().GetBool("tui")
 
+		p, err := resolveProfile(cmd)
+		if err != nil {
+			fmt.Printf("Error loading profile: %v\n", err)
+			return
+		}
+
+		// Records the end of the health checks in the status history
+		defer mcpManager.Shutdown()
+
 		if tui {
-			startTUIChat(mcpServerID, providerID, interactive)
+			startTUIChat(mcpServerID, providerID, interactive, p)
 		} else {
-			startBasicChat(mcpServerID, providerID, interactive)
+			startBasicChat(mcpServerID, providerID, interactive, p)
 		}
 	},
 }
 
-func startBasicChat(mcpServerID, providerID string, interactive bool) {
+func startBasicChat(mcpServerID, providerID string, interactive bool, p *types.Profile) {
 	fmt.Println("🤖 Starting chat session with AI agent...")
+	if p != nil {
+		fmt.Printf("📂 Profile: %s\n", p.Name)
+	}
 	fmt.Println("Type 'exit', 'quit', or press Ctrl+C to end the session.")
 	fmt.Println("Type 'help' for available commands.")
 	fmt.Println("Use '\\n' in your message for line breaks.")
 	fmt.Println(strings.Repeat("=", 60))
 
-	ag := agent.New(mcpManager, llmManager)
-	scanner := bufio.NewScanner(os.Stdin)
+	ag := newAgent()
+	ag.SetProfile(p)
+	// Tab completes the chat commands
+	reader := ui.NewLineReader(ag.CompleteChatCommand)
 
 	// Create conversation session
 	session := &types.ConversationSession{
@@ -71,13 +88,15 @@ func startBasicChat(mcpServerID, providerID string, interactive bool) {
 	}
 
 	for {
-		fmt.Print("\n💬 You: ")
-		
-		if !scanner.Scan() {
-			break // EOF or error
+		raw, err := reader.ReadLine("\n💬 You: ")
+		if err != nil {
+			if err != io.EOF {
+				fmt.Printf("Error reading input: %v\n", err)
+			}
+			break
 		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(raw)
 		
 		// Handle special commands
 		switch strings.ToLower(input) {
@@ -107,16 +126,36 @@ func startBasicChat(mcpServerID, providerID string, interactive bool) {
 		// Process multi-line input (simple implementation)
 		message := strings.ReplaceAll(input, "\\n", "\n")
 
+		if agent.IsChatCommand(input) {
+			command, err := agent.ParseChatCommand(input)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				continue
+			}
+
+			result, err := ag.RunChatCommand(command, display)
+			if err != nil {
+				fmt.Printf("❌ Error running %s: %v\n", command.Kind, err)
+				continue
+			}
+
+			if result.Message == "" {
+				fmt.Printf("\n%s\n", result.Output)
+				continue
+			}
+
+			// Prompt templates are sent to the model as the user's message
+			fmt.Printf("📝 Prompt %s:\n%s\n", command.Name, result.Message)
+			message = result.Message
+		}
+
 		fmt.Printf("🔄 Processing your request...\n")
 
+		// While the request runs, Ctrl+C cancels it instead of ending the session
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
 		// Check if we can use streaming
-		var provider *types.LLMProvider
-		var err error
-		if session.ProviderID != "" {
-			provider, err = llmManager.GetProvider(session.ProviderID)
-		} else {
-			provider, err = llmManager.GetActiveProvider()
-		}
+		provider, err := ag.ResolveProvider(session.ProviderID)
 		
 		if err == nil && provider != nil {
 			// Try streaming if supported
@@ -127,23 +166,41 @@ func startBasicChat(mcpServerID, providerID string, interactive bool) {
 				}
 				
 				fmt.Printf("🔄 Using streaming for provider: %s (%s)\n", provider.Name, provider.Type)
-				err = processWithStreaming(ag, session, message, display)
-				if err != nil {
-					fmt.Printf("❌ Streaming error: %v\n", err)
-					fmt.Printf("🔄 Falling back to non-streaming...\n")
-					// Don't continue here, fall through to non-streaming
-				} else {
+				err = processWithStreaming(ctx, ag, session, message, provider, display)
+				if err == nil {
+					stop()
 					continue
 				}
+				if ctx.Err() != nil {
+					stop()
+					fmt.Println("\n⏹️  Request cancelled")
+					continue
+				}
+				// A turn is only repeated if it had no visible effect yet
+				var startErr *streamStartError
+				if !errors.As(err, &startErr) {
+					stop()
+					continue
+				}
+				fmt.Printf("❌ Streaming error: %v\n", err)
+				fmt.Printf("🔄 Falling back to non-streaming...\n")
+				// Don't continue here, fall through to non-streaming
 			}
 		}
 
 		// Fall back to non-streaming
-		response, err := ag.ProcessConversation(session, message, display)
+		response, err := ag.ProcessConversationContext(ctx, session, message, display)
+		cancelled := ctx.Err() != nil && (err != nil || response.Error != "")
+		stop()
 		
 		// Clear any remaining progress indicators by printing newline
 		fmt.Print("\r\033[K")  // Clear current line
 		
+		if cancelled {
+			fmt.Println("⏹️  Request cancelled")
+			continue
+		}
+		
 		if err != nil {
 			fmt.Printf("❌ Error processing request: %v\n", err)
 			continue
@@ -151,25 +208,30 @@ func startBasicChat(mcpServerID, providerID string, interactive bool) {
 
 		fmt.Printf("\n🤖 Agent: %s\n", response.Message)
 
+		if _, fellBack := response.Data["fallback_from"]; fellBack {
+			fmt.Printf("↪️  Answered by fallback provider %v\n", response.Data["provider_name"])
+		}
+
+		if usage, ok := response.Data["usage"].(types.UsageTotals); ok {
+			printUsage(usage, session)
+		}
+
 		if response.Error != "" {
 			fmt.Printf("⚠️  Warning: %s\n", response.Error)
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading input: %v\n", err)
-	}
 }
 
-func startTUIChat(mcpServerID, providerID string, interactive bool) {
-	ag := agent.New(mcpManager, llmManager)
+func startTUIChat(mcpServerID, providerID string, interactive bool, p *types.Profile) {
+	ag := newAgent()
+	ag.SetProfile(p)
 	
 	fmt.Println("🚀 Starting TUI chat interface...")
 	err := tui.StartTUIChat(ag, mcpServerID, providerID, interactive)
 	if err != nil {
 		fmt.Printf("❌ Error starting TUI chat: %v\n", err)
 		fmt.Println("🔄 Falling back to basic chat mode...")
-		startBasicChat(mcpServerID, providerID, interactive)
+		startBasicChat(mcpServerID, providerID, interactive, p)
 	}
 }
 
@@ -178,10 +240,18 @@ func showChatHelp() {
 	fmt.Println("  help, h     - Show this help message")
 	fmt.Println("  exit, quit  - End the chat session")
 	fmt.Println("  clear, cls  - Clear the screen")
+	fmt.Println("  Ctrl+C      - Cancel the running request")
 	fmt.Println("  \\n          - Insert line break in message")
+	fmt.Println("\n🔧 MCP Commands:")
+	fmt.Println("  /tool <server> <tool> [arg=value ...]            - Call a tool directly")
+	fmt.Println("  /prompt <server> <prompt> [arg=value ...]        - Send a server prompt template")
+	fmt.Println("  /resource <server> <uri-template> [arg=value ...] - Read a resource")
+	fmt.Println("  Tab                                              - Complete servers, names and arguments")
+	fmt.Println("  Quote values with spaces, e.g. path=\"/srv/my files\"")
 	fmt.Println("\n💡 Tips:")
 	fmt.Println("  - Use the -i flag for interactive tool approval")
 	fmt.Println("  - Specify --provider or --mcp-server for specific resources")
+	fmt.Println("  - Use --profile to switch server groups and tool rules")
 	fmt.Println("  - Messages support multi-line input with \\n")
 }
 
@@ -194,39 +264,62 @@ func checkStreamingSupport(provider *types.LLMProvider) bool {
 	// For now, we'll check based on provider type
 	// In a full implementation, we'd check capabilities from the client
 	switch provider.Type {
-	case "openai", "anthropic", "local":
+	case "openai", "anthropic", "google", "local":
 		return true
 	default:
 		return false
 	}
 }
 
-// processWithStreaming handles streaming response from LLM
-func processWithStreaming(ag *agent.Agent, session *types.ConversationSession, message string, display ui.ToolDisplayInterface) error {
+// processWithStreaming handles streaming response from LLM. provider is the
+// provider resolved for the turn, before any fallback.
+func processWithStreaming(ctx context.Context, ag *agent.Agent, session *types.ConversationSession, message string, provider *types.LLMProvider, display ui.ToolDisplayInterface) error {
 	// Use the new Agent streaming method that includes tool support
-	streamCh, err := ag.ProcessConversationWithStreaming(session, message, display)
+	streamCh, err := ag.ProcessConversationWithStreamingContext(ctx, session, message, display)
 	if err != nil {
-		return fmt.Errorf("failed to start streaming: %v", err)
+		return &streamStartError{fmt.Errorf("failed to start streaming: %v", err)}
 	}
 	
-	// Clear processing message and start streaming output
-	fmt.Print("\r\033[K")  // Clear current line
-	fmt.Print("🤖 Agent: ")
-	
+	// Tools may run before the model starts answering, so the prefix is
+	// printed with the first text
 	var fullResponse strings.Builder
 	for chunk := range streamCh {
 		if chunk.Error != "" {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err := fmt.Errorf("streaming error: %s", chunk.Error)
+			if !chunk.ToolsRan && fullResponse.Len() == 0 {
+				return &streamStartError{err}
+			}
 			fmt.Printf("\n❌ Error: %s\n", chunk.Error)
-			return fmt.Errorf("streaming error: %s", chunk.Error)
+			if chunk.Usage != nil {
+				printUsage(*chunk.Usage, session)
+			}
+			return err
 		}
 		
 		if chunk.Content != "" {
+			if fullResponse.Len() == 0 {
+				// Clear processing message and start streaming output
+				fmt.Print("\r\033[K") // Clear current line
+				fmt.Print("🤖 Agent: ")
+			}
 			fmt.Print(chunk.Content)
 			fullResponse.WriteString(chunk.Content)
 		}
 		
 		if chunk.Done {
 			fmt.Println() // New line after response
+			if stoppedEarly(chunk.StopReason) {
+				fmt.Printf("⚠️  The response was cut short (%s)\n", chunk.StopReason)
+			}
+			if fallbackUsed(provider, chunk.Provider) {
+				fmt.Printf("↪️  Answered by fallback provider %s\n", chunk.Provider)
+			}
+			if chunk.Usage != nil {
+				printUsage(*chunk.Usage, session)
+			}
 			break
 		}
 	}
@@ -234,6 +327,40 @@ func processWithStreaming(ag *agent.Agent, session *types.ConversationSession, m
 	return nil
 }
 
+// streamStartError is a streaming failure before any tool ran or any of the
+// answer was shown, so the turn can be repeated without streaming
+type streamStartError struct {
+	err error
+}
+
+func (e *streamStartError) Error() string { return e.err.Error() }
+func (e *streamStartError) Unwrap() error { return e.err }
+
+// printUsage prints the tokens and cost of a request and of the session so far
+func printUsage(usage types.UsageTotals, session *types.ConversationSession) {
+	if usage.Calls == 0 {
+		return
+	}
+	fmt.Printf("📊 %s (session: %s)\n", usage.Summary(), session.Usage.Summary())
+}
+
+// fallbackUsed reports whether the named provider that answered is not the
+// one resolved for the turn
+func fallbackUsed(resolved *types.LLMProvider, providerName string) bool {
+	return providerName != "" && resolved != nil && resolved.Name != providerName
+}
+
+// stoppedEarly reports whether the model ended its answer before finishing
+// it, e.g. because it ran out of output tokens
+func stoppedEarly(stopReason string) bool {
+	switch stopReason {
+	case "", "end_turn", "stop_sequence", "tool_use", "stop":
+		return false
+	default:
+		return true
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(chatCmd)
 	
@@ -241,4 +368,5 @@ func init() {
 	chatCmd.Flags().String("provider", "", "LLM provider ID to use")
 	chatCmd.Flags().BoolP("interactive", "i", false, "Enable interactive mode for tool execution approval")
 	chatCmd.Flags().Bool("tui", false, "Use Terminal UI mode (requires bubbletea)")
+	chatCmd.Flags().String("profile", "", "Profile to use (default: active profile)")
 }
\ No newline at end of file
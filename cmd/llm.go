@@ -55,6 +55,9 @@ var llmAddCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		apiKey, _ := cmd.Flags().GetString("api-key")
 		endpoint, _ := cmd.Flags().GetString("endpoint")
+		nativeTools, _ := cmd.Flags().GetString("native-tools")
+		maxRetries, _ := cmd.Flags().GetInt("max-retries")
+		contextWindow, _ := cmd.Flags().GetInt("context-window")
 
 		provider := &types.LLMProvider{
 			Name:     args[0],
@@ -64,6 +67,40 @@ var llmAddCmd = &cobra.Command{
 			Endpoint: endpoint,
 		}
 
+		switch nativeTools {
+		case "":
+			// Detected from the model
+		case "on", "off":
+			provider.Config = map[string]interface{}{
+				llm.ConfigNativeTools: nativeTools == "on",
+			}
+		default:
+			fmt.Printf("Error adding provider: --native-tools must be on or off\n")
+			return
+		}
+
+		if cmd.Flags().Changed("max-retries") {
+			if maxRetries < 0 {
+				fmt.Printf("Error adding provider: --max-retries must not be negative\n")
+				return
+			}
+			if provider.Config == nil {
+				provider.Config = map[string]interface{}{}
+			}
+			provider.Config[llm.ConfigMaxRetries] = maxRetries
+		}
+
+		if cmd.Flags().Changed("context-window") {
+			if contextWindow <= 0 {
+				fmt.Printf("Error adding provider: --context-window must be positive\n")
+				return
+			}
+			if provider.Config == nil {
+				provider.Config = map[string]interface{}{}
+			}
+			provider.Config[llm.ConfigContextWindow] = contextWindow
+		}
+
 		if err := llmManager.AddProvider(provider); err != nil {
 			fmt.Printf("Error adding provider: %v\n", err)
 			return
@@ -125,6 +162,62 @@ var llmSetActiveCmd = &cobra.Command{
 	},
 }
 
+var llmFallbackCmd = &cobra.Command{
+	Use:   "fallback",
+	Short: "Manage the provider fallback chain",
+	Long: `Manage the providers a turn moves on to, in order, when its provider
+is rate limited, unavailable, rejects the API key or cannot be reached.
+A chain set here takes precedence over agent.fallback in the config file.`,
+}
+
+var llmFallbackSetCmd = &cobra.Command{
+	Use:   "set [provider...]",
+	Short: "Set the fallback providers, by ID or name, in the order they are tried",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := llmManager.SetFallbackChain(args); err != nil {
+			fmt.Printf("Error setting fallback chain: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Fallback chain set to %d provider(s)\n", len(args))
+	},
+}
+
+var llmFallbackShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the fallback chain",
+	Run: func(cmd *cobra.Command, args []string) {
+		chain := llmManager.FallbackChain()
+		if len(chain) == 0 {
+			fmt.Println("No fallback chain set")
+			return
+		}
+
+		for i, id := range chain {
+			provider, err := llmManager.GetProvider(id)
+			if err != nil {
+				fmt.Printf("%d. %s (not found)\n", i+1, id)
+				continue
+			}
+			fmt.Printf("%d. %s (%s, %s) %s\n", i+1, provider.Name, provider.Type, provider.Model, provider.ID)
+		}
+	},
+}
+
+var llmFallbackClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the fallback chain",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := llmManager.SetFallbackChain(nil); err != nil {
+			fmt.Printf("Error clearing fallback chain: %v\n", err)
+			return
+		}
+
+		fmt.Println("Fallback chain cleared")
+	},
+}
+
 func init() {
 	llmManager = llm.NewManager()
 
@@ -134,7 +227,14 @@ func init() {
 	llmCmd.AddCommand(llmRemoveCmd)
 	llmCmd.AddCommand(llmShowCmd)
 	llmCmd.AddCommand(llmSetActiveCmd)
+	llmCmd.AddCommand(llmFallbackCmd)
+	llmFallbackCmd.AddCommand(llmFallbackSetCmd)
+	llmFallbackCmd.AddCommand(llmFallbackShowCmd)
+	llmFallbackCmd.AddCommand(llmFallbackClearCmd)
 
 	llmAddCmd.Flags().String("api-key", "", "API key for the provider")
 	llmAddCmd.Flags().String("endpoint", "", "Endpoint URL for local providers")
+	llmAddCmd.Flags().String("native-tools", "", "Native tool calling for local providers: on or off (default: detect for Ollama)")
+	llmAddCmd.Flags().Int("max-retries", llm.DefaultMaxRetries, "Retries of requests failing with a rate limit, server error or connection error (0 disables retrying)")
+	llmAddCmd.Flags().Int("context-window", 0, "Tokens the model accepts (default: known limit of the model)")
 }
@@ -4,9 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/iteasy-ops-dev/syseng-agent/internal/mcp"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 	"github.com/spf13/cobra"
 )
@@ -26,14 +32,15 @@ var mcpListCmd = &cobra.Command{
 		servers := mcpManager.ListServers()
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tNAME\tURL\tTRANSPORT\tSTATUS\tLAST_PING")
+		fmt.Fprintln(w, "ID\tNAME\tURL\tTRANSPORT\tENABLED\tSTATUS\tLAST_PING")
 
 		for _, server := range servers {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\n",
 				server.ID,
 				server.Name,
 				server.URL,
 				server.Transport,
+				server.IsEnabled(),
 				server.Status,
 				server.LastPing.Format("15:04:05"),
 			)
@@ -54,15 +61,106 @@ var mcpAddCmd = &cobra.Command{
 			Transport: args[2],
 		}
 
+		sandbox, err := sandboxFromFlags(cmd)
+		if err != nil {
+			fmt.Printf("Error parsing sandbox flags: %v\n", err)
+			return
+		}
+		server.Sandbox = sandbox
+
+		if err := credentialsFromFlags(cmd, server); err != nil {
+			fmt.Printf("Error parsing credential flags: %v\n", err)
+			return
+		}
+
 		if err := mcpManager.AddServer(server); err != nil {
 			fmt.Printf("Error adding server: %v\n", err)
 			return
 		}
 
+		if server.Status == "error" {
+			fmt.Printf("Server %s added with ID: %s, but it is not available\n", server.Name, server.ID)
+			return
+		}
+
 		fmt.Printf("Server %s added successfully with ID: %s\n", server.Name, server.ID)
 	},
 }
 
+// sandboxFromFlags builds the sandbox settings of mcp add, or nil when no
+// sandbox flag was given
+func sandboxFromFlags(cmd *cobra.Command) (*types.MCPSandbox, error) {
+	sandboxFlags := []string{"env-allow", "env", "workdir", "cpu-seconds", "memory-mb", "max-open-files", "max-procs", "uid", "gid"}
+	enabled, _ := cmd.Flags().GetBool("sandbox")
+	for _, name := range sandboxFlags {
+		if cmd.Flags().Changed(name) {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	sandbox := &types.MCPSandbox{}
+	sandbox.EnvAllow, _ = cmd.Flags().GetStringSlice("env-allow")
+	sandbox.WorkDir, _ = cmd.Flags().GetString("workdir")
+	sandbox.CPUSeconds, _ = cmd.Flags().GetInt("cpu-seconds")
+	sandbox.MemoryMB, _ = cmd.Flags().GetInt("memory-mb")
+	sandbox.OpenFiles, _ = cmd.Flags().GetInt("max-open-files")
+	sandbox.Processes, _ = cmd.Flags().GetInt("max-procs")
+
+	env, _ := cmd.Flags().GetStringArray("env")
+	for _, entry := range env {
+		name, value, found := strings.Cut(entry, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid --env %q (expected KEY=VALUE)", entry)
+		}
+		if sandbox.Env == nil {
+			sandbox.Env = make(map[string]string)
+		}
+		sandbox.Env[name] = value
+	}
+
+	if cmd.Flags().Changed("uid") {
+		uid, _ := cmd.Flags().GetUint32("uid")
+		sandbox.UID = &uid
+	}
+	if cmd.Flags().Changed("gid") {
+		gid, _ := cmd.Flags().GetUint32("gid")
+		sandbox.GID = &gid
+	}
+
+	return sandbox, nil
+}
+
+// credentialsFromFlags sets the headers and token variable of mcp add,
+// which only apply to remote servers
+func credentialsFromFlags(cmd *cobra.Command, server *types.MCPServer) error {
+	headers, _ := cmd.Flags().GetStringArray("header")
+	tokenEnv, _ := cmd.Flags().GetString("token-env")
+	if len(headers) == 0 && tokenEnv == "" {
+		return nil
+	}
+
+	if server.Transport == "stdio" {
+		return fmt.Errorf("--header and --token-env only apply to http, sse and websocket servers")
+	}
+
+	for _, header := range headers {
+		name, value, err := mcp.ParseHeader(header)
+		if err != nil {
+			return err
+		}
+		if server.Headers == nil {
+			server.Headers = make(map[string]string)
+		}
+		server.Headers[name] = value
+	}
+	server.TokenEnv = tokenEnv
+
+	return nil
+}
+
 var mcpRemoveCmd = &cobra.Command{
 	Use:   "remove [id]",
 	Short: "Remove an MCP server",
@@ -77,6 +175,43 @@ var mcpRemoveCmd = &cobra.Command{
 	},
 }
 
+var mcpEnableCmd = &cobra.Command{
+	Use:   "enable [id]",
+	Short: "Enable a disabled MCP server",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setServerEnabled(args[0], true)
+	},
+}
+
+var mcpDisableCmd = &cobra.Command{
+	Use:   "disable [id]",
+	Short: "Stop offering an MCP server's tools without removing it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setServerEnabled(args[0], false)
+	},
+}
+
+func setServerEnabled(idOrName string, enabled bool) {
+	server, err := mcpManager.ResolveServer(idOrName)
+	if err != nil {
+		fmt.Printf("Error getting server: %v\n", err)
+		return
+	}
+
+	if err := mcpManager.SetServerEnabled(server.ID, enabled); err != nil {
+		fmt.Printf("Error updating server: %v\n", err)
+		return
+	}
+
+	if enabled {
+		fmt.Printf("Server %s enabled\n", server.Name)
+	} else {
+		fmt.Printf("Server %s disabled\n", server.Name)
+	}
+}
+
 var mcpShowCmd = &cobra.Command{
 	Use:   "show [id]",
 	Short: "Show details of an MCP server",
@@ -88,16 +223,112 @@ var mcpShowCmd = &cobra.Command{
 			return
 		}
 
-		data, err := json.MarshalIndent(server, "", "  ")
+		data, err := json.MarshalIndent(server.Masked(), "", "  ")
 		if err != nil {
 			fmt.Printf("Error formatting server data: %v\n", err)
 			return
 		}
 
 		fmt.Println(string(data))
+
+		if server.ProtocolVersion != "" {
+			printServerProtocol(server)
+		}
+
+		if server.Transport == "http" || server.Transport == "sse" {
+			printServerAuthorization(server)
+		}
+
+		if features, _ := cmd.Flags().GetBool("features"); features {
+			printServerFeatures(server)
+		}
 	},
 }
 
+// mcpFeatures are the optional server features shown by `mcp show`
+var mcpFeatures = []string{"tools", "resources", "prompts", "logging", "completions"}
+
+// printServerProtocol prints the negotiated protocol details of a server
+func printServerProtocol(server *types.MCPServer) {
+	fmt.Printf("\nProtocol version: %s\n", server.ProtocolVersion)
+	if server.ServerInfo != nil {
+		fmt.Printf("Server: %s %s\n", server.ServerInfo.Name, server.ServerInfo.Version)
+	}
+
+	var features []string
+	for _, feature := range mcpFeatures {
+		mark := "✗"
+		if server.HasCapability(feature) {
+			mark = "✓"
+		}
+		features = append(features, fmt.Sprintf("%s %s", mark, feature))
+	}
+	fmt.Printf("Capabilities: %s\n", strings.Join(features, "  "))
+}
+
+// printServerAuthorization prints the OAuth state of a remote server without
+// revealing its tokens
+func printServerAuthorization(server *types.MCPServer) {
+	credentials, err := mcpManager.ServerAuthorization(server.ID)
+	if err != nil {
+		fmt.Printf("Error loading credentials: %v\n", err)
+		return
+	}
+
+	if credentials == nil {
+		fmt.Println("Authorization: none")
+		return
+	}
+
+	fmt.Printf("Authorization: OAuth via %s (client %s)\n", credentials.AuthorizationServer, credentials.ClientID)
+	if credentials.Scope != "" {
+		fmt.Printf("Scope: %s\n", credentials.Scope)
+	}
+	switch {
+	case credentials.ExpiresAt.IsZero():
+		fmt.Println("Token expires: never")
+	case credentials.Expired() && credentials.RefreshToken != "":
+		fmt.Printf("Token expired at %s, it is refreshed on the next request\n", credentials.ExpiresAt.Format(time.RFC3339))
+	case credentials.Expired():
+		fmt.Printf("Token expired at %s, run 'syseng-agent mcp login %s'\n", credentials.ExpiresAt.Format(time.RFC3339), server.ID)
+	default:
+		fmt.Printf("Token expires: %s\n", credentials.ExpiresAt.Format(time.RFC3339))
+	}
+}
+
+// printServerFeatures lists the resources and prompts a server exposes
+func printServerFeatures(server *types.MCPServer) {
+	if server.HasCapability("resources") {
+		resources, err := mcpManager.ListServerResources(server.ID)
+		if err != nil {
+			fmt.Printf("Error listing resources: %v\n", err)
+		} else {
+			fmt.Printf("\nResources (%d):\n", len(resources))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "URI\tNAME\tDESCRIPTION")
+			for _, resource := range resources {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", resource.URI, resource.Name, resource.Description)
+			}
+			w.Flush()
+		}
+	}
+
+	if server.HasCapability("prompts") {
+		prompts, err := mcpManager.ListServerPrompts(server.ID)
+		if err != nil {
+			fmt.Printf("Error listing prompts: %v\n", err)
+		} else {
+			fmt.Printf("\nPrompts (%d):\n", len(prompts))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PROMPT\tDESCRIPTION")
+			for _, prompt := range prompts {
+				fmt.Fprintf(w, "%s\t%s\n", prompt.Name, prompt.Description)
+			}
+			w.Flush()
+		}
+	}
+}
+
 var mcpToolsCmd = &cobra.Command{
 	Use:   "tools [server-id]",
 	Short: "List available tools for an MCP server",
@@ -154,7 +385,8 @@ var mcpCallCmd = &cobra.Command{
 			}
 		}
 
-		result, err := mcpManager.CallTool(serverID, toolName, arguments)
+		// Input the server requests during the call is asked for on the terminal
+		result, err := mcpManager.CallToolWithElicitation(serverID, toolName, arguments, ui.NewInteractiveDisplay().PromptElicitation)
 		if err != nil {
 			fmt.Printf("Error calling tool: %v\n", err)
 			return
@@ -170,6 +402,352 @@ var mcpCallCmd = &cobra.Command{
 	},
 }
 
+var mcpCompleteCmd = &cobra.Command{
+	Use:   "complete [server] [ref] [argument] [prefix]",
+	Short: "Complete an argument of a tool, prompt or resource template",
+	Long: `Ask an MCP server for completions of an argument value.
+
+The reference is one of:
+  prompt:<name>            a prompt template (completed by the server)
+  resource:<uri-template>  a resource template (completed by the server)
+  tool:<name>              a tool (completed from the enum and default values in its schema)
+
+The server may be given by ID or name.`,
+	Args: cobra.RangeArgs(3, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		server, err := mcpManager.ResolveServer(args[0])
+		if err != nil {
+			fmt.Printf("Error getting server: %v\n", err)
+			return
+		}
+
+		ref, err := mcp.ParseCompletionRef(args[1])
+		if err != nil {
+			fmt.Printf("Error parsing reference: %v\n", err)
+			return
+		}
+
+		prefix := ""
+		if len(args) > 3 {
+			prefix = args[3]
+		}
+
+		result, err := mcpManager.Complete(server.ID, ref, args[2], prefix, nil)
+		if err != nil {
+			fmt.Printf("Error completing argument: %v\n", err)
+			return
+		}
+
+		for _, value := range result.Values {
+			fmt.Println(value)
+		}
+		if result.HasMore {
+			fmt.Printf("... (%d total)\n", result.Total)
+		}
+	},
+}
+
+var mcpDoctorCmd = &cobra.Command{
+	Use:   "doctor [server]",
+	Short: "Diagnose the connection to MCP servers step by step",
+	Long: `Run the connection handshake with a server one step at a time: spawn,
+initialize, protocol version, capabilities, tools/list and schema sanity.
+Reports timings, the server's stderr and a suggested fix for the failing step.
+
+Without a server, all servers are diagnosed. The server may be given by
+ID or name.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var servers []*types.MCPServer
+		if len(args) > 0 {
+			server, err := mcpManager.ResolveServer(args[0])
+			if err != nil {
+				fmt.Printf("Error getting server: %v\n", err)
+				return
+			}
+			servers = append(servers, server)
+		} else {
+			servers = mcpManager.ListServers()
+			sort.Slice(servers, func(i, j int) bool {
+				return servers[i].Name < servers[j].Name
+			})
+		}
+
+		if len(servers) == 0 {
+			fmt.Println("No MCP servers to diagnose")
+			return
+		}
+
+		for i, server := range servers {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			fmt.Printf("🩺 Diagnosing %s (%s)\n", server.Name, server.ID)
+			report, err := mcpManager.Doctor(server.ID)
+			if err != nil {
+				fmt.Printf("Error diagnosing server: %v\n", err)
+				continue
+			}
+			printDoctorReport(report)
+		}
+	},
+}
+
+var mcpStatusCmd = &cobra.Command{
+	Use:   "status [id]",
+	Short: "Show the status of an MCP server and its history",
+	Long: `Show the current status of a server. With --history, also show the uptime
+percentage, the latest failures with their reasons, the mean time to recover
+and the latest status transitions recorded in the data directory.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		server, err := mcpManager.ResolveServer(args[0])
+		if err != nil {
+			fmt.Printf("Error getting server: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Server: %s (%s)\n", server.Name, server.ID)
+		fmt.Printf("Status: %s\n", server.Status)
+		if !server.IsEnabled() {
+			fmt.Println("Enabled: false")
+		}
+		if !server.LastPing.IsZero() {
+			fmt.Printf("Last ping: %s\n", server.LastPing.Format(time.RFC3339))
+		}
+
+		if showHistory, _ := cmd.Flags().GetBool("history"); !showHistory {
+			return
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		history, err := mcpManager.StatusHistory(server.ID, limit)
+		if err != nil {
+			fmt.Printf("Error loading status history: %v\n", err)
+			return
+		}
+
+		if len(history.Transitions) == 0 {
+			fmt.Println("\nNo status changes recorded")
+			return
+		}
+
+		fmt.Printf("\nSince %s:\n", history.Since.Format(time.RFC3339))
+		fmt.Printf("Uptime: %.2f%%\n", history.Uptime)
+		fmt.Printf("Failures: %d\n", history.FailureCount)
+		if history.MTTR > 0 {
+			fmt.Printf("Mean time to recover: %s\n", history.MTTR.Round(time.Second))
+		}
+
+		if len(history.Failures) > 0 {
+			fmt.Printf("\nLatest failures:\n")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tSTATUS\tDOWNTIME\tREASON")
+			for _, failure := range history.Failures {
+				downtime := failure.Downtime.Round(time.Second).String()
+				if failure.RecoveredAt == nil {
+					downtime += " (ongoing)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+					failure.Time.Format("2006-01-02 15:04:05"), failure.Status, downtime, failure.Reason)
+			}
+			w.Flush()
+		}
+
+		fmt.Printf("\nLatest transitions:\n")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tFROM\tTO\tREASON")
+		for _, transition := range history.Transitions {
+			from := transition.From
+			if from == "" {
+				from = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				transition.Time.Format("2006-01-02 15:04:05"), from, transition.To, transition.Reason)
+		}
+		w.Flush()
+	},
+}
+
+var mcpStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show tool call counts, error rates and latencies",
+	Long: `Aggregate the tool calls recorded in the data directory per server and
+tool: call count, error rate, latency percentiles and the last error.
+Calls whose result the server flagged as an error count as failures.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+
+		since, err := mcp.ParseSince(sinceFlag)
+		if err != nil {
+			fmt.Printf("Error parsing --since: %v\n", err)
+			return
+		}
+
+		stats, err := mcpManager.ToolStats(mcp.StatsFilter{Server: server, Since: since})
+		if err != nil {
+			fmt.Printf("Error loading tool statistics: %v\n", err)
+			return
+		}
+
+		if len(stats) == 0 {
+			fmt.Println("No tool calls recorded")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVER\tTOOL\tCALLS\tERRORS\tP50\tP95\tP99\tMAX\tLAST_CALL\tLAST_ERROR")
+		for _, stat := range stats {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d (%.0f%%)\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				stat.ServerName,
+				stat.Tool,
+				stat.Calls,
+				stat.Errors, stat.ErrorRate*100,
+				stat.P50.Round(time.Millisecond),
+				stat.P95.Round(time.Millisecond),
+				stat.P99.Round(time.Millisecond),
+				stat.Max.Round(time.Millisecond),
+				stat.LastCall.Format("2006-01-02 15:04"),
+				stat.LastError,
+			)
+		}
+		w.Flush()
+	},
+}
+
+var mcpDocsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate documentation for the discovered tools",
+	Long: `Render every discovered tool with its description, its parameters (types,
+required fields, allowed values and defaults) and example arguments built
+from the input schema. The Markdown output is meant for publishing to a wiki.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		format, _ := cmd.Flags().GetString("format")
+
+		docs, err := mcpManager.ToolDocs(server)
+		if err != nil {
+			fmt.Printf("Error generating documentation: %v\n", err)
+			return
+		}
+
+		switch format {
+		case "md", "markdown":
+			fmt.Print(mcp.RenderToolDocsMarkdown(docs))
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(docs); err != nil {
+				fmt.Printf("Error formatting documentation: %v\n", err)
+			}
+		default:
+			fmt.Printf("Error: unknown format %q (expected md or json)\n", format)
+		}
+	},
+}
+
+var mcpLoginCmd = &cobra.Command{
+	Use:   "login [server]",
+	Short: "Authorize access to a remote MCP server with OAuth",
+	Long: `Run the OAuth authorization code flow with PKCE for an http or sse server.
+The authorization server is discovered from the server's protected resource
+metadata, and the agent registers itself as a client unless --client-id is
+given. The browser is redirected back to a listener on 127.0.0.1.
+
+Tokens are stored in the data directory, readable only by the current user,
+and refreshed automatically.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		server, err := mcpManager.ResolveServer(args[0])
+		if err != nil {
+			fmt.Printf("Error getting server: %v\n", err)
+			return
+		}
+
+		noBrowser, _ := cmd.Flags().GetBool("no-browser")
+		options := mcp.LoginOptions{
+			OpenURL: func(authURL string) {
+				fmt.Printf("Open this URL in your browser to authorize %s:\n\n  %s\n\n", server.Name, authURL)
+				if !noBrowser {
+					if err := openBrowser(authURL); err != nil {
+						fmt.Printf("Could not open a browser: %v\n", err)
+					}
+				}
+				fmt.Println("Waiting for authorization...")
+			},
+		}
+		options.ClientID, _ = cmd.Flags().GetString("client-id")
+		options.ClientSecret, _ = cmd.Flags().GetString("client-secret")
+		options.Scopes, _ = cmd.Flags().GetStringSlice("scope")
+		options.Port, _ = cmd.Flags().GetInt("port")
+		options.Timeout, _ = cmd.Flags().GetDuration("timeout")
+
+		if err := mcpManager.Login(server.ID, options); err != nil {
+			fmt.Printf("Error logging in: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Logged in to %s\n", server.Name)
+	},
+}
+
+// openBrowser opens a URL with the desktop's default browser
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+// doctorStatusIcons maps doctor step results to the icons shown in the report
+var doctorStatusIcons = map[string]string{
+	mcp.StepOK:      "✓",
+	mcp.StepWarning: "⚠",
+	mcp.StepFailed:  "✗",
+	mcp.StepSkipped: "-",
+}
+
+func printDoctorReport(report *mcp.DoctorReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tTIME\tDETAIL")
+	for _, step := range report.Steps {
+		fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\n",
+			step.Name,
+			doctorStatusIcons[step.Status], step.Status,
+			step.Duration.Round(time.Millisecond),
+			step.Detail,
+		)
+	}
+	w.Flush()
+
+	for _, step := range report.Steps {
+		if step.Status == mcp.StepWarning && step.Fix != "" {
+			fmt.Printf("⚠️  %s: %s\n", step.Name, step.Fix)
+		}
+	}
+
+	if failed := report.FailedStep(); failed != nil {
+		fmt.Printf("❌ Failed at step: %s\n", failed.Name)
+		fmt.Printf("💡 Suggested fix: %s\n", failed.Fix)
+	} else {
+		fmt.Println("✅ Handshake completed")
+	}
+
+	if report.Stderr != "" {
+		fmt.Println("--- server stderr ---")
+		fmt.Println(report.Stderr)
+	}
+}
+
 func init() {
 	mcpManager = mcp.NewManager()
 
@@ -177,7 +755,47 @@ func init() {
 	mcpCmd.AddCommand(mcpListCmd)
 	mcpCmd.AddCommand(mcpAddCmd)
 	mcpCmd.AddCommand(mcpRemoveCmd)
+	mcpCmd.AddCommand(mcpEnableCmd)
+	mcpCmd.AddCommand(mcpDisableCmd)
 	mcpCmd.AddCommand(mcpShowCmd)
 	mcpCmd.AddCommand(mcpToolsCmd)
 	mcpCmd.AddCommand(mcpCallCmd)
+	mcpCmd.AddCommand(mcpCompleteCmd)
+	mcpCmd.AddCommand(mcpDoctorCmd)
+	mcpCmd.AddCommand(mcpLoginCmd)
+	mcpCmd.AddCommand(mcpStatsCmd)
+	mcpCmd.AddCommand(mcpStatusCmd)
+	mcpCmd.AddCommand(mcpDocsCmd)
+
+	mcpAddCmd.Flags().Bool("sandbox", false, "Run a stdio server with a scrubbed environment in its own working directory")
+	mcpAddCmd.Flags().StringSlice("env-allow", nil, "Environment variables the sandboxed server may inherit (glob patterns allowed)")
+	mcpAddCmd.Flags().StringArray("env", nil, "Environment variable to set for the sandboxed server (KEY=VALUE, repeatable)")
+	mcpAddCmd.Flags().String("workdir", "", "Working directory of the sandboxed server (default: system temp dir)")
+	mcpAddCmd.Flags().Int("cpu-seconds", 0, "CPU time limit in seconds")
+	mcpAddCmd.Flags().Int("memory-mb", 0, "Address space limit in MB")
+	mcpAddCmd.Flags().Int("max-open-files", 0, "Open file limit")
+	mcpAddCmd.Flags().Int("max-procs", 0, "Process limit of the server's user")
+	mcpAddCmd.Flags().Uint32("uid", 0, "Run the server as this user ID")
+	mcpAddCmd.Flags().Uint32("gid", 0, "Run the server with this group ID")
+
+	mcpLoginCmd.Flags().String("client-id", "", "Pre-registered OAuth client ID (default: dynamic client registration)")
+	mcpLoginCmd.Flags().String("client-secret", "", "Secret of the pre-registered OAuth client")
+	mcpLoginCmd.Flags().StringSlice("scope", nil, "Scopes to request (default: those the server advertises)")
+	mcpLoginCmd.Flags().Int("port", 0, "Port of the loopback redirect listener (default: any free port)")
+	mcpLoginCmd.Flags().Duration("timeout", 5*time.Minute, "How long to wait for the authorization")
+	mcpLoginCmd.Flags().Bool("no-browser", false, "Print the authorization URL without opening a browser")
+
+	mcpAddCmd.Flags().StringArray("header", nil, "Header sent to a remote server (\"Name: value\", repeatable); use $VAR to read the value from the environment")
+	mcpAddCmd.Flags().String("token-env", "", "Environment variable holding a bearer token for a remote server")
+
+	mcpStatusCmd.Flags().Bool("history", false, "Show uptime, failures, mean time to recover and recent transitions")
+	mcpStatusCmd.Flags().Int("limit", 10, "Number of failures and transitions to show")
+
+	mcpStatsCmd.Flags().String("server", "", "Only show the tools of this server (ID or name)")
+	mcpStatsCmd.Flags().String("since", "", "Only count calls since a duration ago (24h, 7d) or a date")
+
+	mcpDocsCmd.Flags().String("server", "", "Only document the tools of this server (ID or name)")
+	mcpDocsCmd.Flags().String("format", "md", "Output format: md or json")
+
+	mcpShowCmd.Flags().Bool("features", false, "List the resources and prompts the server exposes")
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/profile"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var profileManager *profile.Manager
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage server groups and tool profiles",
+	Long: `Commands for managing named profiles. A profile bundles MCP server IDs,
+tool allow/deny rules and a default LLM provider for one working context.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all profiles",
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles := profileManager.ListProfiles()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSERVERS\tALLOW\tDENY\tPROVIDER\tACTIVE")
+
+		for _, p := range profiles {
+			active := "No"
+			if p.IsActive {
+				active = "Yes"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				p.Name,
+				listOrAll(p.ServerIDs),
+				listOrAll(p.Allow),
+				strings.Join(p.Deny, ","),
+				p.DefaultProvider,
+				active,
+			)
+		}
+
+		w.Flush()
+	},
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a new profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		servers, _ := cmd.Flags().GetStringSlice("servers")
+		allow, _ := cmd.Flags().GetStringSlice("allow")
+		deny, _ := cmd.Flags().GetStringSlice("deny")
+		provider, _ := cmd.Flags().GetString("provider")
+
+		if provider != "" {
+			if _, err := llmManager.GetProvider(provider); err != nil {
+				fmt.Printf("Error creating profile: %v\n", err)
+				return
+			}
+		}
+
+		p := &types.Profile{
+			Name:            args[0],
+			ServerIDs:       servers,
+			Allow:           allow,
+			Deny:            deny,
+			DefaultProvider: provider,
+		}
+
+		if err := profileManager.CreateProfile(p); err != nil {
+			fmt.Printf("Error creating profile: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Profile %s created successfully\n", p.Name)
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Set a profile as active",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := profileManager.SetActiveProfile(args[0]); err != nil {
+			fmt.Printf("Error setting active profile: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Profile %s set as active\n", args[0])
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := profileManager.RemoveProfile(args[0]); err != nil {
+			fmt.Printf("Error removing profile: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Profile %s removed successfully\n", args[0])
+	},
+}
+
+// listOrAll formats a profile list, where an empty list means no restriction
+func listOrAll(values []string) string {
+	if len(values) == 0 {
+		return "*"
+	}
+	return strings.Join(values, ",")
+}
+
+// resolveProfile returns the profile selected with --profile, or the active
+// profile when the flag is not set
+func resolveProfile(cmd *cobra.Command) (*types.Profile, error) {
+	name, _ := cmd.Flags().GetString("profile")
+	return profileManager.Resolve(name)
+}
+
+func init() {
+	profileManager = profile.NewManager()
+
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+
+	profileCreateCmd.Flags().StringSlice("servers", nil, "MCP server IDs or names to include (default: all)")
+	profileCreateCmd.Flags().StringSlice("allow", nil, "Tool name patterns to allow, e.g. 'read_*' (default: all)")
+	profileCreateCmd.Flags().StringSlice("deny", nil, "Tool name patterns to deny")
+	profileCreateCmd.Flags().String("provider", "", "Default LLM provider ID for this profile")
+}
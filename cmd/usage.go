@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/mcp"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Token usage and cost commands",
+	Long:  "Commands for reviewing the tokens and cost of LLM calls",
+}
+
+var usageReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show token usage and cost per provider and model",
+	Long: `Aggregate the LLM calls recorded in the data directory per provider and
+model: call count, input, output and cached tokens, and cost. Costs are
+computed with the price table when a call is made; calls of models without
+a price are counted as unpriced.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		provider, _ := cmd.Flags().GetString("provider")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+
+		since, err := mcp.ParseSince(sinceFlag)
+		if err != nil {
+			fmt.Printf("Error parsing --since: %v\n", err)
+			return
+		}
+
+		reports, err := llmManager.UsageReport(llm.UsageFilter{Provider: provider, Since: since})
+		if err != nil {
+			fmt.Printf("Error loading usage: %v\n", err)
+			return
+		}
+
+		if len(reports) == 0 {
+			fmt.Println("No LLM calls recorded")
+			return
+		}
+
+		var total types.UsageTotals
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tMODEL\tCALLS\tINPUT\tOUTPUT\tCACHE_READ\tCACHE_WRITE\tCOST")
+		for _, report := range reports {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+				report.ProviderName,
+				report.Model,
+				report.Calls,
+				report.InputTokens,
+				report.OutputTokens,
+				report.CacheReadTokens,
+				report.CacheWriteTokens,
+				formatCost(report.UsageTotals),
+			)
+			total.Merge(report.UsageTotals)
+		}
+		fmt.Fprintf(w, "TOTAL\t\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			total.Calls,
+			total.InputTokens,
+			total.OutputTokens,
+			total.CacheReadTokens,
+			total.CacheWriteTokens,
+			formatCost(total),
+		)
+		w.Flush()
+	},
+}
+
+// formatCost formats the cost of the totals, noting calls without a price
+func formatCost(totals types.UsageTotals) string {
+	switch {
+	case totals.Unpriced == 0:
+		return fmt.Sprintf("$%.4f", totals.Cost)
+	case totals.Unpriced < totals.Calls:
+		return fmt.Sprintf("$%.4f (%d unpriced)", totals.Cost, totals.Unpriced)
+	default:
+		return "unpriced"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.AddCommand(usageReportCmd)
+
+	usageReportCmd.Flags().String("since", "", "Only include calls since a duration ago (24h, 7d), a date or an RFC 3339 time")
+	usageReportCmd.Flags().String("provider", "", "Only include calls of this provider (ID or name)")
+}
@@ -1,32 +1,54 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/mcp"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/profile"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/storage"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
 
 // StreamResponse represents a streaming response chunk
 type StreamResponse struct {
-	Content string
-	Error   string
-	Done    bool
+	Content    string
+	Error      string
+	Done       bool
+	StopReason string // why the model ended its answer, sent with Done
+	Provider   string // name of the provider that answered, sent with Done
+
+	// Usage is the token usage and cost of the request, sent with Done or
+	// Error; the session's totals are in session.Usage
+	Usage *types.UsageTotals
+
+	// ToolsRan is sent with Error when tools ran before the turn failed, so
+	// repeating the turn would run them again
+	ToolsRan bool
 }
 
 type Agent struct {
 	mcpManager       *mcp.Manager
 	llmManager       *llm.Manager
 	processorFactory llm.ProcessorFactory
+	storage          *storage.Storage
+	outputLimits     ToolOutputLimits
+	profile          *types.Profile
+	promptOverrides  PromptOverrides
+	prices           *llm.PriceTable
+
+	// Providers tried in order when the turn's provider fails
+	fallbackProviders []string
 }
 
 func New(mcpManager *mcp.Manager, llmManager *llm.Manager) *Agent {
@@ -34,10 +56,44 @@ func New(mcpManager *mcp.Manager, llmManager *llm.Manager) *Agent {
 		mcpManager:       mcpManager,
 		llmManager:       llmManager,
 		processorFactory: llm.NewDefaultProcessorFactory(),
+		storage:          storage.New(""),
+		outputLimits:     DefaultToolOutputLimits(),
+		prices:           llm.NewPriceTable(nil),
 	}
 }
 
+// SetProfile restricts the agent to the servers, tools and default provider
+// of the given profile. A nil profile removes all restrictions.
+func (a *Agent) SetProfile(profile *types.Profile) {
+	a.profile = profile
+}
+
+// Profile returns the profile the agent is restricted to, or nil
+func (a *Agent) Profile() *types.Profile {
+	return a.profile
+}
+
+// ResolveProvider returns the requested provider, falling back to the
+// profile's default provider and then to the active provider
+func (a *Agent) ResolveProvider(providerID string) (*types.LLMProvider, error) {
+	if providerID != "" {
+		return a.llmManager.GetProvider(providerID)
+	}
+
+	if a.profile != nil && a.profile.DefaultProvider != "" {
+		return a.llmManager.GetProvider(a.profile.DefaultProvider)
+	}
+
+	return a.llmManager.GetActiveProvider()
+}
+
 func (a *Agent) ProcessRequest(message, mcpServerID, providerID string) (*types.AgentResponse, error) {
+	return a.ProcessRequestContext(context.Background(), message, mcpServerID, providerID)
+}
+
+// ProcessRequestContext is like ProcessRequest but aborts the LLM request
+// when ctx is done
+func (a *Agent) ProcessRequestContext(ctx context.Context, message, mcpServerID, providerID string) (*types.AgentResponse, error) {
 	request := &types.AgentRequest{
 		ID:          uuid.New().String(),
 		Message:     message,
@@ -52,31 +108,26 @@ func (a *Agent) ProcessRequest(message, mcpServerID, providerID string) (*types.
 		CreatedAt: time.Now(),
 	}
 
-	var provider *types.LLMProvider
-	var err error
-
-	if providerID != "" {
-		provider, err = a.llmManager.GetProvider(providerID)
-	} else {
-		provider, err = a.llmManager.GetActiveProvider()
-	}
+	chain, err := a.providerChain(providerID)
 
 	if err != nil {
 		response.Error = fmt.Sprintf("Provider error: %v", err)
 		return response, nil
 	}
 
-	// Create processor for this provider
-	processor, err := a.processorFactory.CreateProcessor(provider)
-	if err != nil {
-		response.Error = fmt.Sprintf("Failed to create processor: %v", err)
-		return response, nil
-	}
+	// Process without tools (no MCP tools for simple request)
+	session := requestSession(request, a.composeSystemPrompt(0, mcpServerID))
 
-	// Process with tools (no MCP tools for simple request)
-	processedMessage, err := processor.ProcessWithTools(message, []llm.Tool{}, nil)
+	var processedMessage string
+	usage := a.newUsageTracker("", request.ID)
+	provider, failures, err := a.withFallback(ctx, chain, nil, usage, nil, func(ctx context.Context, processor llm.LLMProcessor, _ llm.ToolCaller) error {
+		var err error
+		processedMessage, err = processor.ProcessConversationContext(ctx, session, nil, nil)
+		return err
+	})
 	if err != nil {
 		response.Error = fmt.Sprintf("LLM processing error: %v", err)
+		recordUsage(response, usage.Totals(), nil)
 		return response, nil
 	}
 
@@ -89,13 +140,36 @@ func (a *Agent) ProcessRequest(message, mcpServerID, providerID string) (*types.
 		response.Data = mcpData
 	}
 
+	recordProvider(response, provider, failures)
+	recordUsage(response, usage.Totals(), nil)
 	response.Message = processedMessage
 	return response, nil
 }
 
-// ProcessRequestWithUI processes a request with enhanced UI feedback
+// requestSession wraps a single request in a conversation of one message, so
+// that it is sent with the composed system prompt like a chat turn
+func requestSession(request *types.AgentRequest, systemPrompt string) *types.ConversationSession {
+	session := &types.ConversationSession{
+		ID:           request.ID,
+		MCPServerID:  request.MCPServerID,
+		ProviderID:   request.ProviderID,
+		SystemPrompt: systemPrompt,
+		CreatedAt:    request.CreatedAt,
+		UpdatedAt:    request.CreatedAt,
+	}
+	session.AddMessage("user", request.Message)
+	return session
+}
+
 // ProcessConversationWithStreaming processes a conversation with streaming support
 func (a *Agent) ProcessConversationWithStreaming(session *types.ConversationSession, message string, display ui.ToolDisplayInterface) (<-chan StreamResponse, error) {
+	return a.ProcessConversationWithStreamingContext(context.Background(), session, message, display)
+}
+
+// ProcessConversationWithStreamingContext is like ProcessConversationWithStreaming
+// but ends the stream when ctx is done. A turn that fails or is cancelled is
+// removed from the session again.
+func (a *Agent) ProcessConversationWithStreamingContext(ctx context.Context, session *types.ConversationSession, message string, display ui.ToolDisplayInterface) (<-chan StreamResponse, error) {
 	ch := make(chan StreamResponse, 10)
 	
 	go func() {
@@ -106,20 +180,15 @@ func (a *Agent) ProcessConversationWithStreaming(session *types.ConversationSess
 		}
 
 		// Add user message to conversation
+		turnStart := len(session.Messages)
 		session.AddMessage("user", message)
 
 
 
-		var provider *types.LLMProvider
-		var err error
-
-		if session.ProviderID != "" {
-			provider, err = a.llmManager.GetProvider(session.ProviderID)
-		} else {
-			provider, err = a.llmManager.GetActiveProvider()
-		}
+		chain, err := a.providerChain(session.ProviderID)
 
 		if err != nil {
+			session.TruncateMessages(turnStart)
 			ch <- StreamResponse{Error: fmt.Sprintf("Provider error: %v", err)}
 			return
 		}
@@ -128,22 +197,39 @@ func (a *Agent) ProcessConversationWithStreaming(session *types.ConversationSess
 			display.ShowProgress("Finding LLM provider...")
 		}
 
-		// Create processor for this provider
-		processor, err := a.processorFactory.CreateProcessor(provider)
-		if err != nil {
-			ch <- StreamResponse{Error: fmt.Sprintf("Failed to create processor: %v", err)}
-			return
-		}
-
 		// Prepare MCP tools and tool caller
-		tools, toolCaller := a.prepareMCPTools(session.MCPServerID, display)
+		tools, toolCaller := a.prepareMCPTools(ctx, session.MCPServerID, display)
+		session.SystemPrompt = a.composeSystemPrompt(len(tools), session.MCPServerID)
+
+		var toolsRan atomic.Bool
+		if toolCaller != nil {
+			callTool := toolCaller
+			toolCaller = func(name string, args map[string]interface{}) (interface{}, error) {
+				toolsRan.Store(true)
+				return callTool(name, args)
+			}
+		}
 
 		// Process conversation with streaming support
-		err = a.processConversationWithToolsStreaming(processor, session, tools, toolCaller, display, ch)
+		var stopReason string
+		usage := a.newUsageTracker(session.ID, uuid.New().String())
+		provider, _, err := a.withFallback(ctx, chain, display, usage, toolCaller, func(ctx context.Context, processor llm.LLMProcessor, toolCaller llm.ToolCaller) error {
+			var err error
+			stopReason, err = a.processConversationWithToolsStreaming(ctx, processor, session, tools, toolCaller, display, ch)
+			return err
+		})
+
+		// Tokens of a failed turn were paid for all the same
+		totals := usage.Totals()
+		session.Usage.Merge(totals)
+
 		if err != nil {
-			ch <- StreamResponse{Error: fmt.Sprintf("Processing error: %v", err)}
+			session.TruncateMessages(turnStart)
+			ch <- StreamResponse{Error: fmt.Sprintf("Processing error: %v", err), Usage: &totals, ToolsRan: toolsRan.Load()}
 			return
 		}
+
+		ch <- StreamResponse{Done: true, StopReason: stopReason, Provider: provider.Name, Usage: &totals}
 	}()
 	
 	return ch, nil
@@ -151,11 +237,19 @@ func (a *Agent) ProcessConversationWithStreaming(session *types.ConversationSess
 
 // ProcessConversation processes a message within a conversation context using the new processor system
 func (a *Agent) ProcessConversation(session *types.ConversationSession, message string, display ui.ToolDisplayInterface) (*types.AgentResponse, error) {
+	return a.ProcessConversationContext(context.Background(), session, message, display)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the LLM
+// request and the tool loop when ctx is done. A turn that fails or is
+// cancelled is removed from the session again.
+func (a *Agent) ProcessConversationContext(ctx context.Context, session *types.ConversationSession, message string, display ui.ToolDisplayInterface) (*types.AgentResponse, error) {
 	if display != nil {
 		display.ShowProgress("Initializing AI agent...")
 	}
 
 	// Add user message to conversation
+	turnStart := len(session.Messages)
 	session.AddMessage("user", message)
 
 	request := &types.AgentRequest{
@@ -172,16 +266,10 @@ func (a *Agent) ProcessConversation(session *types.ConversationSession, message
 		CreatedAt: time.Now(),
 	}
 
-	var provider *types.LLMProvider
-	var err error
-
-	if session.ProviderID != "" {
-		provider, err = a.llmManager.GetProvider(session.ProviderID)
-	} else {
-		provider, err = a.llmManager.GetActiveProvider()
-	}
+	chain, err := a.providerChain(session.ProviderID)
 
 	if err != nil {
+		session.TruncateMessages(turnStart)
 		response.Error = fmt.Sprintf("Provider error: %v", err)
 		return response, nil
 	}
@@ -190,49 +278,69 @@ func (a *Agent) ProcessConversation(session *types.ConversationSession, message
 		display.ShowProgress("Finding LLM provider...")
 	}
 
-	// Create processor for this provider
-	processor, err := a.processorFactory.CreateProcessor(provider)
-	if err != nil {
-		response.Error = fmt.Sprintf("Failed to create processor: %v", err)
-		return response, nil
-	}
-
 	// Prepare MCP tools and tool caller
-	tools, toolCaller := a.prepareMCPTools(session.MCPServerID, display)
+	tools, toolCaller := a.prepareMCPTools(ctx, session.MCPServerID, display)
+	session.SystemPrompt = a.composeSystemPrompt(len(tools), session.MCPServerID)
+
+	// Process conversation with UI feedback. The conversation is kept in a
+	// provider neutral form, so a fallback provider continues it as is.
+	var result string
+	usage := a.newUsageTracker(session.ID, request.ID)
+	provider, failures, err := a.withFallback(ctx, chain, display, usage, toolCaller, func(ctx context.Context, processor llm.LLMProcessor, toolCaller llm.ToolCaller) error {
+		var err error
+		result, err = processor.ProcessConversationWithUIContext(ctx, session, tools, toolCaller, display)
+		return err
+	})
+
+	// Tokens of a failed turn were paid for all the same
+	session.Usage.Merge(usage.Totals())
 
-	// Process conversation with UI feedback
-	result, err := processor.ProcessConversationWithUI(session, tools, toolCaller, display)
 	if err != nil {
+		session.TruncateMessages(turnStart)
 		response.Error = fmt.Sprintf("Processing error: %v", err)
+		recordUsage(response, usage.Totals(), session)
 		return response, nil
 	}
 
 	// Add assistant response to conversation
 	session.AddMessage("assistant", result)
 
+	recordProvider(response, provider, failures)
+	recordUsage(response, usage.Totals(), session)
 	response.Message = result
 	return response, nil
 }
 
-// prepareMCPTools prepares MCP tools and creates a tool caller function
-func (a *Agent) prepareMCPTools(mcpServerID string, display ui.ToolDisplayInterface) ([]llm.Tool, llm.ToolCaller) {
+// prepareMCPTools prepares MCP tools and creates a tool caller function.
+// The tool caller refuses to start tools once ctx is done.
+func (a *Agent) prepareMCPTools(ctx context.Context, mcpServerID string, display ui.ToolDisplayInterface) ([]llm.Tool, llm.ToolCaller) {
 	if display != nil {
 		display.ShowProgress("Loading tools from MCP servers...")
 	}
 
 	// Get all available tools from MCP servers
 	allMCPTools := a.mcpManager.GetAllTools()
+
+	serversByName := make(map[string]*types.MCPServer)
+	for _, server := range a.mcpManager.ListServers() {
+		serversByName[server.Name] = server
+	}
 	
 	var mcpTools []map[string]interface{}
 	
 	for serverName, tools := range allMCPTools {
-		// Clean server name for tool naming
-		cleanServerName := strings.ReplaceAll(serverName, " ", "_")
-		cleanServerName = strings.ReplaceAll(cleanServerName, "-", "_")
-		
+		if server, exists := serversByName[serverName]; exists && !profile.AllowsServer(a.profile, server) {
+			continue
+		}
+
 		for _, tool := range tools {
+			prefixedName := mcp.ExposedToolName(serverName, tool.Name)
+			if !profile.AllowsTool(a.profile, prefixedName, tool.Name) {
+				continue
+			}
+
 			mcpTool := map[string]interface{}{
-				"name":        fmt.Sprintf("%s_%s", cleanServerName, tool.Name),
+				"name":        prefixedName,
 				"description": fmt.Sprintf("[%s] %s", serverName, tool.Description),
 				"inputSchema": tool.Schema,
 				"serverName":  serverName,
@@ -248,9 +356,21 @@ func (a *Agent) prepareMCPTools(mcpServerID string, display ui.ToolDisplayInterf
 
 	// Convert MCP tools to LLM format
 	tools := llm.ConvertMCPToolsToOpenAI(mcpTools)
+	if len(tools) > 0 {
+		tools = append(tools, readToolOutputTool())
+	}
 
 	// Create tool caller function
+	approver := newToolApprover(display)
 	toolCaller := func(name string, args map[string]interface{}) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if name == ReadToolOutputToolName {
+			return a.readToolOutput(args)
+		}
+
 		// Find the corresponding MCP tool
 		for _, mcpTool := range mcpTools {
 			if mcpTool["name"] == name {
@@ -261,7 +381,7 @@ func (a *Agent) prepareMCPTools(mcpServerID string, display ui.ToolDisplayInterf
 				servers := a.mcpManager.ListServers()
 				for _, server := range servers {
 					if server.Name == serverName {
-						return a.mcpManager.CallTool(server.ID, toolName, args)
+						return a.callMCPTool(server, name, toolName, args, approver, display)
 					}
 				}
 			}
@@ -272,7 +392,76 @@ func (a *Agent) prepareMCPTools(mcpServerID string, display ui.ToolDisplayInterf
 	return tools, toolCaller
 }
 
+// callMCPTool runs a tool call of the model or of the /tool chat command. It
+// asks for approval through the display, passes input the server requests on
+// to the display and limits the output that is returned.
+func (a *Agent) callMCPTool(server *types.MCPServer, name, toolName string, args map[string]interface{}, approver *toolApprover, display ui.ToolDisplayInterface) (interface{}, error) {
+	if err := approver.approve(server.Name, toolName, args); err != nil {
+		return nil, err
+	}
+
+	// Input a server requests during a tool call is asked for through the
+	// display; without one the request is declined
+	var elicit mcp.ElicitationHandler
+	if display != nil {
+		elicit = display.PromptElicitation
+	}
+
+	result, err := a.mcpManager.CallToolWithElicitation(server.ID, toolName, args, elicit)
+	if err != nil {
+		return nil, err
+	}
+	return a.limitToolOutput(name, toolName, result), nil
+}
+
+// toolApprover asks the user through the display before each tool call of a
+// turn. Answering "skip prompts" approves the remaining calls of the turn and
+// "abort" refuses them.
+type toolApprover struct {
+	display     ui.ToolDisplayInterface
+	autoApprove bool
+	aborted     bool
+}
+
+func newToolApprover(display ui.ToolDisplayInterface) *toolApprover {
+	return &toolApprover{display: display}
+}
+
+// approve returns an error when the call must not run
+func (t *toolApprover) approve(serverName, toolName string, args map[string]interface{}) error {
+	if t.aborted {
+		return fmt.Errorf("tool calls were aborted by the user")
+	}
+	if t.display == nil || t.autoApprove {
+		return nil
+	}
+
+	approved, err := t.display.PromptToolApproval(serverName, toolName, args)
+	if err != nil {
+		switch err.Error() {
+		case "AUTO_APPROVE_ALL":
+			t.autoApprove = true
+			return nil
+		case "ABORT":
+			t.aborted = true
+			return fmt.Errorf("tool calls were aborted by the user")
+		}
+		return err
+	}
+	if !approved {
+		return fmt.Errorf("the user declined to run %s", toolName)
+	}
+	return nil
+}
+
+// ProcessRequestWithUI processes a request with enhanced UI feedback
 func (a *Agent) ProcessRequestWithUI(message, mcpServerID, providerID string, interactive bool) (*types.AgentResponse, error) {
+	return a.ProcessRequestWithUIContext(context.Background(), message, mcpServerID, providerID, interactive)
+}
+
+// ProcessRequestWithUIContext is like ProcessRequestWithUI but aborts the LLM
+// request and the tool loop when ctx is done
+func (a *Agent) ProcessRequestWithUIContext(ctx context.Context, message, mcpServerID, providerID string, interactive bool) (*types.AgentResponse, error) {
 	// Create appropriate display interface with enhancements
 	var display ui.ToolDisplayInterface
 	if interactive {
@@ -299,16 +488,9 @@ func (a *Agent) ProcessRequestWithUI(message, mcpServerID, providerID string, in
 		CreatedAt: time.Now(),
 	}
 
-	var provider *types.LLMProvider
-	var err error
-
 	display.ShowProgress("Finding LLM provider...")
 
-	if providerID != "" {
-		provider, err = a.llmManager.GetProvider(providerID)
-	} else {
-		provider, err = a.llmManager.GetActiveProvider()
-	}
+	chain, err := a.providerChain(providerID)
 
 	if err != nil {
 		display.ShowError(fmt.Errorf("Provider error: %v", err))
@@ -316,22 +498,23 @@ func (a *Agent) ProcessRequestWithUI(message, mcpServerID, providerID string, in
 		return response, nil
 	}
 
-	// Create processor for this provider
-	processor, err := a.processorFactory.CreateProcessor(provider)
-	if err != nil {
-		display.ShowError(fmt.Errorf("Failed to create processor: %v", err))
-		response.Error = fmt.Sprintf("Failed to create processor: %v", err)
-		return response, nil
-	}
-
 	// Prepare MCP tools and tool caller
-	tools, toolCaller := a.prepareMCPTools(mcpServerID, display)
+	tools, toolCaller := a.prepareMCPTools(ctx, mcpServerID, display)
 
 	// Process with UI feedback
-	processedMessage, err := processor.ProcessWithUI(message, tools, toolCaller, display)
+	session := requestSession(request, a.composeSystemPrompt(len(tools), mcpServerID))
+
+	var processedMessage string
+	usage := a.newUsageTracker("", request.ID)
+	provider, failures, err := a.withFallback(ctx, chain, display, usage, toolCaller, func(ctx context.Context, processor llm.LLMProcessor, toolCaller llm.ToolCaller) error {
+		var err error
+		processedMessage, err = processor.ProcessConversationWithUIContext(ctx, session, tools, toolCaller, display)
+		return err
+	})
 	if err != nil {
 		display.ShowError(fmt.Errorf("LLM processing error: %v", err))
 		response.Error = fmt.Sprintf("LLM processing error: %v", err)
+		recordUsage(response, usage.Totals(), nil)
 		return response, nil
 	}
 
@@ -346,6 +529,8 @@ func (a *Agent) ProcessRequestWithUI(message, mcpServerID, providerID string, in
 		response.Data = mcpData
 	}
 
+	recordProvider(response, provider, failures)
+	recordUsage(response, usage.Totals(), nil)
 	response.Message = processedMessage
 	return response, nil
 }
@@ -353,27 +538,39 @@ func (a *Agent) ProcessRequestWithUI(message, mcpServerID, providerID string, in
 
 
 
-// processConversationWithToolsStreaming handles conversation with tools and streaming
-func (a *Agent) processConversationWithToolsStreaming(processor llm.LLMProcessor, session *types.ConversationSession, tools []llm.Tool, toolCaller llm.ToolCaller, display ui.ToolDisplayInterface, ch chan<- StreamResponse) error {
-	// For now, we'll use the existing tool processing logic and stream the final result
-	// This ensures tools work correctly while providing streaming output
-	result, err := processor.ProcessConversationWithUI(session, tools, toolCaller, display)
+// processConversationWithToolsStreaming handles conversation with tools and streaming.
+// Text is passed on as the model produces it; tools are executed in between
+// by the processor. The streamed answer is added to the conversation once
+// the stream completes, and the reason the model stopped is returned.
+func (a *Agent) processConversationWithToolsStreaming(ctx context.Context, processor llm.LLMProcessor, session *types.ConversationSession, tools []llm.Tool, toolCaller llm.ToolCaller, display ui.ToolDisplayInterface, ch chan<- StreamResponse) (string, error) {
+	stream, err := processor.ProcessConversationStreamWithUIContext(ctx, session, tools, toolCaller, display)
 	if err != nil {
-		return err
+		return "", err
 	}
 	
-	// Add assistant response to conversation
-	session.AddMessage("assistant", result)
-	
-	// Stream the final result character by character for streaming effect
-	for _, char := range result {
-		ch <- StreamResponse{Content: string(char)}
-		// Small delay to simulate streaming effect
-		time.Sleep(10 * time.Millisecond)
+	var result strings.Builder
+	var stopReason string
+	for chunk := range stream {
+		if chunk.Error != nil {
+			// Another provider would repeat what was already passed on
+			if result.Len() > 0 {
+				return "", &finalError{err: chunk.Error}
+			}
+			return "", chunk.Error
+		}
+		if chunk.Content != "" {
+			result.WriteString(chunk.Content)
+			ch <- StreamResponse{Content: chunk.Content}
+		}
+		if chunk.StopReason != "" {
+			stopReason = chunk.StopReason
+		}
 	}
-	ch <- StreamResponse{Done: true}
 	
-	return nil
+	// Add assistant response to conversation
+	session.AddMessage("assistant", result.String())
+	
+	return stopReason, nil
 }
 
 func (a *Agent) processWithMCP(message, serverID string) (map[string]interface{}, error) {
@@ -398,6 +595,7 @@ func (a *Agent) StartServer(port string) error {
 	r.HandleFunc("/api/v1/query", a.handleQuery).Methods("POST")
 	r.HandleFunc("/api/v1/health", a.handleHealth).Methods("GET")
 	r.HandleFunc("/api/v1/mcp/servers", a.handleMCPServers).Methods("GET")
+	r.HandleFunc("/api/v1/mcp/stats", a.handleMCPStats).Methods("GET")
 	r.HandleFunc("/api/v1/llm/providers", a.handleLLMProviders).Methods("GET")
 
 	addr := ":" + port
@@ -413,7 +611,8 @@ func (a *Agent) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := a.ProcessRequest(request.Message, request.MCPServerID, request.ProviderID)
+	// The request is abandoned when the client disconnects
+	response, err := a.ProcessRequestContext(r.Context(), request.Message, request.MCPServerID, request.ProviderID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -429,14 +628,44 @@ func (a *Agent) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"timestamp": time.Now(),
 	}
 
+	// Recent MCP server status changes show flapping servers at a glance
+	if transitions, err := a.mcpManager.RecentStatusTransitions(20); err == nil {
+		health["mcp_status_transitions"] = transitions
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(health)
 }
 
 func (a *Agent) handleMCPServers(w http.ResponseWriter, r *http.Request) {
 	servers := a.mcpManager.ListServers()
+
+	masked := make([]*types.MCPServer, 0, len(servers))
+	for _, server := range servers {
+		masked = append(masked, server.Masked())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(masked)
+}
+
+// handleMCPStats returns the tool usage statistics, optionally filtered by
+// the server and since query parameters
+func (a *Agent) handleMCPStats(w http.ResponseWriter, r *http.Request) {
+	since, err := mcp.ParseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := a.mcpManager.ToolStats(mcp.StatsFilter{Server: r.URL.Query().Get("server"), Since: since})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(servers)
+	json.NewEncoder(w).Encode(stats)
 }
 
 func (a *Agent) handleLLMProviders(w http.ResponseWriter, r *http.Request) {
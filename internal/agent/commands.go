@@ -0,0 +1,506 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/mcp"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/profile"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// Chat commands that address an MCP server directly instead of going
+// through the model:
+//
+//	/tool <server> <tool> [arg=value ...]
+//	/prompt <server> <prompt> [arg=value ...]
+//	/resource <server> <uri-template> [arg=value ...]
+//
+// Values containing spaces are quoted, as in path="/srv/my files" or
+// data='{"a": 1}'. Within double quotes \" and \\ stand for " and \.
+const (
+	ChatCommandTool     = "/tool"
+	ChatCommandPrompt   = "/prompt"
+	ChatCommandResource = "/resource"
+)
+
+// ChatCommand is a parsed chat command
+type ChatCommand struct {
+	Kind   string
+	Server string
+	Name   string
+	Args   map[string]string
+}
+
+// ChatCommandResult is the outcome of running a chat command. Output is
+// shown to the user; a non-empty Message is sent to the model as the user's
+// message (used by /prompt).
+type ChatCommandResult struct {
+	Output  string
+	Message string
+}
+
+var templateVariablePattern = regexp.MustCompile(`\{(\+?)([^}]+)\}`)
+
+// IsChatCommand reports whether the line starts with one of the chat commands
+func IsChatCommand(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case ChatCommandTool, ChatCommandPrompt, ChatCommandResource:
+		return true
+	}
+	return false
+}
+
+// ParseChatCommand parses a chat command line
+func ParseChatCommand(line string) (*ChatCommand, error) {
+	if !IsChatCommand(line) {
+		return nil, fmt.Errorf("not a chat command: %s", line)
+	}
+	fields, _, err := splitChatLine(line)
+	if err != nil {
+		return nil, err
+	}
+	return parseChatFields(fields)
+}
+
+// parseChatFields builds a chat command from the words of its line
+func parseChatFields(fields []string) (*ChatCommand, error) {
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("usage: %s <server> <name> [arg=value ...]", fields[0])
+	}
+
+	command := &ChatCommand{
+		Kind:   fields[0],
+		Server: fields[1],
+		Name:   fields[2],
+		Args:   make(map[string]string),
+	}
+	for _, field := range fields[3:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid argument %q (expected arg=value)", field)
+		}
+		command.Args[key] = value
+	}
+
+	return command, nil
+}
+
+// RunChatCommand executes a chat command against its MCP server. Tool calls
+// are approved through display like the tool calls of the model.
+func (a *Agent) RunChatCommand(command *ChatCommand, display ui.ToolDisplayInterface) (*ChatCommandResult, error) {
+	server, err := a.resolveAllowedServer(command.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	switch command.Kind {
+	case ChatCommandTool:
+		return a.runToolCommand(server, command, display)
+	case ChatCommandPrompt:
+		messages, err := a.mcpManager.GetServerPrompt(server.ID, command.Name, command.Args)
+		if err != nil {
+			return nil, err
+		}
+		var texts []string
+		for _, message := range messages {
+			if message.Content.Text != "" {
+				texts = append(texts, message.Content.Text)
+			}
+		}
+		return &ChatCommandResult{Message: strings.Join(texts, "\n\n")}, nil
+	case ChatCommandResource:
+		contents, err := a.mcpManager.ReadServerResource(server.ID, expandURITemplate(command.Name, command.Args))
+		if err != nil {
+			return nil, err
+		}
+		var parts []string
+		for _, content := range contents {
+			if content.Text != "" {
+				parts = append(parts, content.Text)
+			} else {
+				parts = append(parts, fmt.Sprintf("[%s: %d bytes of %s data]", content.URI, len(content.Blob), content.MimeType))
+			}
+		}
+		return &ChatCommandResult{Output: strings.Join(parts, "\n")}, nil
+	}
+
+	return nil, fmt.Errorf("unknown chat command %s", command.Kind)
+}
+
+// runToolCommand calls a tool, converting the string arguments to the types
+// declared in its schema
+func (a *Agent) runToolCommand(server *types.MCPServer, command *ChatCommand, display ui.ToolDisplayInterface) (*ChatCommandResult, error) {
+	tool, err := a.findAllowedTool(server, command.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	properties, _ := tool.Schema["properties"].(map[string]interface{})
+	arguments := make(map[string]interface{})
+	for key, value := range command.Args {
+		property, _ := properties[key].(map[string]interface{})
+		converted, err := convertArgument(value, property)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+		arguments[key] = converted
+	}
+
+	exposedName := mcp.ExposedToolName(server.Name, tool.Name)
+	result, err := a.callMCPTool(server, exposedName, tool.Name, arguments, newToolApprover(display), display)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatCommandResult{Output: llm.FormatToolResult(result)}, nil
+}
+
+// CompleteChatCommand returns the possible completions of a partially typed
+// chat command line. Each candidate is the full line with the last word
+// completed.
+func (a *Agent) CompleteChatCommand(line string) ([]string, error) {
+	if !IsChatCommand(line) {
+		return nil, nil
+	}
+
+	// The last word may be an unfinished quoted value
+	fields, lastStart, err := splitChatLine(line)
+	if err != nil && !errors.Is(err, errUnterminatedQuote) {
+		return nil, err
+	}
+	if lastStart == len(line) {
+		fields = append(fields, "")
+	}
+	if len(fields) < 2 {
+		return nil, nil
+	}
+
+	current := fields[len(fields)-1]
+	base := line[:lastStart]
+
+	var candidates []string
+	switch len(fields) {
+	case 2:
+		candidates = a.completeServerNames(current)
+	case 3:
+		candidates, err = a.completeNames(fields[0], fields[1], current)
+	default:
+		candidates, err = a.completeArgument(fields, current)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		lines = append(lines, base+quoteChatWord(candidate))
+	}
+	return lines, nil
+}
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// splitChatLine splits a chat command line into words, removing the quotes
+// around values. It also returns the offset at which the last word starts,
+// or the length of the line if it ends in a space.
+func splitChatLine(line string) ([]string, int, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	start := len(line)
+	var quote rune
+
+	for i, r := range line {
+		switch {
+		case quote == '"' && r == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\'):
+			// The escaped character follows, skip the backslash
+			quote = '\\'
+		case quote == '\\':
+			word.WriteRune(r)
+			quote = '"'
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			if !inWord {
+				inWord, start = true, i
+			}
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord, start = false, len(line)
+			}
+		default:
+			word.WriteRune(r)
+			if !inWord {
+				inWord, start = true, i
+			}
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	if quote != 0 {
+		return words, start, errUnterminatedQuote
+	}
+	return words, start, nil
+}
+
+// quoteChatWord quotes a completed word if it contains spaces or quotes.
+// Only the value of an arg=value word is quoted.
+func quoteChatWord(word string) string {
+	if !strings.ContainsAny(word, " \t\"'") {
+		return word
+	}
+	prefix, value := "", word
+	if key, rest, found := strings.Cut(word, "="); found && !strings.ContainsAny(key, " \t\"'") {
+		prefix, value = key+"=", rest
+	}
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	return prefix + `"` + value + `"`
+}
+
+// completeServerNames suggests the servers the agent may use
+func (a *Agent) completeServerNames(prefix string) []string {
+	var names []string
+	for _, server := range a.mcpManager.ListServers() {
+		if !server.IsEnabled() || (server.Status != "available" && server.Status != "connected") {
+			continue
+		}
+		if !profile.AllowsServer(a.profile, server) {
+			continue
+		}
+		name := server.Name
+		if strings.ContainsAny(name, " \t") {
+			name = server.ID
+		}
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeNames suggests tool names, prompt names or resource URIs
+func (a *Agent) completeNames(kind, serverRef, prefix string) ([]string, error) {
+	server, err := a.resolveAllowedServer(serverRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	switch kind {
+	case ChatCommandTool:
+		tools, err := a.mcpManager.GetServerTools(server.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, tool := range tools {
+			if profile.AllowsTool(a.profile, mcp.ExposedToolName(server.Name, tool.Name), tool.Name) {
+				names = append(names, tool.Name)
+			}
+		}
+	case ChatCommandPrompt:
+		prompts, err := a.mcpManager.ListServerPrompts(server.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, prompt := range prompts {
+			names = append(names, prompt.Name)
+		}
+	case ChatCommandResource:
+		resources, err := a.mcpManager.ListServerResources(server.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			names = append(names, resource.URI)
+		}
+	}
+
+	return filterPrefix(names, prefix), nil
+}
+
+// completeArgument suggests argument names, or values once the argument
+// name has been typed
+func (a *Agent) completeArgument(fields []string, current string) ([]string, error) {
+	command, err := parseChatFields(fields[:len(fields)-1])
+	if err != nil {
+		return nil, err
+	}
+	server, err := a.resolveAllowedServer(command.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	argument, value, hasValue := strings.Cut(current, "=")
+	if !hasValue {
+		names, err := a.argumentNames(server, command)
+		if err != nil {
+			return nil, err
+		}
+		var candidates []string
+		for _, name := range filterPrefix(names, argument) {
+			if _, used := command.Args[name]; !used {
+				candidates = append(candidates, name+"=")
+			}
+		}
+		return candidates, nil
+	}
+
+	var ref mcp.CompletionRef
+	switch command.Kind {
+	case ChatCommandTool:
+		ref = mcp.CompletionRef{Type: mcp.RefTool, Name: command.Name}
+	case ChatCommandPrompt:
+		ref = mcp.CompletionRef{Type: mcp.RefPrompt, Name: command.Name}
+	case ChatCommandResource:
+		ref = mcp.CompletionRef{Type: mcp.RefResource, URI: command.Name}
+	}
+
+	result, err := a.mcpManager.Complete(server.ID, ref, argument, value, command.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]string, 0, len(result.Values))
+	for _, candidate := range result.Values {
+		candidates = append(candidates, argument+"="+candidate)
+	}
+	return candidates, nil
+}
+
+// argumentNames returns the arguments accepted by a tool, prompt or
+// resource template
+func (a *Agent) argumentNames(server *types.MCPServer, command *ChatCommand) ([]string, error) {
+	switch command.Kind {
+	case ChatCommandTool:
+		tool, err := a.findAllowedTool(server, command.Name)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.ToolArgumentNames(*tool), nil
+	case ChatCommandPrompt:
+		prompts, err := a.mcpManager.ListServerPrompts(server.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, prompt := range prompts {
+			if prompt.Name != command.Name {
+				continue
+			}
+			var names []string
+			for _, argument := range prompt.Arguments {
+				names = append(names, argument.Name)
+			}
+			return names, nil
+		}
+		return nil, fmt.Errorf("prompt %s not found", command.Name)
+	case ChatCommandResource:
+		var names []string
+		for _, match := range templateVariablePattern.FindAllStringSubmatch(command.Name, -1) {
+			names = append(names, match[2])
+		}
+		return names, nil
+	}
+	return nil, nil
+}
+
+// resolveAllowedServer finds a server by ID or name and checks that the
+// active profile allows it
+func (a *Agent) resolveAllowedServer(idOrName string) (*types.MCPServer, error) {
+	server, err := a.mcpManager.ResolveServer(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.AllowsServer(a.profile, server) {
+		return nil, fmt.Errorf("server %s is not part of profile %s", server.Name, a.profile.Name)
+	}
+	return server, nil
+}
+
+// findAllowedTool looks up a tool on the server and checks that the active
+// profile allows it
+func (a *Agent) findAllowedTool(server *types.MCPServer, name string) (*mcp.Tool, error) {
+	tools, err := a.mcpManager.GetServerTools(server.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tool := range tools {
+		if tool.Name != name {
+			continue
+		}
+		if !profile.AllowsTool(a.profile, mcp.ExposedToolName(server.Name, tool.Name), tool.Name) {
+			return nil, fmt.Errorf("tool %s is not allowed by profile %s", name, a.profile.Name)
+		}
+		return &tool, nil
+	}
+	return nil, fmt.Errorf("tool %s not found on server %s", name, server.Name)
+}
+
+// convertArgument converts a typed-in value to the type its schema declares
+func convertArgument(value string, property map[string]interface{}) (interface{}, error) {
+	switch property["type"] {
+	case "integer":
+		return strconv.Atoi(value)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "array", "object":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return nil, err
+		}
+		return decoded, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandURITemplate fills in the simple ({var}) and reserved ({+var})
+// expressions of a resource URI template
+func expandURITemplate(template string, args map[string]string) string {
+	return templateVariablePattern.ReplaceAllStringFunc(template, func(expression string) string {
+		match := templateVariablePattern.FindStringSubmatch(expression)
+		value, ok := args[match[2]]
+		if !ok {
+			return expression
+		}
+		if match[1] == "+" {
+			return value
+		}
+		return url.PathEscape(value)
+	})
+}
+
+// filterPrefix returns the sorted values that start with prefix
+func filterPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChatCommandQuoting(t *testing.T) {
+	tests := []struct {
+		line string
+		args map[string]string
+	}{
+		{`/tool files read path=/etc/hosts`, map[string]string{"path": "/etc/hosts"}},
+		{`/tool files read path="/srv/my files"`, map[string]string{"path": "/srv/my files"}},
+		{`/tool files read data='{"a": 1}'`, map[string]string{"data": `{"a": 1}`}},
+		{`/tool files read text="say \"hi\" \\o/"`, map[string]string{"text": `say "hi" \o/`}},
+		{`/tool files read "path=a b"  mode=ro`, map[string]string{"path": "a b", "mode": "ro"}},
+		{`/tool files read empty=""`, map[string]string{"empty": ""}},
+	}
+
+	for _, tt := range tests {
+		command, err := ParseChatCommand(tt.line)
+		if err != nil {
+			t.Errorf("ParseChatCommand(%s): %v", tt.line, err)
+			continue
+		}
+		if command.Server != "files" || command.Name != "read" || !reflect.DeepEqual(command.Args, tt.args) {
+			t.Errorf("ParseChatCommand(%s) = %+v, want args %v", tt.line, command, tt.args)
+		}
+	}
+
+	if _, err := ParseChatCommand(`/tool files read path="/srv/my files`); err == nil {
+		t.Error("unterminated quote was accepted")
+	}
+}
+
+func TestSplitChatLineLastWord(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+		start int
+	}{
+		{`/tool files`, []string{"/tool", "files"}, 6},
+		{`/tool files `, []string{"/tool", "files"}, 12},
+		{`/tool files read path="/srv/my fi`, []string{"/tool", "files", "read", "path=/srv/my fi"}, 17},
+	}
+
+	for _, tt := range tests {
+		words, start, _ := splitChatLine(tt.line)
+		if !reflect.DeepEqual(words, tt.words) || start != tt.start {
+			t.Errorf("splitChatLine(%s) = %q, %d, want %q, %d", tt.line, words, start, tt.words, tt.start)
+		}
+	}
+}
+
+func TestQuoteChatWord(t *testing.T) {
+	tests := map[string]string{
+		"read_file":          "read_file",
+		"path=/srv/my files": `path="/srv/my files"`,
+		`say "hi"`:           `"say \"hi\""`,
+	}
+	for word, want := range tests {
+		if got := quoteChatWord(word); got != want {
+			t.Errorf("quoteChatWord(%s) = %s, want %s", word, got, want)
+		}
+		if words, _, err := splitChatLine(quoteChatWord(word)); err != nil || len(words) != 1 || words[0] != word {
+			t.Errorf("%s does not split back to %q: %q, %v", quoteChatWord(word), word, words, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// SetFallbackProviders sets the providers, given by ID or name, that a turn
+// moves on to in order when its provider fails with a rate limit, server,
+// authentication or connection error
+func (a *Agent) SetFallbackProviders(refs []string) {
+	a.fallbackProviders = refs
+}
+
+// providerChain returns the providers to try for a turn: the requested or
+// default provider followed by the fallback providers. Fallback providers
+// that no longer exist are skipped.
+func (a *Agent) providerChain(providerID string) ([]*types.LLMProvider, error) {
+	var chain []*types.LLMProvider
+	seen := make(map[string]bool)
+
+	primary, err := a.ResolveProvider(providerID)
+	if err == nil {
+		chain = append(chain, primary)
+		seen[primary.ID] = true
+	}
+
+	for _, ref := range a.fallbackProviders {
+		provider, findErr := a.llmManager.FindProvider(ref)
+		if findErr != nil || seen[provider.ID] {
+			continue
+		}
+		chain = append(chain, provider)
+		seen[provider.ID] = true
+	}
+
+	if len(chain) == 0 {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// finalError marks an error after which the fallback chain must not be
+// continued, e.g. because part of the answer was already passed on
+type finalError struct {
+	err error
+}
+
+func (e *finalError) Error() string { return e.err.Error() }
+func (e *finalError) Unwrap() error { return e.err }
+
+// providerFailure records a provider that failed a turn another one answered
+type providerFailure struct {
+	Provider string `json:"provider"`
+	Reason   string `json:"reason"`
+	Error    string `json:"error"`
+}
+
+// withFallback runs a turn with the providers of the chain in order until
+// one answers. It moves on after errors of the classes llm.FallbackReason
+// names; other errors end the turn. run is given a context that reports the
+// token usage of its LLM calls, attributed to the provider, to usage, and
+// toolCaller wrapped to note the tools that ran. Once a tool ran the turn is
+// not repeated with another provider, as that would run the tool again. It
+// returns the provider that answered or failed last and the providers that
+// failed before it.
+func (a *Agent) withFallback(ctx context.Context, chain []*types.LLMProvider, display ui.ToolDisplayInterface, usage *usageTracker, toolCaller llm.ToolCaller, run func(ctx context.Context, processor llm.LLMProcessor, toolCaller llm.ToolCaller) error) (*types.LLMProvider, []providerFailure, error) {
+	var failures []providerFailure
+
+	var toolsRan atomic.Bool
+	var trackedCaller llm.ToolCaller
+	if toolCaller != nil {
+		trackedCaller = func(name string, args map[string]interface{}) (interface{}, error) {
+			toolsRan.Store(true)
+			return toolCaller(name, args)
+		}
+	}
+
+	for i, provider := range chain {
+		processor, err := a.processorFactory.CreateProcessor(provider)
+		if err != nil {
+			return provider, failures, fmt.Errorf("failed to create processor: %v", err)
+		}
+
+		err = run(usage.context(ctx, provider), processor, trackedCaller)
+		if err == nil {
+			return provider, failures, nil
+		}
+
+		var final *finalError
+		if errors.As(err, &final) {
+			return provider, failures, final.err
+		}
+
+		reason := llm.FallbackReason(err)
+		if reason == "" || ctx.Err() != nil || i == len(chain)-1 {
+			return provider, failures, err
+		}
+		if toolsRan.Load() {
+			return provider, failures, fmt.Errorf("%w (not retried with %s, as tools already ran in this turn)", err, chain[i+1].Name)
+		}
+
+		failures = append(failures, providerFailure{Provider: provider.Name, Reason: reason, Error: err.Error()})
+		if display != nil {
+			display.ShowProgress(fmt.Sprintf("⚠️ %s is %s, falling back to %s...", provider.Name, reason, chain[i+1].Name))
+		}
+	}
+
+	// Not reached for a non-empty chain
+	return nil, failures, fmt.Errorf("no provider available")
+}
+
+// recordProvider notes in the response which provider answered the turn and
+// which ones failed before it
+func recordProvider(response *types.AgentResponse, provider *types.LLMProvider, failures []providerFailure) {
+	if provider == nil {
+		return
+	}
+	if response.Data == nil {
+		response.Data = make(map[string]interface{})
+	}
+
+	response.Data["provider_id"] = provider.ID
+	response.Data["provider_name"] = provider.Name
+	response.Data["provider_type"] = provider.Type
+	response.Data["model"] = provider.Model
+	if len(failures) > 0 {
+		response.Data["fallback_from"] = failures
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/profile"
+)
+
+// PromptOverrides holds the user's changes to the composed system prompt
+type PromptOverrides struct {
+	System             string            // replaces the built-in guidance when set
+	ServerInstructions map[string]string // keyed by server name, case-insensitive; empty hides the server's instructions
+}
+
+// SetPromptOverrides replaces the user's system prompt overrides
+func (a *Agent) SetPromptOverrides(overrides PromptOverrides) {
+	a.promptOverrides = overrides
+}
+
+// composeSystemPrompt builds the system prompt for a turn from the
+// instructions of the servers the agent may use. When mcpServerID is set,
+// only that server's instructions are included.
+func (a *Agent) composeSystemPrompt(toolCount int, mcpServerID string) string {
+	reported := a.mcpManager.GetServerInstructions()
+
+	var servers []llm.ServerInstructions
+	for _, server := range a.mcpManager.ListServers() {
+		if mcpServerID != "" && server.ID != mcpServerID {
+			continue
+		}
+		if !server.IsEnabled() || (server.Status != "available" && server.Status != "connected") {
+			continue
+		}
+		if !profile.AllowsServer(a.profile, server) {
+			continue
+		}
+
+		instructions := reported[server.Name]
+		if override, ok := a.serverInstructionsOverride(server.Name); ok {
+			instructions = override
+		}
+		if instructions == "" {
+			continue
+		}
+
+		servers = append(servers, llm.ServerInstructions{
+			Server:       server.Name,
+			Instructions: instructions,
+		})
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Server < servers[j].Server
+	})
+
+	return llm.ComposeSystemPrompt(toolCount, servers, a.promptOverrides.System)
+}
+
+// serverInstructionsOverride looks up the configured instructions for a
+// server. Config keys are lowercased by viper, so names match case-insensitively.
+func (a *Agent) serverInstructionsOverride(serverName string) (string, bool) {
+	for name, instructions := range a.promptOverrides.ServerInstructions {
+		if strings.EqualFold(name, serverName) {
+			return instructions, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/utils"
+)
+
+// ReadToolOutputToolName is the name of the built-in tool that pages through
+// tool outputs which were too large to pass to the model in full
+const ReadToolOutputToolName = "read_tool_output"
+
+// ToolOutputLimits controls how much of a tool result is passed to the model.
+// Results larger than the limit are stored in full and replaced with head and
+// tail excerpts plus a handle for read_tool_output.
+type ToolOutputLimits struct {
+	MaxBytes  int
+	HeadBytes int
+	TailBytes int
+	PerTool   map[string]int // keyed by tool name, case-insensitive
+}
+
+// DefaultToolOutputLimits returns the limits used when none are configured
+func DefaultToolOutputLimits() ToolOutputLimits {
+	return ToolOutputLimits{
+		MaxBytes:  16000,
+		HeadBytes: 6000,
+		TailBytes: 4000,
+	}
+}
+
+// limitFor returns the maximum output size for a tool. Both the prefixed
+// name the model sees and the original MCP tool name are checked.
+func (l ToolOutputLimits) limitFor(names ...string) int {
+	for _, name := range names {
+		for key, limit := range l.PerTool {
+			if strings.EqualFold(key, name) && limit > 0 {
+				return limit
+			}
+		}
+	}
+	return l.MaxBytes
+}
+
+// SetToolOutputLimits replaces the limits applied to tool results
+func (a *Agent) SetToolOutputLimits(limits ToolOutputLimits) {
+	a.outputLimits = limits
+}
+
+// limitToolOutput returns the result unchanged when it fits within the
+// tool's limit. Otherwise the full output is spilled to the data dir and an
+// excerpt with a handle is returned in its place.
+func (a *Agent) limitToolOutput(name, toolName string, result interface{}) interface{} {
+	limit := a.outputLimits.limitFor(name, toolName)
+	if limit <= 0 {
+		return result
+	}
+
+	formatted := llm.FormatToolResult(result)
+	if len(formatted) <= limit {
+		return result
+	}
+
+	handle := uuid.New().String()[:8]
+	if err := a.storage.SaveToolOutput(handle, []byte(formatted)); err != nil {
+		// Without a stored copy there is nothing to page through, so fall back
+		// to a plain truncation
+		return utils.TruncateUTF8(formatted, limit) + fmt.Sprintf("\n... [output truncated, %d bytes total]", len(formatted))
+	}
+
+	head := a.outputLimits.HeadBytes
+	tail := a.outputLimits.TailBytes
+	if head+tail > limit || head+tail == 0 {
+		head = limit * 3 / 5
+		tail = limit - head
+	}
+
+	headText := utils.TruncateUTF8(formatted, head)
+	tailText := utils.TailUTF8(formatted, tail)
+	omitted := len(formatted) - len(headText) - len(tailText)
+
+	var builder strings.Builder
+	builder.WriteString(headText)
+	builder.WriteString(fmt.Sprintf("\n\n... [%d bytes omitted. Full output (%d bytes) stored as handle %q. "+
+		"Call %s with this handle, an offset and a length to read more.] ...\n\n",
+		omitted, len(formatted), handle, ReadToolOutputToolName))
+	builder.WriteString(tailText)
+
+	return builder.String()
+}
+
+// readToolOutputTool describes the built-in paging tool to the model
+func readToolOutputTool() llm.Tool {
+	return llm.Tool{
+		Type: "function",
+		Function: llm.ToolFunction{
+			Name:        ReadToolOutputToolName,
+			Description: "Read part of a large tool output that was truncated. Use the handle from the truncation notice.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"handle": map[string]interface{}{
+						"type":        "string",
+						"description": "Handle of the stored tool output",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Byte offset to start reading from",
+					},
+					"length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of bytes to read",
+					},
+				},
+				"required": []interface{}{"handle"},
+			},
+		},
+	}
+}
+
+// readToolOutput executes the built-in paging tool
+func (a *Agent) readToolOutput(args map[string]interface{}) (interface{}, error) {
+	handle, _ := args["handle"].(string)
+	offset := intArg(args, "offset")
+	length := intArg(args, "length")
+
+	maxLength := a.outputLimits.MaxBytes
+	if length <= 0 || (maxLength > 0 && length > maxLength) {
+		length = maxLength
+	}
+
+	content, offset, total, err := a.storage.ReadToolOutput(handle, offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"handle":      handle,
+		"offset":      offset,
+		"length":      len(content),
+		"total_bytes": total,
+		"content":     content,
+	}
+	if next := offset + len(content); next < total {
+		result["next_offset"] = next
+	}
+
+	return result, nil
+}
+
+// intArg reads an integer argument that may have been decoded as a float
+func intArg(args map[string]interface{}, key string) int {
+	switch v := args[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// SetPrices replaces the price table the cost of LLM calls is computed with
+func (a *Agent) SetPrices(prices *llm.PriceTable) {
+	a.prices = prices
+}
+
+// usageTracker sums the token usage of the LLM calls made for one request
+// and records each call in the usage ledger
+type usageTracker struct {
+	agent     *Agent
+	sessionID string
+	requestID string
+
+	mu     sync.Mutex
+	totals types.UsageTotals
+}
+
+func (a *Agent) newUsageTracker(sessionID, requestID string) *usageTracker {
+	return &usageTracker{agent: a, sessionID: sessionID, requestID: requestID}
+}
+
+// context returns a context that reports the LLM calls made with it, which
+// are attributed to provider, to the tracker
+func (t *usageTracker) context(ctx context.Context, provider *types.LLMProvider) context.Context {
+	return llm.WithUsageRecorder(ctx, func(usage llm.TokenUsage) {
+		entry := t.agent.prices.Entry(provider, usage)
+		entry.Time = time.Now()
+		entry.SessionID = t.sessionID
+		entry.RequestID = t.requestID
+		t.agent.llmManager.RecordUsage(entry)
+
+		// Streaming clients report from their own goroutine
+		t.mu.Lock()
+		t.totals.Add(entry)
+		t.mu.Unlock()
+	})
+}
+
+// Totals returns the usage of the calls recorded so far
+func (t *usageTracker) Totals() types.UsageTotals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totals
+}
+
+// recordUsage notes the usage of the request in the response and, for
+// conversations, that of the whole session
+func recordUsage(response *types.AgentResponse, usage types.UsageTotals, session *types.ConversationSession) {
+	if response.Data == nil {
+		response.Data = make(map[string]interface{})
+	}
+
+	response.Data["usage"] = usage
+	if session != nil {
+		response.Data["session_usage"] = session.Usage
+	}
+}
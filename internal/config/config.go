@@ -9,7 +9,7 @@ import (
 	"github.com/spf13/viper"
 )
 
-func Load() (*types.Config, error) {
+func setDefaults() {
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "localhost")
 	viper.SetDefault("database.type", "memory")
@@ -17,6 +17,13 @@ func Load() (*types.Config, error) {
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
 	viper.SetDefault("agent.timeout", 30)
+	viper.SetDefault("tool_output.max_bytes", 16000)
+	viper.SetDefault("tool_output.head_bytes", 6000)
+	viper.SetDefault("tool_output.tail_bytes", 4000)
+}
+
+func Load() (*types.Config, error) {
+	setDefaults()
 
 	viper.SetEnvPrefix("SYSENG_AGENT")
 	viper.AutomaticEnv()
@@ -47,11 +54,26 @@ func Load() (*types.Config, error) {
 	return &config, nil
 }
 
+// Current returns the configuration already read by the root command,
+// with defaults applied for any missing values
+func Current() (*types.Config, error) {
+	setDefaults()
+
+	var config types.Config
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	return &config, nil
+}
+
 func Save(config *types.Config) error {
 	viper.Set("server", config.Server)
 	viper.Set("database", config.Database)
 	viper.Set("logging", config.Logging)
 	viper.Set("agent", config.Agent)
+	viper.Set("tool_output", config.ToolOutput)
+	viper.Set("prompt", config.Prompt)
 
 	return viper.WriteConfig()
 }
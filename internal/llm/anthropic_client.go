@@ -1,7 +1,10 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
@@ -12,39 +15,83 @@ type AnthropicRequest struct {
 	MaxTokens int                `json:"max_tokens"`
 	Messages  []AnthropicMessage `json:"messages"`
 	System    string             `json:"system,omitempty"`
+	Tools     []AnthropicTool    `json:"tools,omitempty"`
 	Stream    bool               `json:"stream,omitempty"`
 }
 
 // AnthropicMessage represents a message in Anthropic format
 type AnthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string             `json:"role"`
+	Content []AnthropicContent `json:"content"`
 }
 
 // AnthropicResponse represents the response from Anthropic API
 type AnthropicResponse struct {
-	Content []AnthropicContent `json:"content"`
-	Model   string             `json:"model"`
-	Usage   map[string]int     `json:"usage,omitempty"`
+	Content    []AnthropicContent `json:"content"`
+	Model      string             `json:"model"`
+	StopReason string             `json:"stop_reason,omitempty"`
+	Usage      *AnthropicUsage    `json:"usage,omitempty"`
+}
+
+// AnthropicUsage reports the tokens used by a request
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
-// AnthropicContent represents content block in Anthropic response
+// AnthropicContent represents a content block: text, a tool_use block the
+// model sends or the tool_result block answering it
 type AnthropicContent struct {
 	Type string `json:"type"`
-	Text string `json:"text"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// incomplete marks a streamed tool_use block whose input was cut off
+	incomplete bool
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// AnthropicTool describes a tool the model may use
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
 }
 
 // AnthropicStreamEvent represents a streaming event from Anthropic API
 type AnthropicStreamEvent struct {
-	Type  string                `json:"type"`
-	Index int                   `json:"index,omitempty"`
-	Delta *AnthropicStreamDelta `json:"delta,omitempty"`
+	Type         string                `json:"type"`
+	Index        int                   `json:"index,omitempty"`
+	Delta        *AnthropicStreamDelta `json:"delta,omitempty"`
+	ContentBlock *AnthropicContent     `json:"content_block,omitempty"` // content_block_start
+	Message      *AnthropicResponse    `json:"message,omitempty"`       // message_start
+	Usage        *AnthropicUsage       `json:"usage,omitempty"`         // message_delta
+	Error        *AnthropicStreamError `json:"error,omitempty"`         // error
 }
 
-// AnthropicStreamDelta represents the delta content in streaming
+// AnthropicStreamDelta represents the delta content in streaming: text,
+// partial tool input JSON or, in message_delta events, the stop reason
 type AnthropicStreamDelta struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+// AnthropicStreamError is the error of an error event, e.g. overloaded_error
+type AnthropicStreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
 }
 
 // AnthropicClient implements LLMClient for Anthropic models
@@ -63,6 +110,7 @@ func NewAnthropicClient(provider *types.LLMProvider) *AnthropicClient {
 	httpClient := NewHTTPClient(HTTPConfig{
 		Timeout: DefaultHTTPTimeout,
 		Headers: headers,
+		Retry:   RetryPolicyForProvider(provider),
 	})
 
 	return &AnthropicClient{
@@ -73,6 +121,11 @@ func NewAnthropicClient(provider *types.LLMProvider) *AnthropicClient {
 
 // ProcessMessage processes a simple message
 func (c *AnthropicClient) ProcessMessage(message string) (string, error) {
+	return c.ProcessMessageContext(context.Background(), message)
+}
+
+// ProcessMessageContext is like ProcessMessage but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessMessageContext(ctx context.Context, message string) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderAnthropic)
 	}
@@ -86,30 +139,34 @@ func (c *AnthropicClient) ProcessMessage(message string) (string, error) {
 		Model:     c.provider.Model,
 		MaxTokens: AnthropicDefaultMaxTokens,
 		Messages: []AnthropicMessage{
-			{Role: RoleUser, Content: message},
+			anthropicTextMessage(RoleUser, message),
 		},
 	}
 
-	return c.executeRequest(endpoint, reqBody)
+	return c.executeRequest(ctx, endpoint, reqBody)
 }
 
-// ProcessWithTools processes a message with tools (currently limited support)
+// ProcessWithTools processes a message with tools using native tool use
 func (c *AnthropicClient) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
-	// Anthropic doesn't have native function calling like OpenAI yet
-	// We'll enhance the message with tool information as context
-	if len(tools) > 0 {
-		toolContext := "Available tools:\n"
-		for _, tool := range tools {
-			toolContext += fmt.Sprintf("- %s: %s\n", tool.Function.Name, tool.Function.Description)
-		}
-		message = toolContext + "\n" + message + "\n\nPlease respond in a way that indicates if you would use any of these tools and how."
+	return c.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	messages := []AnthropicMessage{
+		anthropicTextMessage(RoleUser, message),
 	}
 
-	return c.ProcessMessage(message)
+	return c.processToolConversation(ctx, "", messages, tools, toolCaller)
 }
 
 // ProcessConversation processes a message within conversation context
 func (c *AnthropicClient) ProcessConversation(session *types.ConversationSession) (string, error) {
+	return c.ProcessConversationContext(context.Background(), session)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessConversationContext(ctx context.Context, session *types.ConversationSession) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderAnthropic)
 	}
@@ -120,31 +177,48 @@ func (c *AnthropicClient) ProcessConversation(session *types.ConversationSession
 	}
 
 	// Convert conversation to Anthropic format
-	messages := c.convertConversationToAnthropic(session)
+	messages := c.convertConversationToAnthropic(fitConversation(session, c.provider, nil))
 
 	reqBody := AnthropicRequest{
 		Model:     c.provider.Model,
 		MaxTokens: AnthropicDefaultMaxTokens,
 		Messages:  messages,
-		System:    DefaultSystemPrompt + " Maintain context from the conversation history.",
+		System:    anthropicSystemPrompt(session),
 	}
 
-	return c.executeRequest(endpoint, reqBody)
+	return c.executeRequest(ctx, endpoint, reqBody)
 }
 
 // ProcessConversationWithTools processes conversation with tools
 func (c *AnthropicClient) ProcessConversationWithTools(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
-	// For now, add tool context to the system prompt
-	systemPrompt := DefaultSystemPrompt + " Maintain context from the conversation history."
+	return c.ProcessConversationWithToolsContext(context.Background(), session, tools, toolCaller)
+}
 
-	if len(tools) > 0 {
-		systemPrompt += "\n\nAvailable tools:\n"
-		for _, tool := range tools {
-			systemPrompt += fmt.Sprintf("- %s: %s\n", tool.Function.Name, tool.Function.Description)
+// ProcessConversationWithToolsContext is like ProcessConversationWithTools but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessConversationWithToolsContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	messages := c.convertConversationToAnthropic(fitConversation(session, c.provider, tools))
+
+	return c.processToolConversation(ctx, anthropicSystemPrompt(session), messages, tools, toolCaller)
+}
+
+// fitAnthropicToolResults shortens the tool_result blocks when the loop's
+// messages no longer fit in budget tokens
+func fitAnthropicToolResults(messages []AnthropicMessage, budget int) {
+	var results []*string
+	for i := range messages {
+		for j := range messages[i].Content {
+			if messages[i].Content[j].Type == ContentTypeToolResult {
+				results = append(results, &messages[i].Content[j].Content)
+			}
 		}
-		systemPrompt += "\nWhen you need to use tools, clearly indicate which tool you would use and with what parameters."
 	}
+	fitToolResults(results, estimateJSONTokens(messages), budget)
+}
 
+// processToolConversation handles the tool use loop: tool_use blocks in the
+// model's answer are executed and their results sent back as tool_result
+// blocks until the model answers without using a tool
+func (c *AnthropicClient) processToolConversation(ctx context.Context, systemPrompt string, messages []AnthropicMessage, tools []Tool, toolCaller ToolCaller) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderAnthropic)
 	}
@@ -154,33 +228,235 @@ func (c *AnthropicClient) ProcessConversationWithTools(session *types.Conversati
 		endpoint = c.provider.Endpoint
 	}
 
-	messages := c.convertConversationToAnthropic(session)
+	// Create tool processor for validation and execution
+	toolProcessor := NewToolProcessor(tools, toolCaller)
+	anthropicTools := ConvertToolsToAnthropic(tools)
+	budget := messageBudget(c.provider, systemPrompt, tools)
 
-	reqBody := AnthropicRequest{
-		Model:     c.provider.Model,
-		MaxTokens: AnthropicDefaultMaxTokens,
-		Messages:  messages,
-		System:    systemPrompt,
+	// Iterative conversation with tools
+	for iteration := 0; iteration < MaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		fitAnthropicToolResults(messages, budget)
+
+		reqBody := AnthropicRequest{
+			Model:     c.provider.Model,
+			MaxTokens: AnthropicDefaultMaxTokens,
+			Messages:  messages,
+			System:    systemPrompt,
+			Tools:     anthropicTools,
+		}
+
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
+		if err != nil {
+			return "", err
+		}
+
+		var anthropicResp AnthropicResponse
+		if err := UnmarshalJSONResponse(resp, &anthropicResp); err != nil {
+			return "", err
+		}
+		recordUsage(ctx, anthropicUsage(anthropicResp.Usage))
+
+		if len(anthropicResp.Content) == 0 {
+			return "", fmt.Errorf(ErrNoContentInResponse)
+		}
+
+		messages = append(messages, AnthropicMessage{
+			Role:    RoleAssistant,
+			Content: anthropicResp.Content,
+		})
+
+		var toolUses []AnthropicContent
+		for _, block := range anthropicResp.Content {
+			if block.Type == ContentTypeToolUse {
+				toolUses = append(toolUses, block)
+			}
+		}
+
+		// If no tool use, we're done
+		if len(toolUses) == 0 {
+			return anthropicText(anthropicResp.Content)
+		}
+
+		// Every tool_use block needs a tool_result in the next user message
+		var results []AnthropicContent
+		for _, toolUse := range toolUses {
+			results = append(results, c.executeToolUse(toolProcessor, toolCaller, toolUse))
+		}
+
+		messages = append(messages, AnthropicMessage{
+			Role:    RoleUser,
+			Content: results,
+		})
+	}
+
+	return "", fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
+}
+
+// executeToolUse runs the tool the model asked for and returns the
+// tool_result block answering it
+func (c *AnthropicClient) executeToolUse(toolProcessor *ToolProcessor, toolCaller ToolCaller, toolUse AnthropicContent) AnthropicContent {
+	result := AnthropicContent{
+		Type:      ContentTypeToolResult,
+		ToolUseID: toolUse.ID,
+	}
+
+	if toolCaller == nil {
+		result.Content = fmt.Sprintf("Error: tool %s is not available", toolUse.Name)
+		result.IsError = true
+		return result
+	}
+
+	if toolUse.incomplete {
+		result.Content = fmt.Sprintf("Error: the input for tool %s was cut off before it was complete", toolUse.Name)
+		result.IsError = true
+		return result
+	}
+
+	var args map[string]interface{}
+	if len(toolUse.Input) > 0 {
+		if err := json.Unmarshal(toolUse.Input, &args); err != nil {
+			result.Content = fmt.Sprintf(ErrParsingArguments, err)
+			result.IsError = true
+			return result
+		}
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	output, err := toolProcessor.ExecuteTool(toolUse.Name, args)
+	if err != nil {
+		result.Content = fmt.Sprintf("Error: %v", err)
+		result.IsError = true
+		return result
+	}
+
+	// Use utility function to format result
+	result.Content = FormatToolResult(output)
+	return result
+}
+
+// ConvertToolsToAnthropic converts tools to the Messages API format. The
+// input schema must be an object schema.
+func ConvertToolsToAnthropic(tools []Tool) []AnthropicTool {
+	var anthropicTools []AnthropicTool
+
+	for _, tool := range tools {
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{}
+		}
+		if _, ok := schema["type"]; !ok {
+			schema = copySchema(schema)
+			schema["type"] = "object"
+		}
+
+		anthropicTools = append(anthropicTools, AnthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	return anthropicTools
+}
+
+// copySchema returns a shallow copy so that the caller's schema is not modified
+func copySchema(schema map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(schema)+1)
+	for key, value := range schema {
+		copied[key] = value
+	}
+	return copied
+}
+
+// anthropicTextMessage creates a message with a single text block
+func anthropicTextMessage(role, text string) AnthropicMessage {
+	return AnthropicMessage{
+		Role:    role,
+		Content: []AnthropicContent{{Type: ContentTypeText, Text: text}},
+	}
+}
+
+// anthropicText joins the text blocks of a response
+func anthropicText(content []AnthropicContent) (string, error) {
+	var texts []string
+	for _, block := range content {
+		if block.Type == ContentTypeText && block.Text != "" {
+			texts = append(texts, block.Text)
+		}
 	}
 
-	return c.executeRequest(endpoint, reqBody)
+	if len(texts) == 0 {
+		return "", fmt.Errorf(ErrNoTextContentFound)
+	}
+	return strings.Join(texts, "\n"), nil
 }
 
-// convertConversationToAnthropic converts conversation session to Anthropic format
+// anthropicSystemPrompt returns the session's composed system prompt, or the
+// default prompt when none was set
+func anthropicSystemPrompt(session *types.ConversationSession) string {
+	if session.SystemPrompt != "" {
+		return session.SystemPrompt
+	}
+	return DefaultSystemPrompt + " Maintain context from the conversation history."
+}
+
+// convertConversationToAnthropic converts conversation session to Anthropic format.
+// Tool calls become tool_use blocks and tool results tool_result blocks in a
+// user message; consecutive messages of the same role are merged.
 func (c *AnthropicClient) convertConversationToAnthropic(session *types.ConversationSession) []AnthropicMessage {
 	var messages []AnthropicMessage
 
 	for _, msg := range session.Messages {
-		// Anthropic uses "user" and "assistant" roles
-		role := msg.Role
-		if role == "system" {
+		var role string
+		var blocks []AnthropicContent
+
+		switch msg.Role {
+		case RoleSystem:
 			// Skip system messages as they go in the system field
 			continue
+		case RoleTool:
+			role = RoleUser
+			blocks = append(blocks, AnthropicContent{
+				Type:      ContentTypeToolResult,
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			})
+		default:
+			role = msg.Role
+			if msg.Content != "" {
+				blocks = append(blocks, AnthropicContent{Type: ContentTypeText, Text: msg.Content})
+			}
+			for _, toolCall := range msg.ToolCalls {
+				input := json.RawMessage(toolCall.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, AnthropicContent{
+					Type:  ContentTypeToolUse,
+					ID:    toolCall.ID,
+					Name:  toolCall.Function.Name,
+					Input: input,
+				})
+			}
+		}
+
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if last := len(messages) - 1; last >= 0 && messages[last].Role == role {
+			messages[last].Content = append(messages[last].Content, blocks...)
+			continue
 		}
 
 		messages = append(messages, AnthropicMessage{
 			Role:    role,
-			Content: msg.Content,
+			Content: blocks,
 		})
 	}
 
@@ -188,8 +464,8 @@ func (c *AnthropicClient) convertConversationToAnthropic(session *types.Conversa
 }
 
 // executeRequest executes a request to Anthropic API
-func (c *AnthropicClient) executeRequest(endpoint string, reqBody AnthropicRequest) (string, error) {
-	resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+func (c *AnthropicClient) executeRequest(ctx context.Context, endpoint string, reqBody AnthropicRequest) (string, error) {
+	resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 	if err != nil {
 		return "", err
 	}
@@ -199,19 +475,13 @@ func (c *AnthropicClient) executeRequest(endpoint string, reqBody AnthropicReque
 	if err := UnmarshalJSONResponse(resp, &anthropicResp); err != nil {
 		return "", err
 	}
+	recordUsage(ctx, anthropicUsage(anthropicResp.Usage))
 
 	if len(anthropicResp.Content) == 0 {
 		return "", fmt.Errorf(ErrNoContentInResponse)
 	}
 
-	// Return the first text content block
-	for _, content := range anthropicResp.Content {
-		if content.Type == ContentTypeText {
-			return content.Text, nil
-		}
-	}
-
-	return "", fmt.Errorf(ErrNoTextContentFound)
+	return anthropicText(anthropicResp.Content)
 }
 
 // Interface compliance methods
@@ -230,8 +500,7 @@ func (c *AnthropicClient) IsHealthy() bool {
 }
 
 func (c *AnthropicClient) SupportsFunctionCalling() bool {
-	// Anthropic doesn't have native function calling yet
-	return false
+	return true
 }
 
 func (c *AnthropicClient) SupportsConversation() bool {
@@ -240,7 +509,7 @@ func (c *AnthropicClient) SupportsConversation() bool {
 
 func (c *AnthropicClient) GetCapabilities() ClientCapabilities {
 	return ClientCapabilities{
-		SupportsTools:        false, // No native tool support yet
+		SupportsTools:        true,
 		SupportsConversation: true,
 		SupportsStreaming:    true,
 		MaxTokens:            getMaxTokensForAnthropicModel(c.provider.Model),
@@ -250,6 +519,11 @@ func (c *AnthropicClient) GetCapabilities() ClientCapabilities {
 
 // ProcessMessageStream processes a message and streams the response
 func (c *AnthropicClient) ProcessMessageStream(message string) (<-chan StreamChunk, error) {
+	return c.ProcessMessageStreamContext(context.Background(), message)
+}
+
+// ProcessMessageStreamContext is like ProcessMessageStream but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessMessageStreamContext(ctx context.Context, message string) (<-chan StreamChunk, error) {
 	if c.provider.APIKey == "" {
 		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderAnthropic)
 	}
@@ -267,12 +541,12 @@ func (c *AnthropicClient) ProcessMessageStream(message string) (<-chan StreamChu
 			Model:     c.provider.Model,
 			MaxTokens: 4096,
 			Messages: []AnthropicMessage{
-				{Role: RoleUser, Content: message},
+				anthropicTextMessage(RoleUser, message),
 			},
 			Stream: true,
 		}
 
-		resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 		if err != nil {
 			ch <- StreamChunk{Error: err}
 			return
@@ -288,7 +562,7 @@ func (c *AnthropicClient) ProcessMessageStream(message string) (<-chan StreamChu
 		streamHandler := NewStreamHandler(resp.Body)
 		parser := &AnthropicSSEParser{}
 
-		if err := streamHandler.ProcessSSEStream(ch, parser); err != nil {
+		if err := streamHandler.ProcessSSEStreamContext(ctx, ch, parser); err != nil {
 			ch <- StreamChunk{Error: err}
 		}
 	}()
@@ -298,6 +572,11 @@ func (c *AnthropicClient) ProcessMessageStream(message string) (<-chan StreamChu
 
 // ProcessConversationStream processes conversation and streams the response
 func (c *AnthropicClient) ProcessConversationStream(session *types.ConversationSession) (<-chan StreamChunk, error) {
+	return c.ProcessConversationStreamContext(context.Background(), session)
+}
+
+// ProcessConversationStreamContext is like ProcessConversationStream but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessConversationStreamContext(ctx context.Context, session *types.ConversationSession) (<-chan StreamChunk, error) {
 	if c.provider.APIKey == "" {
 		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderAnthropic)
 	}
@@ -307,8 +586,8 @@ func (c *AnthropicClient) ProcessConversationStream(session *types.ConversationS
 		defer close(ch)
 
 		// Convert conversation to Anthropic format
-		messages := c.convertConversationToAnthropic(session)
-		systemPrompt := DefaultSystemPrompt + " Maintain context from the conversation history."
+		messages := c.convertConversationToAnthropic(fitConversation(session, c.provider, nil))
+		systemPrompt := anthropicSystemPrompt(session)
 
 		endpoint := AnthropicMessagesURL
 		if c.provider.Endpoint != "" {
@@ -323,7 +602,7 @@ func (c *AnthropicClient) ProcessConversationStream(session *types.ConversationS
 			Stream:    true,
 		}
 
-		resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 		if err != nil {
 			ch <- StreamChunk{Error: err}
 			return
@@ -339,7 +618,7 @@ func (c *AnthropicClient) ProcessConversationStream(session *types.ConversationS
 		streamHandler := NewStreamHandler(resp.Body)
 		parser := &AnthropicSSEParser{}
 
-		if err := streamHandler.ProcessSSEStream(ch, parser); err != nil {
+		if err := streamHandler.ProcessSSEStreamContext(ctx, ch, parser); err != nil {
 			ch <- StreamChunk{Error: err}
 		}
 	}()
@@ -352,6 +631,127 @@ func (c *AnthropicClient) SupportsStreaming() bool {
 	return true
 }
 
+// ProcessConversationWithToolsStream processes conversation with tools and
+// streams the response. tool_use blocks are assembled from the streamed
+// input JSON and executed when the turn ends, then streaming resumes with
+// the tool results.
+func (c *AnthropicClient) ProcessConversationWithToolsStream(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (<-chan StreamChunk, error) {
+	return c.ProcessConversationWithToolsStreamContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationWithToolsStreamContext is like ProcessConversationWithToolsStream but aborts the request when ctx is done
+func (c *AnthropicClient) ProcessConversationWithToolsStreamContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (<-chan StreamChunk, error) {
+	if c.provider.APIKey == "" {
+		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderAnthropic)
+	}
+
+	messages := c.convertConversationToAnthropic(fitConversation(session, c.provider, tools))
+	systemPrompt := anthropicSystemPrompt(session)
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+
+		if err := c.streamToolConversation(ctx, systemPrompt, messages, tools, toolCaller, ch); err != nil {
+			ch <- StreamChunk{Error: err}
+			return
+		}
+		ch <- StreamChunk{Done: true}
+	}()
+
+	return ch, nil
+}
+
+// streamToolConversation is the streaming counterpart of processToolConversation
+func (c *AnthropicClient) streamToolConversation(ctx context.Context, systemPrompt string, messages []AnthropicMessage, tools []Tool, toolCaller ToolCaller, ch chan<- StreamChunk) error {
+	endpoint := AnthropicMessagesURL
+	if c.provider.Endpoint != "" {
+		endpoint = c.provider.Endpoint
+	}
+
+	// Create tool processor for validation and execution
+	toolProcessor := NewToolProcessor(tools, toolCaller)
+	anthropicTools := ConvertToolsToAnthropic(tools)
+	budget := messageBudget(c.provider, systemPrompt, tools)
+	streamed := false
+
+	for iteration := 0; iteration < MaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fitAnthropicToolResults(messages, budget)
+
+		reqBody := AnthropicRequest{
+			Model:     c.provider.Model,
+			MaxTokens: AnthropicDefaultMaxTokens,
+			Messages:  messages,
+			System:    systemPrompt,
+			Tools:     anthropicTools,
+			Stream:    true,
+		}
+
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode >= 400 {
+			body, _ := ReadResponseBody(resp)
+			return CheckStatusCode(resp, body)
+		}
+
+		// Separate the text of this turn from that of the previous ones
+		parser := &AnthropicSSEParser{}
+		if streamed {
+			parser.prefix = "\n\n"
+		}
+
+		err = NewStreamHandler(resp.Body).ForwardSSEStream(ch, parser)
+		resp.Body.Close()
+		recordUsage(ctx, parser.Usage())
+		if err != nil {
+			return err
+		}
+
+		content := parser.Content()
+		if len(content) == 0 {
+			return nil
+		}
+
+		messages = append(messages, AnthropicMessage{
+			Role:    RoleAssistant,
+			Content: content,
+		})
+
+		var results []AnthropicContent
+		for _, block := range content {
+			switch block.Type {
+			case ContentTypeText:
+				streamed = streamed || block.Text != ""
+			case ContentTypeToolUse:
+				results = append(results, c.executeToolUse(toolProcessor, toolCaller, block))
+			}
+		}
+
+		// If no tool use, we're done
+		if len(results) == 0 {
+			return nil
+		}
+
+		messages = append(messages, AnthropicMessage{
+			Role:    RoleUser,
+			Content: results,
+		})
+	}
+
+	return fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
+}
+
+// SupportsToolStreaming indicates if this client can stream while calling tools
+func (c *AnthropicClient) SupportsToolStreaming() bool {
+	return true
+}
+
 // getMaxTokensForAnthropicModel returns the maximum token limit for Anthropic models
 func getMaxTokensForAnthropicModel(model string) int {
 	switch model {
@@ -368,6 +768,10 @@ func getMaxTokensForAnthropicModel(model string) int {
 	case ModelClaudeInstant:
 		return ClaudeInstantTokens
 	default:
+		// Later Claude 3 models share the window of the first ones
+		if strings.HasPrefix(model, "claude-3") {
+			return Claude3SonnetTokens
+		}
 		return ClaudeInstantTokens
 	}
 }
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// anthropicStandIn is a local Messages API that answers each request with
+// the response reply returns for it
+type anthropicStandIn struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []AnthropicRequest
+	headers  []http.Header
+}
+
+func newAnthropicStandIn(t *testing.T, reply func(request AnthropicRequest, n int) AnthropicResponse) *anthropicStandIn {
+	t.Helper()
+
+	standIn := &anthropicStandIn{}
+	standIn.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request AnthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		standIn.mu.Lock()
+		standIn.requests = append(standIn.requests, request)
+		standIn.headers = append(standIn.headers, r.Header.Clone())
+		n := len(standIn.requests)
+		standIn.mu.Unlock()
+
+		w.Header().Set(HeaderContentType, ContentTypeJSON)
+		json.NewEncoder(w).Encode(reply(request, n))
+	}))
+	t.Cleanup(standIn.Close)
+
+	return standIn
+}
+
+func (s *anthropicStandIn) client() *AnthropicClient {
+	return NewAnthropicClient(&types.LLMProvider{
+		Name:     "stand-in",
+		Type:     ProviderAnthropic,
+		APIKey:   "test-key",
+		Model:    "claude-test",
+		Endpoint: s.URL,
+		Config:   map[string]interface{}{ConfigMaxRetries: 0},
+	})
+}
+
+var echoTool = Tool{
+	Type: "function",
+	Function: ToolFunction{
+		Name:        "echo",
+		Description: "Echoes its input",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"text"},
+		},
+	},
+}
+
+func toolUseResponse(id string) AnthropicResponse {
+	return AnthropicResponse{
+		Model:      "claude-test",
+		StopReason: "tool_use",
+		Content: []AnthropicContent{
+			{Type: ContentTypeText, Text: "Let me check."},
+			{Type: ContentTypeToolUse, ID: id, Name: "echo", Input: json.RawMessage(`{"text":"hi"}`)},
+		},
+	}
+}
+
+func TestAnthropicToolUseRoundTrip(t *testing.T) {
+	standIn := newAnthropicStandIn(t, func(request AnthropicRequest, n int) AnthropicResponse {
+		if n == 1 {
+			return toolUseResponse("toolu_1")
+		}
+		return AnthropicResponse{
+			Model:      "claude-test",
+			StopReason: "end_turn",
+			Content:    []AnthropicContent{{Type: ContentTypeText, Text: "The tool said hi back."}},
+		}
+	})
+
+	var calls []map[string]interface{}
+	toolCaller := func(name string, args map[string]interface{}) (interface{}, error) {
+		if name != "echo" {
+			return nil, fmt.Errorf("unexpected tool %s", name)
+		}
+		calls = append(calls, args)
+		return "hi back", nil
+	}
+
+	answer, err := standIn.client().ProcessWithTools("Say hi", []Tool{echoTool}, toolCaller)
+	if err != nil {
+		t.Fatalf("ProcessWithTools: %v", err)
+	}
+	if answer != "The tool said hi back." {
+		t.Errorf("answer = %q", answer)
+	}
+
+	if len(calls) != 1 || calls[0]["text"] != "hi" {
+		t.Fatalf("tool calls = %v, want one with text=hi", calls)
+	}
+	if len(standIn.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(standIn.requests))
+	}
+
+	first := standIn.requests[0]
+	if got := standIn.headers[0].Get(HeaderAPIKey); got != "test-key" {
+		t.Errorf("%s = %q", HeaderAPIKey, got)
+	}
+	if got := standIn.headers[0].Get(HeaderAnthropicVersion); got != AnthropicAPIVersion {
+		t.Errorf("%s = %q", HeaderAnthropicVersion, got)
+	}
+	if len(first.Tools) != 1 || first.Tools[0].Name != "echo" || first.Tools[0].InputSchema["type"] != "object" {
+		t.Errorf("tools = %+v", first.Tools)
+	}
+
+	// The follow-up repeats the tool_use turn and answers it with a
+	// tool_result block
+	second := standIn.requests[1]
+	if len(second.Messages) != 3 {
+		t.Fatalf("follow-up has %d messages, want 3", len(second.Messages))
+	}
+	assistant, user := second.Messages[1], second.Messages[2]
+	if assistant.Role != RoleAssistant || len(assistant.Content) != 2 || assistant.Content[1].Type != ContentTypeToolUse || assistant.Content[1].ID != "toolu_1" {
+		t.Errorf("assistant turn = %+v", assistant)
+	}
+	if user.Role != RoleUser || len(user.Content) != 1 {
+		t.Fatalf("tool result turn = %+v", user)
+	}
+	result := user.Content[0]
+	if result.Type != ContentTypeToolResult || result.ToolUseID != "toolu_1" || result.Content != "hi back" || result.IsError {
+		t.Errorf("tool_result = %+v", result)
+	}
+}
+
+func TestAnthropicToolErrorIsReported(t *testing.T) {
+	standIn := newAnthropicStandIn(t, func(request AnthropicRequest, n int) AnthropicResponse {
+		if n == 1 {
+			return toolUseResponse("toolu_1")
+		}
+		return AnthropicResponse{StopReason: "end_turn", Content: []AnthropicContent{{Type: ContentTypeText, Text: "It failed."}}}
+	})
+
+	toolCaller := func(name string, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	if _, err := standIn.client().ProcessWithTools("Say hi", []Tool{echoTool}, toolCaller); err != nil {
+		t.Fatalf("ProcessWithTools: %v", err)
+	}
+
+	result := standIn.requests[1].Messages[2].Content[0]
+	if !result.IsError || !strings.Contains(result.Content, "connection refused") {
+		t.Errorf("tool_result = %+v, want the error", result)
+	}
+}
+
+func TestAnthropicToolLoopStopsAtMaxIterations(t *testing.T) {
+	standIn := newAnthropicStandIn(t, func(request AnthropicRequest, n int) AnthropicResponse {
+		return toolUseResponse(fmt.Sprintf("toolu_%d", n))
+	})
+
+	calls := 0
+	toolCaller := func(name string, args map[string]interface{}) (interface{}, error) {
+		calls++
+		return "again", nil
+	}
+
+	_, err := standIn.client().ProcessWithTools("Loop forever", []Tool{echoTool}, toolCaller)
+	if err == nil || err.Error() != fmt.Sprintf(ErrMaxIterationsExceeded, MaxToolIterations) {
+		t.Fatalf("error = %v, want the iteration limit", err)
+	}
+	if len(standIn.requests) != MaxToolIterations {
+		t.Errorf("sent %d requests, want %d", len(standIn.requests), MaxToolIterations)
+	}
+	if calls != MaxToolIterations {
+		t.Errorf("ran %d tools, want %d", calls, MaxToolIterations)
+	}
+}
+
+func TestAnthropicSSEParserCutOffToolInput(t *testing.T) {
+	parser := &AnthropicSSEParser{}
+	for _, line := range []string{
+		`data: {"type":"message_start","message":{"model":"claude-test","content":[]}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"echo","input":{}}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"text\": \"hel"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"max_tokens"}}`,
+		`data: {"type":"message_stop"}`,
+	} {
+		if _, _, err := parser.ParseLine(line); err != nil {
+			t.Fatalf("ParseLine(%s): %v", line, err)
+		}
+	}
+
+	content := parser.Content()
+	if len(content) != 1 || string(content[0].Input) != "{}" {
+		t.Fatalf("content = %+v, want the tool_use block with empty input", content)
+	}
+
+	// The block can be sent back, and its result reports the cut-off input
+	if _, err := json.Marshal(AnthropicMessage{Role: RoleAssistant, Content: content}); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	called := false
+	toolCaller := func(name string, args map[string]interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+	client := &AnthropicClient{}
+	result := client.executeToolUse(NewToolProcessor([]Tool{echoTool}, toolCaller), toolCaller, content[0])
+	if called {
+		t.Error("tool ran with cut-off input")
+	}
+	if !result.IsError || result.ToolUseID != "toolu_1" {
+		t.Errorf("tool_result = %+v, want an error", result)
+	}
+}
@@ -47,6 +47,8 @@ func (f *DefaultClientFactory) CreateClient(provider *types.LLMProvider) (LLMCli
 		client = NewOpenAIClient(provider)
 	case "anthropic", "claude":
 		client = NewAnthropicClient(provider)
+	case "google", "gemini":
+		client = NewGeminiClient(provider)
 	case "local", "ollama", "llama":
 		client = NewLocalClient(provider)
 	default:
@@ -72,6 +74,8 @@ func (f *DefaultClientFactory) SupportedTypes() []string {
 		"openai",
 		"anthropic",
 		"claude",
+		"google",
+		"gemini",
 		"local", 
 		"ollama",
 		"llama",
@@ -108,6 +112,10 @@ func (f *DefaultClientFactory) ValidateProvider(provider *types.LLMProvider) err
 		if provider.APIKey == "" {
 			return fmt.Errorf("API key is required for Anthropic provider")
 		}
+	case "google", "gemini":
+		if provider.APIKey == "" {
+			return fmt.Errorf("API key is required for Google provider")
+		}
 	case "local", "ollama", "llama":
 		if provider.Endpoint == "" {
 			return fmt.Errorf("endpoint is required for local provider")
@@ -1,6 +1,8 @@
 package llm
 
 import (
+	"context"
+
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
 
@@ -9,6 +11,9 @@ type LLMClient interface {
 	// ProcessMessage processes a simple message and returns the response
 	ProcessMessage(message string) (string, error)
 	
+	// ProcessMessageContext is like ProcessMessage but aborts when ctx is done
+	ProcessMessageContext(ctx context.Context, message string) (string, error)
+	
 	// GetProviderInfo returns information about the LLM provider
 	GetProviderInfo() ProviderInfo
 	
@@ -21,6 +26,9 @@ type ToolSupport interface {
 	// ProcessWithTools processes a message with available tools
 	ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error)
 	
+	// ProcessWithToolsContext is like ProcessWithTools but stops the tool loop when ctx is done
+	ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error)
+	
 	// SupportsFunctionCalling indicates if this client supports native function calling
 	SupportsFunctionCalling() bool
 }
@@ -30,9 +38,15 @@ type ConversationSupport interface {
 	// ProcessConversation processes a message within conversation context
 	ProcessConversation(session *types.ConversationSession) (string, error)
 	
+	// ProcessConversationContext is like ProcessConversation but aborts when ctx is done
+	ProcessConversationContext(ctx context.Context, session *types.ConversationSession) (string, error)
+	
 	// ProcessConversationWithTools processes conversation with tools
 	ProcessConversationWithTools(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error)
 	
+	// ProcessConversationWithToolsContext is like ProcessConversationWithTools but stops the tool loop when ctx is done
+	ProcessConversationWithToolsContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error)
+	
 	// SupportsConversation indicates if this client supports conversation context
 	SupportsConversation() bool
 }
@@ -42,18 +56,54 @@ type StreamingSupport interface {
 	// ProcessMessageStream processes a message and streams the response
 	ProcessMessageStream(message string) (<-chan StreamChunk, error)
 	
+	// ProcessMessageStreamContext is like ProcessMessageStream but ends the stream when ctx is done
+	ProcessMessageStreamContext(ctx context.Context, message string) (<-chan StreamChunk, error)
+	
 	// ProcessConversationStream processes conversation and streams the response
 	ProcessConversationStream(session *types.ConversationSession) (<-chan StreamChunk, error)
 	
+	// ProcessConversationStreamContext is like ProcessConversationStream but ends the stream when ctx is done
+	ProcessConversationStreamContext(ctx context.Context, session *types.ConversationSession) (<-chan StreamChunk, error)
+	
 	// SupportsStreaming indicates if this client supports streaming
 	SupportsStreaming() bool
 }
 
+// ToolStreamingSupport defines interface for streaming clients that can stream
+// through the tool calling loop: text is passed on as the model produces it,
+// tools are executed when a turn ends and streaming resumes with their results
+type ToolStreamingSupport interface {
+	StreamingSupport
+	
+	// ProcessConversationWithToolsStream processes conversation with tools and streams the response
+	ProcessConversationWithToolsStream(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (<-chan StreamChunk, error)
+	
+	// ProcessConversationWithToolsStreamContext is like ProcessConversationWithToolsStream but ends the stream when ctx is done
+	ProcessConversationWithToolsStreamContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (<-chan StreamChunk, error)
+	
+	// SupportsToolStreaming indicates if this client can stream while calling tools
+	SupportsToolStreaming() bool
+}
+
 // StreamChunk represents a chunk of streaming response
 type StreamChunk struct {
 	Content string
 	Error   error
 	Done    bool
+	
+	// StopReason tells why the model ended its turn, e.g. max_tokens, and
+	// Usage the tokens the turn used, where the provider reports them
+	StopReason string
+	Usage      *TokenUsage
+}
+
+// TokenUsage counts the tokens of a request. InputTokens excludes the
+// prompt tokens read from or written to the provider's prompt cache.
+type TokenUsage struct {
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
 }
 
 // ProviderInfo contains information about the LLM provider
@@ -1,7 +1,6 @@
 package llm
 
 import (
-	"fmt"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/utils"
 )
@@ -12,6 +11,13 @@ type OpenAIRequest struct {
 	Tools      []Tool      `json:"tools,omitempty"`
 	ToolChoice interface{} `json:"tool_choice,omitempty"`
 	Stream     bool        `json:"stream,omitempty"`
+
+	// StreamOptions asks for the usage to be sent at the end of a stream
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type Message struct {
@@ -45,8 +51,19 @@ type ToolFunction struct {
 }
 
 type OpenAIResponse struct {
-	Choices []Choice  `json:"choices"`
-	Error   *APIError `json:"error,omitempty"`
+	Choices []Choice     `json:"choices"`
+	Usage   *OpenAIUsage `json:"usage,omitempty"`
+	Error   *APIError    `json:"error,omitempty"`
+}
+
+// OpenAIUsage reports the tokens used by a request. PromptTokens includes
+// the cached tokens.
+type OpenAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
 }
 
 type Choice struct {
@@ -61,14 +78,27 @@ type APIError struct {
 // OpenAIStreamResponse represents streaming response from OpenAI
 type OpenAIStreamResponse struct {
 	Choices []StreamChoice `json:"choices"`
+	Usage   *OpenAIUsage   `json:"usage,omitempty"` // last event, with include_usage
 }
 
 type StreamChoice struct {
-	Delta Delta `json:"delta"`
+	Delta        Delta  `json:"delta"`
+	FinishReason string `json:"finish_reason,omitempty"`
 }
 
 type Delta struct {
-	Content string `json:"content"`
+	Content   string          `json:"content"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// ToolCallDelta is a fragment of a streamed tool call. The first fragment of
+// a call carries its ID and name, the following ones pieces of the arguments;
+// fragments with the same index belong to the same call.
+type ToolCallDelta struct {
+	Index    int      `json:"index"`
+	ID       string   `json:"id,omitempty"`
+	Type     string   `json:"type,omitempty"`
+	Function Function `json:"function"`
 }
 
 func ConvertMCPToolsToOpenAI(mcpTools []map[string]interface{}) []Tool {
@@ -98,61 +128,35 @@ func ConvertConversationToOpenAI(session *types.ConversationSession) []Message {
 func ConvertConversationToOpenAIWithTools(session *types.ConversationSession, toolCount int) []Message {
 	var messages []Message
 	
-	// Add improved system prompt that encourages appropriate tool usage
-	systemPrompt := fmt.Sprintf(`You are a system engineer AI assistant with access to powerful desktop tools.
-
-CURRENT STATUS: You have %d tools available.
-
-TOOL USAGE PRIORITY:
-1. ALWAYS use tools for system operations, even if they seem simple:
-   - Network operations (ping, curl, wget, etc.)
-   - File operations (read, write, list, search, etc.)  
-   - Process management (ps, kill, start, etc.)
-   - System information (uname, df, free, etc.)
-   - Command execution (any shell command)
-
-2. Use conversation context for follow-up questions:
-   - "What did I just do?" → Answer from conversation history
-   - "Where did you save that file?" → Reference previous actions
-   - "Show me that again" → Use previous results
-
-3. Answer directly WITHOUT tools only for:
-   - Pure greetings (안녕, hello, hi)
-   - General knowledge questions unrelated to system
-   - Questions about previous conversation content
-
-CRITICAL EXAMPLES:
-• "ping google" → USE start_process tool
-• "check disk space" → USE start_process tool  
-• "list files" → USE list_directory tool
-• "what's running" → USE start_process tool
-• "안녕" → Answer directly
-• "방금 뭐했어?" → Answer from conversation history
-
-ERROR HANDLING STRATEGY:
-• When a tool call fails, ALWAYS try alternative approaches before giving up
-• If a path doesn't exist, try common alternative locations
-• If a command fails, try variations or related commands
-• Provide helpful suggestions even when tools fail
-
-COMMON FALLBACK PATHS:
-• Downloads: Try in order: ~/Downloads, ~/다운로드, ~/Desktop, ~/Documents, /tmp, $HOME
-• System info: Try multiple commands: uname, lsb_release, cat /etc/os-release
-• Processes: Try ps, top, htop, systemctl commands as appropriate
-
-Remember: Use tools proactively! Don't just describe what you COULD do - actually DO it with the tools available.`, toolCount)
+	// Use the prompt composed by the caller, which includes the instructions
+	// of the connected MCP servers, falling back to the generic guidance
+	systemPrompt := session.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = ComposeSystemPrompt(toolCount, nil, "")
+	}
 
 	messages = append(messages, Message{
 		Role:    "system",
 		Content: systemPrompt,
 	})
 
-	// Add conversation messages
+	// Add conversation messages, with the tool calls and the results
+	// answering them
 	for _, msg := range session.Messages {
-		messages = append(messages, Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+		message := Message{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+			Name:       msg.Name,
+		}
+		for _, toolCall := range msg.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, ToolCall{
+				ID:       toolCall.ID,
+				Type:     "function",
+				Function: Function{Name: toolCall.Function.Name, Arguments: toolCall.Function.Arguments},
+			})
+		}
+		messages = append(messages, message)
 	}
 
 	return messages
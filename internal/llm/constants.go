@@ -12,6 +12,9 @@ const (
 	AnthropicAPIBaseURL  = "https://api.anthropic.com/v1"
 	AnthropicMessagesURL = "https://api.anthropic.com/v1/messages"
 
+	// Google Gemini API endpoint
+	GeminiAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 	// API endpoint paths
 	ChatCompletionsPath = "/chat/completions"
 	MessagesPath        = "/messages"
@@ -24,12 +27,19 @@ const (
 	HeaderContentType      = "Content-Type"
 	HeaderAPIKey           = "x-api-key"
 	HeaderAnthropicVersion = "anthropic-version"
+	HeaderGoogleAPIKey     = "x-goog-api-key"
+	HeaderRetryAfter       = "Retry-After"
+	HeaderRetryAfterMS     = "retry-after-ms" // sent by OpenAI and Anthropic
 
 	// Content types
 	ContentTypeJSON = "application/json"
 	ContentTypeText = "text"
 	ContentTypeSSE  = "text/event-stream"
 
+	// Anthropic content block types
+	ContentTypeToolUse    = "tool_use"
+	ContentTypeToolResult = "tool_result"
+
 	// Authorization prefixes
 	AuthBearerPrefix = "Bearer "
 )
@@ -38,6 +48,7 @@ const (
 const (
 	AnthropicAPIVersion = "2023-06-01"
 	OpenAIAPIVersion    = "v1"
+	GeminiAPIVersion    = "v1beta"
 )
 
 // Timeout and Duration Values
@@ -46,9 +57,16 @@ const (
 	LocalModelTimeout    = 120 * time.Second
 	DefaultStreamTimeout = 300 * time.Second
 
+	// Retry backoff of failed requests
+	RetryBaseDelay = 1 * time.Second
+	RetryMaxDelay  = 30 * time.Second
+
 	// Health check timeouts
 	StdioHealthTimeout      = 5 * time.Minute
 	PersistentHealthTimeout = 60 * time.Second
+
+	// How long a failed Ollama tool support check is remembered
+	OllamaToolSupportRetryDelay = 30 * time.Second
 )
 
 // Token Limits and Model Configurations
@@ -59,8 +77,9 @@ const (
 	LocalModelDefaultTokens   = 4096
 
 	// OpenAI model token limits
+	GPT4oTokenLimit      = 128000 // also gpt-4-turbo
 	GPT4TokenLimit       = 8192
-	GPT35TurboTokenLimit = 4096
+	GPT35TurboTokenLimit = 16385
 
 	// Anthropic model token limits
 	Claude3OpusTokens   = 200000
@@ -70,14 +89,27 @@ const (
 	Claude20Tokens      = 100000
 	ClaudeInstantTokens = 100000
 
+	// Context windows assumed for models not listed above
+	OpenAIDefaultContextWindow    = 128000 // e.g. o1, o3 and gpt-4.1
+	AnthropicDefaultContextWindow = 200000 // Claude 3 and later
+
+	// Gemini model token limits
+	Gemini15ProTokens   = 2097152
+	GeminiProTokens     = 32760
+	GeminiDefaultTokens = 1048576
+
 	// Conversation limits
 	MaxConversationTurns          = 50
 	LocalMaxConversationTurns     = 20
 	AnthropicMaxConversationTurns = 100
+	GeminiMaxConversationTurns    = 100
 
 	// Iteration limits
 	MaxToolIterations      = 10
 	LocalMaxToolIterations = 10
+
+	// Retries of requests failing with a transient error
+	DefaultMaxRetries = 3
 )
 
 // Model Names
@@ -85,6 +117,8 @@ const (
 	// OpenAI Models
 	ModelGPT4       = "gpt-4"
 	ModelGPT35Turbo = "gpt-3.5-turbo"
+	ModelGPT4o      = "gpt-4o"
+	ModelGPT4Turbo  = "gpt-4-turbo"
 
 	// Anthropic Models
 	ModelClaude3Opus   = "claude-3-opus-20240229"
@@ -93,6 +127,10 @@ const (
 	ModelClaude21      = "claude-2.1"
 	ModelClaude20      = "claude-2.0"
 	ModelClaudeInstant = "claude-instant-1.2"
+
+	// Gemini Models
+	ModelGemini15Pro = "gemini-1.5-pro"
+	ModelGeminiPro   = "gemini-pro"
 )
 
 // Provider Types
@@ -100,6 +138,7 @@ const (
 	ProviderOpenAI    = "openai"
 	ProviderAnthropic = "anthropic"
 	ProviderLocal     = "local"
+	ProviderGoogle    = "google"
 )
 
 // Role Names
@@ -108,6 +147,7 @@ const (
 	RoleAssistant = "assistant"
 	RoleSystem    = "system"
 	RoleTool      = "tool"
+	RoleModel     = "model"
 )
 
 // Tool Choice Options
@@ -123,16 +163,33 @@ const (
 	StreamEventDone  = "done"
 
 	// Anthropic streaming events
-	AnthropicEventContentBlock = "content_block_delta"
-	AnthropicEventMessage      = "message_delta"
+	AnthropicEventMessageStart      = "message_start"
+	AnthropicEventContentBlockStart = "content_block_start"
+	AnthropicEventContentBlock      = "content_block_delta"
+	AnthropicEventContentBlockStop  = "content_block_stop"
+	AnthropicEventMessage           = "message_delta"
+	AnthropicEventMessageStop       = "message_stop"
+	AnthropicEventError             = "error"
+
+	// Anthropic content block delta types
+	AnthropicDeltaText      = "text_delta"
+	AnthropicDeltaInputJSON = "input_json_delta"
 )
 
 // Default Values
 const (
-	DefaultSystemPrompt        = "You are a helpful AI assistant."
-	LocalContextWindowSize     = 8   // messages (increased from 5)
-	AnthropicContextWindowSize = 5   // messages (increased from 3)
-	LocalContentTruncateLimit  = 1000 // characters (increased from 200)
+	DefaultSystemPrompt       = "You are a helpful AI assistant."
+	LocalContentTruncateLimit = 1000 // characters of a tool result shown in the TUI
+)
+
+// Conversation context assembly, in estimated tokens
+const (
+	ContextOutputReserveTokens   = 4096 // kept free for the answer, at most a quarter of the window
+	ContextMinHistoryTokens      = 1024 // history sent even if the tools fill the window
+	ContextMinMessageTokens      = 64   // shortest a message of the latest turn is cut to
+	ContextSummaryMaxTokens      = 1000 // summary of the turns left out
+	ContextMessageOverheadTokens = 4    // role and separators of a message
+	ContextSummaryExcerptLength  = 160  // characters of a question or answer in the summary
 )
 
 // Error Messages
@@ -148,12 +205,13 @@ const (
 	ErrParsingArguments       = "Error parsing arguments: %v"
 	ErrFailedToMarshal        = "failed to marshal request: %v"
 	ErrFailedToCreateRequest  = "failed to create request: %v"
-	ErrFailedToSendRequest    = "failed to send request: %v"
+	ErrFailedToSendRequest    = "failed to send request: %w"
 	ErrFailedToReadResponse   = "failed to read response body: %v"
 	ErrAPIRequestFailed       = "API request failed with status %d: %s"
 	ErrFailedToDecodeResponse = "failed to decode response: %v"
 	ErrFailedToParseResponse  = "failed to parse response: %v"
 	ErrStreamingReadError     = "error reading stream: %v"
+	ErrStreamEndedEarly       = "stream ended before the response was complete: %w"
 	ErrUnableToParseResponse  = "unable to parse response body as any known format: %s"
 	ErrNoContentInStream      = "no content found in streaming response"
 )
@@ -166,7 +224,8 @@ const (
 
 // HTTP Status Codes (commonly used)
 const (
-	StatusOK = 200
+	StatusOK         = 200
+	StatusOverloaded = 529 // Anthropic's API is temporarily overloaded
 )
 
 // Ollama Detection Patterns
@@ -174,6 +233,25 @@ const (
 	OllamaPortPattern = ":11434"
 	OllamaNamePattern = "ollama"
 	OllamaAPIPath     = "/api/chat"
+	OllamaShowPath    = "/api/show"
+
+	// Capability reported by /api/show for models with tool support
+	OllamaCapabilityTools = "tools"
+)
+
+// Provider Config Keys
+const (
+	// ConfigNativeTools turns native tool calling of local providers on or
+	// off instead of detecting it
+	ConfigNativeTools = "native_tools"
+
+	// ConfigMaxRetries sets how often requests failing with a transient
+	// error are retried
+	ConfigMaxRetries = "max_retries"
+
+	// ConfigContextWindow sets the number of tokens the model accepts, for
+	// models whose window is not known or was changed, e.g. Ollama's num_ctx
+	ConfigContextWindow = "context_window"
 )
 
 // Tool Usage Patterns for Local Models
@@ -190,6 +268,7 @@ const (
 	ProgressProcessingWithTools             = "Processing with %s and available tools..."
 	ProgressProcessingConversation          = "Processing conversation with %s (no tools available)..."
 	ProgressProcessingConversationWithTools = "Processing conversation with %s and available tools..."
+	ProgressRetrying                        = "Request failed (%s), retrying in %s (%d/%d)..."
 )
 
 // Stream Response Markers
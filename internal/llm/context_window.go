@@ -0,0 +1,374 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// ContextWindowForProvider returns the number of tokens the model of provider
+// accepts, taken from the context_window setting of the provider config if
+// set and from the known limits of the model otherwise
+func ContextWindowForProvider(provider *types.LLMProvider) int {
+	// Numbers read back from the JSON config are float64
+	switch window := provider.Config[ConfigContextWindow].(type) {
+	case int:
+		if window > 0 {
+			return window
+		}
+	case float64:
+		if window > 0 {
+			return int(window)
+		}
+	}
+
+	switch provider.Type {
+	case ProviderOpenAI:
+		return openAIContextWindow(provider.Model)
+	case ProviderAnthropic:
+		return anthropicContextWindow(provider.Model)
+	case ProviderGoogle:
+		return getMaxTokensForGeminiModel(provider.Model)
+	case ProviderLocal:
+		return getMaxTokensForLocalModel(provider.Model)
+	default:
+		return DefaultMaxTokens
+	}
+}
+
+// openAIContextWindow returns the context window of an OpenAI model. Only
+// the older models with smaller windows are listed; newer ones have at least
+// OpenAIDefaultContextWindow.
+func openAIContextWindow(model string) int {
+	switch {
+	case model == ModelGPT4 || strings.HasPrefix(model, ModelGPT4+"-0"):
+		// gpt-4, gpt-4-0314 and gpt-4-0613
+		return GPT4TokenLimit
+	case strings.HasPrefix(model, ModelGPT35Turbo):
+		return GPT35TurboTokenLimit
+	default:
+		return OpenAIDefaultContextWindow
+	}
+}
+
+// anthropicContextWindow returns the context window of a Claude model
+func anthropicContextWindow(model string) int {
+	switch model {
+	case ModelClaude20:
+		return Claude20Tokens
+	case ModelClaudeInstant:
+		return ClaudeInstantTokens
+	default:
+		return AnthropicDefaultContextWindow
+	}
+}
+
+// EstimateTokens estimates the number of tokens of text without a tokenizer:
+// about four characters per token for ASCII text and one token per character
+// for other scripts, which tokenizers split more finely
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// estimateMessageTokens estimates the tokens of a message including its tool
+// calls and the per-message overhead of the chat format
+func estimateMessageTokens(msg types.ConversationMessage) int {
+	tokens := ContextMessageOverheadTokens + EstimateTokens(msg.Content)
+	for _, toolCall := range msg.ToolCalls {
+		tokens += ContextMessageOverheadTokens + EstimateTokens(toolCall.Function.Name) + EstimateTokens(toolCall.Function.Arguments)
+	}
+	return tokens
+}
+
+// estimateToolTokens estimates the tokens the tool definitions take up in a
+// request
+func estimateToolTokens(tools []Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	return estimateJSONTokens(tools)
+}
+
+// estimateJSONTokens estimates the tokens of a value sent as JSON
+func estimateJSONTokens(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return EstimateTokens(string(data))
+}
+
+// messageBudget returns the tokens the messages of a request to provider may
+// take up next to the system prompt, the tools and room for the answer
+func messageBudget(provider *types.LLMProvider, systemPrompt string, tools []Tool) int {
+	window := ContextWindowForProvider(provider)
+
+	reserve := ContextOutputReserveTokens
+	if reserve > window/4 {
+		reserve = window / 4
+	}
+
+	budget := window - reserve - EstimateTokens(systemPrompt) - estimateToolTokens(tools)
+	if budget < ContextMinHistoryTokens {
+		budget = ContextMinHistoryTokens
+	}
+	return budget
+}
+
+// fitConversation returns session with its history reduced to what fits the
+// context window of provider next to the system prompt, the tools and room
+// for the answer. The session itself is returned if all of it fits.
+func fitConversation(session *types.ConversationSession, provider *types.LLMProvider, tools []Tool) *types.ConversationSession {
+	systemPrompt := session.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+
+	budget := messageBudget(provider, systemPrompt, tools)
+	messages := FitMessages(session.Messages, budget)
+	if messagesEqual(messages, session.Messages) {
+		return session
+	}
+
+	debugPrint("Context of %s: %d of %d messages fit in %d tokens\n", provider.Name, len(messages), len(session.Messages), budget)
+
+	fitted := *session
+	fitted.Messages = messages
+	return &fitted
+}
+
+// FitMessages returns the most recent part of a conversation that fits in
+// budget tokens. History is dropped a turn at a time, from the oldest on; a
+// turn starts with a user message and keeps the messages up to the next one
+// together. The dropped turns are replaced with a short summary of them. The
+// latest turn is always kept, with its longest messages shortened if it does
+// not fit on its own. The tool calls and results of a running tool loop are
+// not part of the session and are kept in budget by fitToolResults.
+func FitMessages(messages []types.ConversationMessage, budget int) []types.ConversationMessage {
+	total := 0
+	for _, msg := range messages {
+		total += estimateMessageTokens(msg)
+	}
+	if total <= budget {
+		return messages
+	}
+
+	turns := splitTurns(messages)
+
+	// Keep room for the summary of the dropped turns
+	summaryBudget := budget / 10
+	if summaryBudget > ContextSummaryMaxTokens {
+		summaryBudget = ContextSummaryMaxTokens
+	}
+
+	latest := turns[len(turns)-1]
+	if turnTokens(latest) > budget-summaryBudget {
+		latest = shrinkTurn(latest, budget-summaryBudget)
+	}
+
+	used := turnTokens(latest)
+	first := len(turns) - 1
+	for first > 0 && used+turnTokens(turns[first-1]) <= budget-summaryBudget {
+		first--
+		used += turnTokens(turns[first])
+	}
+
+	var fitted []types.ConversationMessage
+	if first > 0 {
+		fitted = append(fitted, summarizeTurns(turns[:first], budget-used))
+	}
+	for _, turn := range turns[first : len(turns)-1] {
+		fitted = append(fitted, turn...)
+	}
+	return append(fitted, latest...)
+}
+
+// splitTurns splits a conversation into turns, each starting with a user
+// message. Messages before the first user message form a turn of their own.
+func splitTurns(messages []types.ConversationMessage) [][]types.ConversationMessage {
+	var turns [][]types.ConversationMessage
+	for i, msg := range messages {
+		if i == 0 || msg.Role == RoleUser {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+	}
+	return turns
+}
+
+func turnTokens(turn []types.ConversationMessage) int {
+	tokens := 0
+	for _, msg := range turn {
+		tokens += estimateMessageTokens(msg)
+	}
+	return tokens
+}
+
+// shrinkTurn shortens the longest messages of a turn, tool results first in
+// practice, until the turn fits in budget tokens
+func shrinkTurn(turn []types.ConversationMessage, budget int) []types.ConversationMessage {
+	shrunk := make([]types.ConversationMessage, len(turn))
+	copy(shrunk, turn)
+
+	for range shrunk {
+		excess := turnTokens(shrunk) - budget
+		if excess <= 0 {
+			break
+		}
+
+		longest := 0
+		for i := range shrunk {
+			if EstimateTokens(shrunk[i].Content) > EstimateTokens(shrunk[longest].Content) {
+				longest = i
+			}
+		}
+
+		limit := EstimateTokens(shrunk[longest].Content) - excess
+		if limit < ContextMinMessageTokens {
+			limit = ContextMinMessageTokens
+		}
+		shrunk[longest].Content = truncateToTokens(shrunk[longest].Content, limit)
+	}
+
+	return shrunk
+}
+
+// fitToolResults shortens the tool results a tool loop added, the oldest
+// first, until the loop's messages of about total tokens fit in budget. The
+// history before the loop was fitted when it started, so only the results
+// that piled up since are cut, none below ContextMinMessageTokens.
+func fitToolResults(results []*string, total, budget int) {
+	excess := total - budget
+	for _, result := range results {
+		if excess <= 0 {
+			return
+		}
+
+		tokens := EstimateTokens(*result)
+		limit := tokens - excess
+		if limit < ContextMinMessageTokens {
+			limit = ContextMinMessageTokens
+		}
+		if limit >= tokens {
+			continue
+		}
+
+		*result = truncateToTokens(*result, limit)
+		excess -= tokens - EstimateTokens(*result)
+	}
+}
+
+// truncateToTokens cuts text to about maxTokens tokens
+func truncateToTokens(text string, maxTokens int) string {
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	marker := "\n...[truncated to fit the context window]"
+	limit := maxTokens - EstimateTokens(marker)
+
+	// Count in quarter tokens so that ASCII characters weigh one each
+	cost := 0
+	for i, r := range text {
+		if r < utf8.RuneSelf {
+			cost++
+		} else {
+			cost += 4
+		}
+		if cost > limit*4 {
+			return text[:i] + marker
+		}
+	}
+	return text
+}
+
+// summarizeTurns describes the dropped turns in a user message of at most
+// budget tokens: an excerpt of the question and of the answer of each turn,
+// the most recent turns first to be kept when not all of them fit
+func summarizeTurns(turns [][]types.ConversationMessage, budget int) types.ConversationMessage {
+	header := fmt.Sprintf("[%d earlier turns of this conversation were left out to fit the context window.", len(turns))
+	summary := types.ConversationMessage{Role: RoleUser, Content: header + "]"}
+
+	remaining := budget - estimateMessageTokens(summary) - EstimateTokens("\nSummary of the omitted turns:")
+	var lines []string
+	for i := len(turns) - 1; i >= 0; i-- {
+		line := summarizeTurn(turns[i])
+		if line == "" {
+			continue
+		}
+		cost := EstimateTokens(line) + 1
+		if cost > remaining {
+			break
+		}
+		remaining -= cost
+		lines = append([]string{line}, lines...)
+	}
+
+	if len(lines) > 0 {
+		summary.Content = header + "\nSummary of the omitted turns:\n" + strings.Join(lines, "\n") + "]"
+	}
+	if len(turns) > 0 && len(turns[0]) > 0 {
+		summary.Timestamp = turns[0][0].Timestamp
+	}
+	return summary
+}
+
+// summarizeTurn describes a turn in one line
+func summarizeTurn(turn []types.ConversationMessage) string {
+	var question, answer string
+	var tools []string
+	for _, msg := range turn {
+		switch msg.Role {
+		case RoleUser:
+			if question == "" {
+				question = msg.Content
+			}
+		case RoleAssistant:
+			if msg.Content != "" {
+				answer = msg.Content
+			}
+			for _, toolCall := range msg.ToolCalls {
+				tools = append(tools, toolCall.Function.Name)
+			}
+		}
+	}
+
+	if question == "" && answer == "" {
+		return ""
+	}
+
+	line := "- User: " + excerpt(question, ContextSummaryExcerptLength)
+	if len(tools) > 0 {
+		line += " (tools used: " + strings.Join(tools, ", ") + ")"
+	}
+	if answer != "" {
+		line += " / Assistant: " + excerpt(answer, ContextSummaryExcerptLength)
+	}
+	return line
+}
+
+// excerpt returns the first maxRunes characters of text on a single line
+func excerpt(text string, maxRunes int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxRunes]) + "..."
+}
+
+// messagesEqual reports whether two message slices share their elements
+func messagesEqual(a, b []types.ConversationMessage) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
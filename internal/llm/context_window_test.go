@@ -0,0 +1,331 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+func TestContextWindowForProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider types.LLMProvider
+		want     int
+	}{
+		{"gpt-4", types.LLMProvider{Type: ProviderOpenAI, Model: "gpt-4"}, GPT4TokenLimit},
+		{"gpt-4 snapshot", types.LLMProvider{Type: ProviderOpenAI, Model: "gpt-4-0613"}, GPT4TokenLimit},
+		{"gpt-3.5-turbo", types.LLMProvider{Type: ProviderOpenAI, Model: "gpt-3.5-turbo-0125"}, GPT35TurboTokenLimit},
+		{"gpt-4-turbo", types.LLMProvider{Type: ProviderOpenAI, Model: "gpt-4-turbo"}, OpenAIDefaultContextWindow},
+		{"gpt-4.1", types.LLMProvider{Type: ProviderOpenAI, Model: "gpt-4.1"}, OpenAIDefaultContextWindow},
+		{"o3", types.LLMProvider{Type: ProviderOpenAI, Model: "o3-mini"}, OpenAIDefaultContextWindow},
+		{"claude-2.0", types.LLMProvider{Type: ProviderAnthropic, Model: ModelClaude20}, Claude20Tokens},
+		{"claude-sonnet-4", types.LLMProvider{Type: ProviderAnthropic, Model: "claude-sonnet-4-20250514"}, AnthropicDefaultContextWindow},
+		{"claude-3-5-haiku", types.LLMProvider{Type: ProviderAnthropic, Model: "claude-3-5-haiku-latest"}, AnthropicDefaultContextWindow},
+		{"config int", types.LLMProvider{Type: ProviderOpenAI, Model: "gpt-4", Config: map[string]interface{}{ConfigContextWindow: 32000}}, 32000},
+		{"config from JSON", types.LLMProvider{Type: ProviderAnthropic, Model: "claude-test", Config: map[string]interface{}{ConfigContextWindow: float64(50000)}}, 50000},
+		{"config zero", types.LLMProvider{Type: ProviderAnthropic, Model: "claude-test", Config: map[string]interface{}{ConfigContextWindow: 0}}, AnthropicDefaultContextWindow},
+	}
+
+	for _, tt := range tests {
+		if got := ContextWindowForProvider(&tt.provider); got != tt.want {
+			t.Errorf("%s: ContextWindowForProvider = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	long := strings.Repeat("a", 4000)
+	wide := strings.Repeat("한", 1000)
+
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		truncated bool
+	}{
+		{"fits", "short text", 100, false},
+		{"exactly fits", strings.Repeat("a", 400), 100, false},
+		{"ascii", long, 100, true},
+		{"multi-byte", wide, 100, true},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		got := truncateToTokens(tt.text, tt.maxTokens)
+		if !tt.truncated {
+			if got != tt.text {
+				t.Errorf("%s: text was changed to %q", tt.name, got)
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(got, "[truncated to fit the context window]") {
+			t.Errorf("%s: no truncation marker in %q", tt.name, got)
+		}
+		if tokens := EstimateTokens(got); tokens > tt.maxTokens {
+			t.Errorf("%s: %d tokens left, want at most %d", tt.name, tokens, tt.maxTokens)
+		}
+		if !strings.HasPrefix(tt.text, strings.TrimSuffix(got, "\n...[truncated to fit the context window]")) {
+			t.Errorf("%s: %q is not a prefix of the text", tt.name, got)
+		}
+	}
+}
+
+func TestShrinkTurn(t *testing.T) {
+	question := types.ConversationMessage{Role: RoleUser, Content: "Show the logs"}
+	call := types.ConversationMessage{Role: RoleAssistant, ToolCalls: []types.ToolCall{
+		{ID: "call_1", Type: "function", Function: types.ToolCallFunction{Name: "read_logs", Arguments: `{"lines":1000}`}},
+	}}
+	logs := types.ConversationMessage{Role: RoleTool, Content: strings.Repeat("log line\n", 1000), ToolCallID: "call_1"}
+	answer := types.ConversationMessage{Role: RoleAssistant, Content: "The logs look fine."}
+
+	tests := []struct {
+		name      string
+		turn      []types.ConversationMessage
+		budget    int
+		shortened int // index of the message cut, -1 for none
+	}{
+		{"fits", []types.ConversationMessage{question, answer}, 100, -1},
+		{"tool result", []types.ConversationMessage{question, call, logs, answer}, 500, 2},
+		{"long question", []types.ConversationMessage{{Role: RoleUser, Content: strings.Repeat("why ", 2000)}, answer}, 300, 0},
+	}
+
+	for _, tt := range tests {
+		got := shrinkTurn(tt.turn, tt.budget)
+		if len(got) != len(tt.turn) {
+			t.Fatalf("%s: %d messages, want %d", tt.name, len(got), len(tt.turn))
+		}
+		if tokens := turnTokens(got); tokens > tt.budget {
+			t.Errorf("%s: %d tokens, want at most %d", tt.name, tokens, tt.budget)
+		}
+		for i := range got {
+			if changed := got[i].Content != tt.turn[i].Content; changed != (i == tt.shortened) {
+				t.Errorf("%s: message %d changed = %v", tt.name, i, changed)
+			}
+		}
+	}
+
+	// The turn passed in is left as it was
+	turn := []types.ConversationMessage{question, call, logs, answer}
+	shrinkTurn(turn, 200)
+	if turn[2].Content != logs.Content {
+		t.Error("shrinkTurn changed the turn passed in")
+	}
+}
+
+func TestShrinkTurnKeepsMinimum(t *testing.T) {
+	turn := []types.ConversationMessage{
+		{Role: RoleUser, Content: strings.Repeat("a", 2000)},
+		{Role: RoleAssistant, Content: strings.Repeat("b", 2000)},
+	}
+
+	got := shrinkTurn(turn, 10)
+	for i, msg := range got {
+		if tokens := EstimateTokens(msg.Content); tokens > ContextMinMessageTokens || tokens < ContextMinMessageTokens/2 {
+			t.Errorf("message %d has %d tokens, want about %d", i, tokens, ContextMinMessageTokens)
+		}
+	}
+}
+
+func TestSummarizeTurns(t *testing.T) {
+	turns := [][]types.ConversationMessage{
+		{
+			{Role: RoleUser, Content: "Check the disk"},
+			{Role: RoleAssistant, ToolCalls: []types.ToolCall{{Function: types.ToolCallFunction{Name: "df"}}}},
+			{Role: RoleTool, Content: "/dev/sda1 80%"},
+			{Role: RoleAssistant, Content: "The disk is 80% full."},
+		},
+		{
+			{Role: RoleUser, Content: "Restart   nginx\nplease"},
+			{Role: RoleAssistant, Content: "Done."},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		turns    [][]types.ConversationMessage
+		budget   int
+		contains []string
+		missing  []string
+	}{
+		{
+			name:   "all fit",
+			turns:  turns,
+			budget: 1000,
+			contains: []string{
+				"[2 earlier turns",
+				"- User: Check the disk (tools used: df) / Assistant: The disk is 80% full.",
+				"- User: Restart nginx please / Assistant: Done.",
+			},
+		},
+		{
+			name:     "only the latest fits",
+			turns:    turns,
+			budget:   60,
+			contains: []string{"[2 earlier turns", "Restart nginx please"},
+			missing:  []string{"Check the disk"},
+		},
+		{
+			name:     "none fit",
+			turns:    turns,
+			budget:   10,
+			contains: []string{"[2 earlier turns"},
+			missing:  []string{"Summary of the omitted turns", "Check the disk", "Restart nginx"},
+		},
+		{
+			name:     "long excerpt",
+			turns:    [][]types.ConversationMessage{{{Role: RoleUser, Content: strings.Repeat("x", 500)}}},
+			budget:   1000,
+			contains: []string{"- User: " + strings.Repeat("x", ContextSummaryExcerptLength) + "..."},
+		},
+	}
+
+	for _, tt := range tests {
+		summary := summarizeTurns(tt.turns, tt.budget)
+		if summary.Role != RoleUser {
+			t.Errorf("%s: role = %q", tt.name, summary.Role)
+		}
+		for _, want := range tt.contains {
+			if !strings.Contains(summary.Content, want) {
+				t.Errorf("%s: %q not in %q", tt.name, want, summary.Content)
+			}
+		}
+		for _, unwanted := range tt.missing {
+			if strings.Contains(summary.Content, unwanted) {
+				t.Errorf("%s: %q in %q", tt.name, unwanted, summary.Content)
+			}
+		}
+	}
+}
+
+func TestFitMessages(t *testing.T) {
+	turn := func(n, answerLength int) []types.ConversationMessage {
+		return []types.ConversationMessage{
+			{Role: RoleUser, Content: fmt.Sprintf("question %d", n)},
+			{Role: RoleAssistant, Content: fmt.Sprintf("answer %d %s", n, strings.Repeat("z", answerLength))},
+		}
+	}
+	var conversation []types.ConversationMessage
+	for n := 1; n <= 5; n++ {
+		conversation = append(conversation, turn(n, 400)...)
+	}
+	var shrinking []types.ConversationMessage
+	shrinking = append(shrinking, turn(1, 400)...)
+	shrinking = append(shrinking, turn(2, 8000)...)
+
+	tests := []struct {
+		name      string
+		messages  []types.ConversationMessage
+		budget    int
+		summary   bool     // the first message is the summary
+		questions []string // the questions kept after it
+	}{
+		{"everything fits", conversation, 10000, false, []string{"question 1", "question 2", "question 3", "question 4", "question 5"}},
+		{"oldest turns dropped", conversation, 300, true, []string{"question 4", "question 5"}},
+		{"only the latest turn", conversation, 150, true, []string{"question 5"}},
+		{"latest turn shortened", shrinking, 500, true, []string{"question 2"}},
+		{"empty", nil, 100, false, nil},
+	}
+
+	for _, tt := range tests {
+		fitted := FitMessages(tt.messages, tt.budget)
+
+		if !tt.summary && len(fitted) != len(tt.messages) {
+			t.Errorf("%s: %d messages, want all %d", tt.name, len(fitted), len(tt.messages))
+			continue
+		}
+		if tt.summary {
+			if len(fitted) == 0 || !strings.Contains(fitted[0].Content, "earlier turns of this conversation were left out") {
+				t.Errorf("%s: no summary first in %+v", tt.name, fitted)
+				continue
+			}
+			fitted = fitted[1:]
+			if tokens := turnTokens(fitted); tokens > tt.budget {
+				t.Errorf("%s: %d tokens kept, want at most %d", tt.name, tokens, tt.budget)
+			}
+		}
+
+		var questions []string
+		for _, msg := range fitted {
+			if msg.Role == RoleUser {
+				questions = append(questions, msg.Content)
+			}
+		}
+		if strings.Join(questions, ",") != strings.Join(tt.questions, ",") {
+			t.Errorf("%s: questions = %v, want %v", tt.name, questions, tt.questions)
+		}
+	}
+}
+
+func TestFitToolResults(t *testing.T) {
+	big := strings.Repeat("a", 4000) // 1000 tokens
+
+	tests := []struct {
+		name    string
+		results []string
+		total   int
+		budget  int
+		want    []int // tokens of each result afterwards, about
+	}{
+		{"fits", []string{big, big}, 2100, 3000, []int{1000, 1000}},
+		{"oldest cut first", []string{big, big}, 2100, 1600, []int{500, 1000}},
+		{"oldest cut to the minimum", []string{big, big}, 2100, 600, []int{ContextMinMessageTokens, 440}},
+		{"short results kept", []string{"ok", big}, 1100, 600, []int{1, 500}},
+	}
+
+	for _, tt := range tests {
+		results := make([]*string, len(tt.results))
+		for i := range tt.results {
+			result := tt.results[i]
+			results[i] = &result
+		}
+
+		fitToolResults(results, tt.total, tt.budget)
+
+		for i, result := range results {
+			tokens := EstimateTokens(*result)
+			if tokens > tt.want[i] || tokens < tt.want[i]-20 {
+				t.Errorf("%s: result %d has %d tokens, want about %d", tt.name, i, tokens, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestAnthropicToolLoopStaysInContextWindow(t *testing.T) {
+	standIn := newAnthropicStandIn(t, func(request AnthropicRequest, n int) AnthropicResponse {
+		if n < 4 {
+			return toolUseResponse(fmt.Sprintf("toolu_%d", n))
+		}
+		return AnthropicResponse{StopReason: "end_turn", Content: []AnthropicContent{{Type: ContentTypeText, Text: "Done."}}}
+	})
+
+	client := standIn.client()
+	client.provider.Config[ConfigContextWindow] = 4000
+	budget := messageBudget(client.provider, "", []Tool{echoTool})
+
+	toolCaller := func(name string, args map[string]interface{}) (interface{}, error) {
+		return strings.Repeat("output ", 2000), nil
+	}
+	if _, err := client.ProcessWithTools("Run it three times", []Tool{echoTool}, toolCaller); err != nil {
+		t.Fatalf("ProcessWithTools: %v", err)
+	}
+
+	if len(standIn.requests) != 4 {
+		t.Fatalf("sent %d requests, want 4", len(standIn.requests))
+	}
+	for i, request := range standIn.requests {
+		if tokens := estimateJSONTokens(request.Messages); tokens > budget {
+			t.Errorf("request %d has %d tokens of messages, budget %d", i+1, tokens, budget)
+		}
+	}
+
+	// The latest result is cut the least
+	last := standIn.requests[3].Messages
+	first := last[2].Content[0].Content
+	latest := last[len(last)-1].Content[0].Content
+	if len(first) >= len(latest) {
+		t.Errorf("first result has %d bytes, latest %d", len(first), len(latest))
+	}
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Error classes on which a turn moves on to the next provider of the
+// fallback chain
+const (
+	FallbackRateLimited  = "rate limited" // 429
+	FallbackUnavailable  = "unavailable"  // server errors and overload
+	FallbackUnauthorized = "unauthorized" // rejected API key
+	FallbackUnreachable  = "unreachable"  // connection errors and timeouts
+)
+
+// FallbackReason returns the error class of err if another provider should
+// be tried after it, or "" if the error would not be solved by switching
+// providers, e.g. an invalid request or a cancellation
+func FallbackReason(err error) string {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return FallbackRateLimited
+		case statusErr.StatusCode == http.StatusUnauthorized, statusErr.StatusCode == http.StatusForbidden:
+			return FallbackUnauthorized
+		case statusErr.Temporary():
+			return FallbackUnavailable
+		default:
+			return ""
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return FallbackUnreachable
+	}
+
+	return ""
+}
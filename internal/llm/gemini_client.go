@@ -0,0 +1,653 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// GeminiRequest represents the request structure for the Gemini API
+type GeminiRequest struct {
+	Contents          []GeminiContent `json:"contents"`
+	SystemInstruction *GeminiContent  `json:"systemInstruction,omitempty"`
+	Tools             []GeminiTool    `json:"tools,omitempty"`
+}
+
+// GeminiContent is a message: the parts sent by the user or the model
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart is text, a function call of the model or the response to it
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall is a function the model wants to call
+type GeminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse carries the result of a function call back to the model
+type GeminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// GeminiTool groups the function declarations offered to the model
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration describes a function the model may call
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// GeminiResponse represents the response from the Gemini API
+type GeminiResponse struct {
+	Candidates     []GeminiCandidate `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+}
+
+// GeminiUsageMetadata reports the tokens used by a request. PromptTokenCount
+// includes the cached tokens.
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+}
+
+// GeminiCandidate is one answer of the model
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+}
+
+// GeminiClient implements FullClient and StreamingSupport for Google Gemini
+type GeminiClient struct {
+	provider   *types.LLMProvider
+	httpClient *HTTPClient
+}
+
+// NewGeminiClient creates a new Gemini client
+func NewGeminiClient(provider *types.LLMProvider) *GeminiClient {
+	headers := map[string]string{
+		HeaderGoogleAPIKey: provider.APIKey,
+	}
+
+	httpClient := NewHTTPClient(HTTPConfig{
+		Timeout: DefaultHTTPTimeout,
+		Headers: headers,
+		Retry:   RetryPolicyForProvider(provider),
+	})
+
+	return &GeminiClient{
+		provider:   provider,
+		httpClient: httpClient,
+	}
+}
+
+// endpoint returns the URL of a model method. The provider's endpoint, if
+// set, replaces the API base URL.
+func (c *GeminiClient) endpoint(method string) string {
+	base := GeminiAPIBaseURL
+	if c.provider.Endpoint != "" {
+		base = strings.TrimSuffix(c.provider.Endpoint, "/")
+	}
+	return fmt.Sprintf("%s/models/%s:%s", base, c.provider.Model, method)
+}
+
+// ProcessMessage processes a simple message
+func (c *GeminiClient) ProcessMessage(message string) (string, error) {
+	return c.ProcessMessageContext(context.Background(), message)
+}
+
+// ProcessMessageContext is like ProcessMessage but aborts the request when ctx is done
+func (c *GeminiClient) ProcessMessageContext(ctx context.Context, message string) (string, error) {
+	if c.provider.APIKey == "" {
+		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderGoogle)
+	}
+
+	reqBody := GeminiRequest{
+		Contents: []GeminiContent{
+			geminiTextContent(RoleUser, message),
+		},
+	}
+
+	response, err := c.generateContent(ctx, reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	return geminiText(response)
+}
+
+// ProcessWithTools processes a message with tools using function calling
+func (c *GeminiClient) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return c.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (c *GeminiClient) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	contents := []GeminiContent{
+		geminiTextContent(RoleUser, message),
+	}
+
+	return c.processToolConversation(ctx, nil, contents, tools, toolCaller)
+}
+
+// ProcessConversation processes a message within conversation context
+func (c *GeminiClient) ProcessConversation(session *types.ConversationSession) (string, error) {
+	return c.ProcessConversationContext(context.Background(), session)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (c *GeminiClient) ProcessConversationContext(ctx context.Context, session *types.ConversationSession) (string, error) {
+	if c.provider.APIKey == "" {
+		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderGoogle)
+	}
+
+	reqBody := GeminiRequest{
+		Contents:          c.convertConversationToGemini(fitConversation(session, c.provider, nil)),
+		SystemInstruction: geminiSystemInstruction(session),
+	}
+
+	response, err := c.generateContent(ctx, reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	return geminiText(response)
+}
+
+// ProcessConversationWithTools processes conversation with tools
+func (c *GeminiClient) ProcessConversationWithTools(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return c.ProcessConversationWithToolsContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationWithToolsContext is like ProcessConversationWithTools but aborts the request when ctx is done
+func (c *GeminiClient) ProcessConversationWithToolsContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	contents := c.convertConversationToGemini(fitConversation(session, c.provider, tools))
+
+	return c.processToolConversation(ctx, geminiSystemInstruction(session), contents, tools, toolCaller)
+}
+
+// fitGeminiToolResults shortens the function responses when the loop's
+// contents no longer fit in budget tokens
+func fitGeminiToolResults(contents []GeminiContent, budget int) {
+	var responses []map[string]interface{}
+	var results []*string
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			if part.FunctionResponse == nil {
+				continue
+			}
+			if result, ok := part.FunctionResponse.Response["result"].(string); ok {
+				responses = append(responses, part.FunctionResponse.Response)
+				results = append(results, &result)
+			}
+		}
+	}
+
+	fitToolResults(results, estimateJSONTokens(contents), budget)
+	for i, response := range responses {
+		response["result"] = *results[i]
+	}
+}
+
+// processToolConversation handles the function calling loop: the calls in
+// the model's answer are executed and their results sent back as function
+// responses until the model answers without calling a function
+func (c *GeminiClient) processToolConversation(ctx context.Context, systemInstruction *GeminiContent, contents []GeminiContent, tools []Tool, toolCaller ToolCaller) (string, error) {
+	if c.provider.APIKey == "" {
+		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderGoogle)
+	}
+
+	// Create tool processor for validation and execution
+	toolProcessor := NewToolProcessor(tools, toolCaller)
+	geminiTools := ConvertToolsToGemini(tools)
+
+	systemPrompt := ""
+	if systemInstruction != nil && len(systemInstruction.Parts) > 0 {
+		systemPrompt = systemInstruction.Parts[0].Text
+	}
+	budget := messageBudget(c.provider, systemPrompt, tools)
+
+	// Iterative conversation with tools
+	for iteration := 0; iteration < MaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		fitGeminiToolResults(contents, budget)
+
+		reqBody := GeminiRequest{
+			Contents:          contents,
+			SystemInstruction: systemInstruction,
+			Tools:             geminiTools,
+		}
+
+		response, err := c.generateContent(ctx, reqBody)
+		if err != nil {
+			return "", err
+		}
+
+		if len(response.Candidates) == 0 {
+			return geminiText(response)
+		}
+
+		candidate := response.Candidates[0].Content
+		candidate.Role = RoleModel
+		contents = append(contents, candidate)
+
+		var calls []*GeminiFunctionCall
+		for _, part := range candidate.Parts {
+			if part.FunctionCall != nil {
+				calls = append(calls, part.FunctionCall)
+			}
+		}
+
+		// If no function calls, we're done
+		if len(calls) == 0 {
+			return geminiText(response)
+		}
+
+		// Answer all calls of the turn in one message
+		var responses []GeminiPart
+		for _, call := range calls {
+			responses = append(responses, GeminiPart{
+				FunctionResponse: executeGeminiFunctionCall(toolProcessor, toolCaller, call),
+			})
+		}
+
+		contents = append(contents, GeminiContent{
+			Role:  RoleUser,
+			Parts: responses,
+		})
+	}
+
+	return "", fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
+}
+
+// executeGeminiFunctionCall runs the tool the model asked for and returns
+// the function response answering it
+func executeGeminiFunctionCall(toolProcessor *ToolProcessor, toolCaller ToolCaller, call *GeminiFunctionCall) *GeminiFunctionResponse {
+	response := &GeminiFunctionResponse{
+		ID:   call.ID,
+		Name: call.Name,
+	}
+
+	if toolCaller == nil {
+		response.Response = map[string]interface{}{"error": fmt.Sprintf("tool %s is not available", call.Name)}
+		return response
+	}
+
+	args := call.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	result, err := toolProcessor.ExecuteTool(call.Name, args)
+	if err != nil {
+		response.Response = map[string]interface{}{"error": err.Error()}
+		return response
+	}
+
+	// Use utility function to format result
+	response.Response = map[string]interface{}{"result": FormatToolResult(result)}
+	return response
+}
+
+// generateContent sends a request and decodes the response
+func (c *GeminiClient) generateContent(ctx context.Context, reqBody GeminiRequest) (*GeminiResponse, error) {
+	resp, err := c.httpClient.PostJSONContext(ctx, c.endpoint("generateContent"), reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var geminiResp GeminiResponse
+	if err := UnmarshalJSONResponse(resp, &geminiResp); err != nil {
+		return nil, err
+	}
+	recordUsage(ctx, geminiUsage(geminiResp.UsageMetadata))
+
+	return &geminiResp, nil
+}
+
+// ConvertToolsToGemini converts tools to function declarations
+func ConvertToolsToGemini(tools []Tool) []GeminiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	var declarations []GeminiFunctionDeclaration
+	for _, tool := range tools {
+		declaration := GeminiFunctionDeclaration{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+		}
+
+		// Functions without parameters are declared without a schema, an
+		// object schema with no properties is rejected
+		if properties, ok := tool.Function.Parameters["properties"].(map[string]interface{}); ok && len(properties) > 0 {
+			declaration.Parameters = geminiSchema(tool.Function.Parameters)
+		}
+
+		declarations = append(declarations, declaration)
+	}
+
+	return []GeminiTool{{FunctionDeclarations: declarations}}
+}
+
+// geminiSchemaFields are the JSON schema keywords the Gemini API accepts
+var geminiSchemaFields = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"title":       true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"properties":  true,
+	"required":    true,
+	"items":       true,
+	"minItems":    true,
+	"maxItems":    true,
+	"minimum":     true,
+	"maximum":     true,
+	"anyOf":       true,
+}
+
+// geminiSchema converts a JSON schema to the OpenAPI subset Gemini accepts:
+// unsupported keywords such as $schema or additionalProperties are dropped
+// and ["string", "null"] types become nullable
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{})
+
+	for key, value := range schema {
+		if !geminiSchemaFields[key] {
+			continue
+		}
+
+		switch key {
+		case "type":
+			if names, ok := value.([]interface{}); ok {
+				for _, name := range names {
+					if name == "null" {
+						converted["nullable"] = true
+					} else if _, set := converted["type"]; !set {
+						converted["type"] = name
+					}
+				}
+				continue
+			}
+			converted[key] = value
+		case "properties":
+			properties, _ := value.(map[string]interface{})
+			convertedProperties := make(map[string]interface{}, len(properties))
+			for name, property := range properties {
+				if propertySchema, ok := property.(map[string]interface{}); ok {
+					convertedProperties[name] = geminiSchema(propertySchema)
+				}
+			}
+			converted[key] = convertedProperties
+		case "items":
+			if items, ok := value.(map[string]interface{}); ok {
+				converted[key] = geminiSchema(items)
+			}
+		case "anyOf":
+			variants, _ := value.([]interface{})
+			var convertedVariants []interface{}
+			for _, variant := range variants {
+				if variantSchema, ok := variant.(map[string]interface{}); ok {
+					convertedVariants = append(convertedVariants, geminiSchema(variantSchema))
+				}
+			}
+			converted[key] = convertedVariants
+		default:
+			converted[key] = value
+		}
+	}
+
+	return converted
+}
+
+// convertConversationToGemini converts conversation session to Gemini format.
+// The assistant is the "model" role, tool calls become function calls and
+// tool results function responses; consecutive messages of the same role
+// are merged.
+func (c *GeminiClient) convertConversationToGemini(session *types.ConversationSession) []GeminiContent {
+	var contents []GeminiContent
+
+	for _, msg := range session.Messages {
+		var role string
+		var parts []GeminiPart
+
+		switch msg.Role {
+		case RoleSystem:
+			// Skip system messages as they go in the system instruction
+			continue
+		case RoleTool:
+			role = RoleUser
+			parts = append(parts, GeminiPart{
+				FunctionResponse: &GeminiFunctionResponse{
+					ID:       msg.ToolCallID,
+					Name:     msg.Name,
+					Response: map[string]interface{}{"result": msg.Content},
+				},
+			})
+		case RoleAssistant:
+			role = RoleModel
+			if msg.Content != "" {
+				parts = append(parts, GeminiPart{Text: msg.Content})
+			}
+			for _, toolCall := range msg.ToolCalls {
+				var args map[string]interface{}
+				json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
+				parts = append(parts, GeminiPart{
+					FunctionCall: &GeminiFunctionCall{
+						ID:   toolCall.ID,
+						Name: toolCall.Function.Name,
+						Args: args,
+					},
+				})
+			}
+		default:
+			role = RoleUser
+			if msg.Content != "" {
+				parts = append(parts, GeminiPart{Text: msg.Content})
+			}
+		}
+
+		if len(parts) == 0 {
+			continue
+		}
+
+		if last := len(contents) - 1; last >= 0 && contents[last].Role == role {
+			contents[last].Parts = append(contents[last].Parts, parts...)
+			continue
+		}
+
+		contents = append(contents, GeminiContent{
+			Role:  role,
+			Parts: parts,
+		})
+	}
+
+	return contents
+}
+
+// geminiSystemInstruction returns the session's composed system prompt, or
+// the default prompt when none was set
+func geminiSystemInstruction(session *types.ConversationSession) *GeminiContent {
+	systemPrompt := session.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt + " Maintain context from the conversation history."
+	}
+
+	return &GeminiContent{
+		Parts: []GeminiPart{{Text: systemPrompt}},
+	}
+}
+
+// geminiTextContent creates a message with a single text part
+func geminiTextContent(role, text string) GeminiContent {
+	return GeminiContent{
+		Role:  role,
+		Parts: []GeminiPart{{Text: text}},
+	}
+}
+
+// geminiText joins the text parts of the first candidate
+func geminiText(response *GeminiResponse) (string, error) {
+	if len(response.Candidates) == 0 {
+		if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+			return "", fmt.Errorf("prompt was blocked by Gemini: %s", response.PromptFeedback.BlockReason)
+		}
+		return "", fmt.Errorf(ErrNoResponseChoices)
+	}
+
+	candidate := response.Candidates[0]
+	var texts []string
+	for _, part := range candidate.Content.Parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	if len(texts) == 0 {
+		if candidate.FinishReason != "" && candidate.FinishReason != "STOP" {
+			return "", fmt.Errorf("Gemini stopped without an answer: %s", candidate.FinishReason)
+		}
+		return "", fmt.Errorf(ErrNoTextContentFound)
+	}
+	return strings.Join(texts, ""), nil
+}
+
+// Interface compliance methods
+
+func (c *GeminiClient) GetProviderInfo() ProviderInfo {
+	return ProviderInfo{
+		Type:     c.provider.Type,
+		Model:    c.provider.Model,
+		Endpoint: c.provider.Endpoint,
+		Version:  GeminiAPIVersion,
+	}
+}
+
+func (c *GeminiClient) IsHealthy() bool {
+	return c.provider.APIKey != ""
+}
+
+func (c *GeminiClient) SupportsFunctionCalling() bool {
+	return true
+}
+
+func (c *GeminiClient) SupportsConversation() bool {
+	return true
+}
+
+func (c *GeminiClient) GetCapabilities() ClientCapabilities {
+	return ClientCapabilities{
+		SupportsTools:        true,
+		SupportsConversation: true,
+		SupportsStreaming:    true,
+		MaxTokens:            getMaxTokensForGeminiModel(c.provider.Model),
+		MaxConversationTurn:  GeminiMaxConversationTurns,
+	}
+}
+
+// ProcessMessageStream processes a message and streams the response
+func (c *GeminiClient) ProcessMessageStream(message string) (<-chan StreamChunk, error) {
+	return c.ProcessMessageStreamContext(context.Background(), message)
+}
+
+// ProcessMessageStreamContext is like ProcessMessageStream but aborts the request when ctx is done
+func (c *GeminiClient) ProcessMessageStreamContext(ctx context.Context, message string) (<-chan StreamChunk, error) {
+	reqBody := GeminiRequest{
+		Contents: []GeminiContent{
+			geminiTextContent(RoleUser, message),
+		},
+	}
+
+	return c.stream(ctx, reqBody)
+}
+
+// ProcessConversationStream processes conversation and streams the response
+func (c *GeminiClient) ProcessConversationStream(session *types.ConversationSession) (<-chan StreamChunk, error) {
+	return c.ProcessConversationStreamContext(context.Background(), session)
+}
+
+// ProcessConversationStreamContext is like ProcessConversationStream but aborts the request when ctx is done
+func (c *GeminiClient) ProcessConversationStreamContext(ctx context.Context, session *types.ConversationSession) (<-chan StreamChunk, error) {
+	reqBody := GeminiRequest{
+		Contents:          c.convertConversationToGemini(fitConversation(session, c.provider, nil)),
+		SystemInstruction: geminiSystemInstruction(session),
+	}
+
+	return c.stream(ctx, reqBody)
+}
+
+// stream sends a request to streamGenerateContent and passes the text of the
+// server-sent events on
+func (c *GeminiClient) stream(ctx context.Context, reqBody GeminiRequest) (<-chan StreamChunk, error) {
+	if c.provider.APIKey == "" {
+		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderGoogle)
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+
+		resp, err := c.httpClient.PostJSONContext(ctx, c.endpoint("streamGenerateContent")+"?alt=sse", reqBody)
+		if err != nil {
+			ch <- StreamChunk{Error: err}
+			return
+		}
+
+		if resp.StatusCode >= 400 {
+			body, _ := ReadResponseBody(resp)
+			ch <- StreamChunk{Error: CheckStatusCode(resp, body)}
+			return
+		}
+		defer resp.Body.Close()
+
+		streamHandler := NewStreamHandler(resp.Body)
+		parser := &GeminiSSEParser{}
+		if err := streamHandler.ProcessSSEStreamContext(ctx, ch, parser); err != nil {
+			ch <- StreamChunk{Error: err}
+		}
+	}()
+
+	return ch, nil
+}
+
+// SupportsStreaming indicates if this client supports streaming
+func (c *GeminiClient) SupportsStreaming() bool {
+	return true
+}
+
+// getMaxTokensForGeminiModel returns the context window of Gemini models
+func getMaxTokensForGeminiModel(model string) int {
+	switch {
+	case strings.HasPrefix(model, ModelGemini15Pro):
+		return Gemini15ProTokens
+	case strings.HasPrefix(model, ModelGeminiPro):
+		return GeminiProTokens
+	default:
+		return GeminiDefaultTokens
+	}
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// GeminiProcessor implements LLMProcessor for Google Gemini models using the new client interface
+type GeminiProcessor struct {
+	*BaseProcessor
+	client LLMClient
+}
+
+// NewGeminiProcessor creates a new GeminiProcessor
+func NewGeminiProcessor(provider *types.LLMProvider, promptManager PromptManager) *GeminiProcessor {
+	client := NewGeminiClient(provider)
+	return &GeminiProcessor{
+		BaseProcessor: NewBaseProcessor(provider, promptManager),
+		client:        client,
+	}
+}
+
+// ProcessWithTools processes a message with tools using Gemini
+func (p *GeminiProcessor) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return p.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (p *GeminiProcessor) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	enhancedMessage := p.buildEnhancedMessage(message, len(tools))
+
+	// Use the new client interface
+	if toolSupport, ok := p.client.(ToolSupport); ok {
+		return toolSupport.ProcessWithToolsContext(ctx, enhancedMessage, tools, toolCaller)
+	}
+
+	// Fallback to simple message processing
+	return p.client.ProcessMessageContext(ctx, enhancedMessage)
+}
+
+// ProcessConversation processes a conversation with tools using Gemini
+func (p *GeminiProcessor) ProcessConversation(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return p.ProcessConversationContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (p *GeminiProcessor) ProcessConversationContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	// Use the new client interface
+	if conversationSupport, ok := p.client.(ConversationSupport); ok {
+		if len(tools) == 0 {
+			return conversationSupport.ProcessConversationContext(ctx, session)
+		}
+		return conversationSupport.ProcessConversationWithToolsContext(ctx, session, tools, toolCaller)
+	}
+
+	// Fallback for clients that don't support conversation
+	lastMessage := session.GetLastUserMessage()
+	return p.ProcessWithToolsContext(ctx, lastMessage, tools, toolCaller)
+}
+
+// ProcessWithUI processes a message with tools and UI feedback
+func (p *GeminiProcessor) ProcessWithUI(message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.ProcessWithUIContext(context.Background(), message, tools, toolCaller, display)
+}
+
+// ProcessWithUIContext is like ProcessWithUI but aborts the request when ctx is done
+func (p *GeminiProcessor) ProcessWithUIContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.processWithToolsCommon(ctx, p.client, message, tools, toolCaller, display)
+}
+
+// ProcessConversationWithUI processes a conversation with tools and UI feedback
+func (p *GeminiProcessor) ProcessConversationWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.ProcessConversationWithUIContext(context.Background(), session, tools, toolCaller, display)
+}
+
+// ProcessConversationWithUIContext is like ProcessConversationWithUI but aborts the request when ctx is done
+func (p *GeminiProcessor) ProcessConversationWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.processConversationCommon(ctx, p.client, session, tools, toolCaller, display)
+}
+
+// ProcessConversationStreamWithUI processes a conversation with tools and UI feedback, streaming the response
+func (p *GeminiProcessor) ProcessConversationStreamWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	return p.ProcessConversationStreamWithUIContext(context.Background(), session, tools, toolCaller, display)
+}
+
+// ProcessConversationStreamWithUIContext is like ProcessConversationStreamWithUI but aborts the request when ctx is done
+func (p *GeminiProcessor) ProcessConversationStreamWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	return p.processConversationStreamCommon(ctx, p.client, session, tools, toolCaller, display)
+}
+
+// Capability methods
+func (p *GeminiProcessor) SupportsConversation() bool {
+	return true
+}
+
+func (p *GeminiProcessor) SupportsFunctionCalling() bool {
+	return true
+}
+
+func (p *GeminiProcessor) SupportsUIFeedback() bool {
+	return true
+}
+
+// GetClient returns the underlying LLM client
+func (p *GeminiProcessor) GetClient() LLMClient {
+	return p.client
+}
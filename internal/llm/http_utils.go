@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,12 +14,14 @@ import (
 type HTTPClient struct {
 	client  *http.Client
 	headers map[string]string
+	retry   RetryPolicy
 }
 
 // HTTPConfig holds configuration for HTTP requests
 type HTTPConfig struct {
 	Timeout time.Duration
 	Headers map[string]string
+	Retry   RetryPolicy
 }
 
 // NewHTTPClient creates a new HTTPClient with the given configuration
@@ -26,23 +29,36 @@ func NewHTTPClient(config HTTPConfig) *HTTPClient {
 	if config.Timeout == 0 {
 		config.Timeout = DefaultHTTPTimeout
 	}
+	if config.Retry.BaseDelay == 0 {
+		config.Retry.BaseDelay = RetryBaseDelay
+	}
+	if config.Retry.MaxDelay == 0 {
+		config.Retry.MaxDelay = RetryMaxDelay
+	}
 	
 	return &HTTPClient{
 		client: &http.Client{
 			Timeout: config.Timeout,
 		},
 		headers: config.Headers,
+		retry:   config.Retry,
 	}
 }
 
 // PostJSON makes a POST request with JSON payload
 func (h *HTTPClient) PostJSON(url string, payload interface{}) (*http.Response, error) {
+	return h.PostJSONContext(context.Background(), url, payload)
+}
+
+// PostJSONContext is like PostJSON but the request is aborted when ctx is
+// done. Transient failures are retried according to the client's retry policy.
+func (h *HTTPClient) PostJSONContext(ctx context.Context, url string, payload interface{}) (*http.Response, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal JSON: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -55,9 +71,9 @@ func (h *HTTPClient) PostJSON(url string, payload interface{}) (*http.Response,
 		req.Header.Set(key, value)
 	}
 
-	resp, err := h.client.Do(req)
+	resp, err := doWithRetry(h.client, req, h.retry)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %v", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 
 	return resp, nil
@@ -75,10 +91,10 @@ func ReadResponseBody(resp *http.Response) (string, error) {
 	return string(body), nil
 }
 
-// CheckStatusCode validates HTTP status code and returns error if not successful
+// CheckStatusCode validates HTTP status code and returns a *StatusError if not successful
 func CheckStatusCode(resp *http.Response, body string) error {
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf(ErrAPIRequestFailed, resp.StatusCode, body)
+		return &StatusError{StatusCode: resp.StatusCode, Body: body}
 	}
 	return nil
 }
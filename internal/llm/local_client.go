@@ -3,6 +3,7 @@ package llm
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,7 @@ import (
 type LocalClient struct {
 	provider   *types.LLMProvider
 	httpClient *http.Client // Keep http.Client for now due to complex custom logic
+	retry      RetryPolicy
 }
 
 // NewLocalClient creates a new local LLM client
@@ -26,11 +28,17 @@ func NewLocalClient(provider *types.LLMProvider) *LocalClient {
 		httpClient: &http.Client{
 			Timeout: LocalModelTimeout, // Local models might be slower
 		},
+		retry: RetryPolicyForProvider(provider),
 	}
 }
 
 // ProcessMessage processes a simple message
 func (c *LocalClient) ProcessMessage(message string) (string, error) {
+	return c.ProcessMessageContext(context.Background(), message)
+}
+
+// ProcessMessageContext is like ProcessMessage but aborts the request when ctx is done
+func (c *LocalClient) ProcessMessageContext(ctx context.Context, message string) (string, error) {
 	if c.provider.Endpoint == "" {
 		return "", fmt.Errorf(ErrEndpointRequired)
 	}
@@ -43,18 +51,39 @@ func (c *LocalClient) ProcessMessage(message string) (string, error) {
 		},
 	}
 
-	return c.executeRequest(reqBody)
+	return c.executeRequest(ctx, reqBody)
 }
 
 // ProcessWithTools processes a message with available tools
 func (c *LocalClient) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return c.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (c *LocalClient) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
 	if c.provider.Endpoint == "" {
 		return "", fmt.Errorf(ErrEndpointRequired)
 	}
 
-	// Enhanced message with tool context for local models
+	native := len(tools) > 0 && c.supportsNativeTools(ctx)
+	return c.processWithTools(ctx, message, tools, toolCaller, native)
+}
+
+// processWithTools runs the tool loop for a message. native tells whether
+// the model supports native tool calling.
+func (c *LocalClient) processWithTools(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller, native bool) (string, error) {
+	// Ollama models with tool support use the native protocol
+	if native && c.isOllamaAPI() {
+		messages := []OllamaMessage{
+			{Role: RoleUser, Content: message},
+		}
+		return c.processOllamaToolConversation(ctx, messages, tools, toolCaller)
+	}
+
+	// Otherwise the tools are described in the message and the tool call is
+	// scraped from the reply. Enhanced message with tool context for local models
 	enhancedMessage := message
-	if len(tools) > 0 {
+	if len(tools) > 0 && !native {
 		debugPrint("Providing %d tools to local LLM\n", len(tools))
 
 		toolContext := "\n\nIMPORTANT: Available tools for use (use EXACT names):\n"
@@ -64,11 +93,10 @@ func (c *LocalClient) ProcessWithTools(message string, tools []Tool, toolCaller
 		}
 		toolContext += "\nCRITICAL RULES:\n"
 		toolContext += "1. ONLY use tools from the list above with EXACT names\n"
-		toolContext += "2. For system commands, use: Desktop_Commander_start_process\n"
-		toolContext += "3. For file operations, use: Desktop_Commander_read_file or Desktop_Commander_list_directory\n"
-		toolContext += "4. Tool format: {\"use_tool\": \"EXACT_TOOL_NAME\", \"parameters\": {\"key\": \"value\"}}\n"
-		toolContext += "5. If no suitable tool exists, respond normally without tools\n"
-		toolContext += "\nExample: {\"use_tool\": \"Desktop_Commander_start_process\", \"parameters\": {\"command\": \"hostname\"}}\n"
+		toolContext += "2. Choose the tool whose description best matches the task\n"
+		toolContext += "3. Tool format: {\"use_tool\": \"EXACT_TOOL_NAME\", \"parameters\": {\"key\": \"value\"}}\n"
+		toolContext += "4. If no suitable tool exists, respond normally without tools\n"
+		toolContext += fmt.Sprintf("\nExample: {\"use_tool\": \"%s\", \"parameters\": {}}\n", tools[0].Function.Name)
 
 		enhancedMessage = toolContext + "\n\n" + message
 		debugPrint("Enhanced message length: %d characters\n", len(enhancedMessage))
@@ -81,13 +109,17 @@ func (c *LocalClient) ProcessWithTools(message string, tools []Tool, toolCaller
 
 	// Simple iteration for local models (limit to avoid overwhelming local models)
 	for iteration := 0; iteration < LocalMaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
 		reqBody := OpenAIRequest{
 			Model:    c.provider.Model,
 			Messages: messages,
 		}
 
 		// Local models might support OpenAI-compatible tools
-		if len(tools) > 0 && c.supportsNativeTools() {
+		if native {
 			reqBody.Tools = tools
 			reqBody.ToolChoice = ToolChoiceAuto
 		}
@@ -97,7 +129,7 @@ func (c *LocalClient) ProcessWithTools(message string, tools []Tool, toolCaller
 			return "", fmt.Errorf(ErrFailedToMarshal, err)
 		}
 
-		req, err := http.NewRequest("POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
 		if err != nil {
 			return "", fmt.Errorf(ErrFailedToCreateRequest, err)
 		}
@@ -107,7 +139,7 @@ func (c *LocalClient) ProcessWithTools(message string, tools []Tool, toolCaller
 			req.Header.Set(HeaderAuthorization, AuthBearerPrefix+c.provider.APIKey)
 		}
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := doWithRetry(c.httpClient, req, c.retry)
 		if err != nil {
 			return "", fmt.Errorf(ErrFailedToSendRequest, err)
 		}
@@ -123,8 +155,9 @@ func (c *LocalClient) ProcessWithTools(message string, tools []Tool, toolCaller
 		debugPrint("Response body: %s\n", string(body))
 
 		if resp.StatusCode != StatusOK {
-			return "", fmt.Errorf(ErrAPIRequestFailed, resp.StatusCode, string(body))
+			return "", &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 		}
+		recordUsage(ctx, localUsage(body))
 
 		// Try to parse using multi-format logic
 		isOllamaAPI := c.isOllamaAPI()
@@ -368,6 +401,37 @@ type OllamaResponse struct {
 		Content string `json:"content"`
 	} `json:"message"`
 	Done bool `json:"done"`
+
+	// Token counts, sent with the last chunk
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+// usage returns the token counts of the response, or nil if it has none
+func (r OllamaResponse) usage() *TokenUsage {
+	if r.PromptEvalCount == 0 && r.EvalCount == 0 {
+		return nil
+	}
+	return &TokenUsage{InputTokens: r.PromptEvalCount, OutputTokens: r.EvalCount}
+}
+
+// localUsage returns the token counts of a response body in the Ollama
+// format, streamed or not, or in the OpenAI format, or nil if there are none
+func localUsage(body []byte) *TokenUsage {
+	var openAIResp OpenAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err == nil && openAIResp.Usage != nil {
+		return openAIUsage(openAIResp.Usage)
+	}
+
+	// The counts of a streamed Ollama response are in its last line
+	var usage *TokenUsage
+	for _, line := range strings.Split(string(body), "\n") {
+		var resp OllamaResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &resp); err == nil && resp.usage() != nil {
+			usage = resp.usage()
+		}
+	}
+	return usage
 }
 
 // OllamaRequest represents the native Ollama API request format
@@ -429,37 +493,56 @@ func (c *LocalClient) parseToolCallFromResponse(response string) *ToolCallReques
 
 // ProcessConversation processes a message within conversation context
 func (c *LocalClient) ProcessConversation(session *types.ConversationSession) (string, error) {
-	// Convert conversation to simple messages (keep it lightweight for local models)
-	messages := c.convertConversationToLocal(session, LocalContextWindowSize) // Keep last few messages only
+	return c.ProcessConversationContext(context.Background(), session)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (c *LocalClient) ProcessConversationContext(ctx context.Context, session *types.ConversationSession) (string, error) {
+	// Convert conversation to simple messages, keeping what fits the model's context
+	messages := c.convertConversationToLocal(fitConversation(session, c.provider, nil))
 
 	reqBody := OpenAIRequest{
 		Model:    c.provider.Model,
 		Messages: messages,
 	}
 
-	return c.executeRequest(reqBody)
+	return c.executeRequest(ctx, reqBody)
 }
 
 // ProcessConversationWithTools processes conversation with tools
 func (c *LocalClient) ProcessConversationWithTools(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return c.ProcessConversationWithToolsContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationWithToolsContext is like ProcessConversationWithTools but aborts the request when ctx is done
+func (c *LocalClient) ProcessConversationWithToolsContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	if c.provider.Endpoint == "" {
+		return "", fmt.Errorf(ErrEndpointRequired)
+	}
+
+	// With native tool calling the recent conversation is kept
+	native := len(tools) > 0 && c.supportsNativeTools(ctx)
+	if native && c.isOllamaAPI() {
+		var messages []OllamaMessage
+		if session.SystemPrompt != "" {
+			messages = append(messages, OllamaMessage{Role: RoleSystem, Content: session.SystemPrompt})
+		}
+		messages = append(messages, toOllamaMessages(c.convertConversationToLocal(fitConversation(session, c.provider, tools)))...)
+		return c.processOllamaToolConversation(ctx, messages, tools, toolCaller)
+	}
+
 	// Get last user message and add tool context
 	lastMessage := session.GetLastUserMessage()
 
-	return c.ProcessWithTools(lastMessage, tools, toolCaller)
+	return c.processWithTools(ctx, lastMessage, tools, toolCaller, native)
 }
 
-// convertConversationToLocal converts conversation for local models (simplified)
-func (c *LocalClient) convertConversationToLocal(session *types.ConversationSession, maxMessages int) []Message {
+// convertConversationToLocal converts conversation for local models
+// (simplified). Callers fit the session to the context window first.
+func (c *LocalClient) convertConversationToLocal(session *types.ConversationSession) []Message {
 	var messages []Message
 
-	// Start from the end and take only recent messages
-	startIdx := len(session.Messages) - maxMessages
-	if startIdx < 0 {
-		startIdx = 0
-	}
-
-	for i := startIdx; i < len(session.Messages); i++ {
-		msg := session.Messages[i]
+	for _, msg := range session.Messages {
 		if msg.Role == "system" {
 			continue // Skip system messages for simplicity
 		}
@@ -474,7 +557,7 @@ func (c *LocalClient) convertConversationToLocal(session *types.ConversationSess
 }
 
 // executeRequest executes a simple request without tools
-func (c *LocalClient) executeRequest(reqBody OpenAIRequest) (string, error) {
+func (c *LocalClient) executeRequest(ctx context.Context, reqBody OpenAIRequest) (string, error) {
 	if c.provider.Endpoint == "" {
 		return "", fmt.Errorf(ErrEndpointRequired)
 	}
@@ -515,7 +598,7 @@ func (c *LocalClient) executeRequest(reqBody OpenAIRequest) (string, error) {
 		return "", fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %v", err)
 	}
@@ -526,9 +609,9 @@ func (c *LocalClient) executeRequest(reqBody OpenAIRequest) (string, error) {
 	}
 
 	debugPrint("Making request to %s\n", c.provider.Endpoint)
-	resp, err := c.httpClient.Do(req)
+	resp, err := doWithRetry(c.httpClient, req, c.retry)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return "", fmt.Errorf(ErrFailedToSendRequest, err)
 	}
 	defer resp.Body.Close()
 
@@ -542,7 +625,7 @@ func (c *LocalClient) executeRequest(reqBody OpenAIRequest) (string, error) {
 	debugPrint("Response body: %s\n", string(body))
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return "", &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// Try to parse response in multiple formats
@@ -550,6 +633,7 @@ func (c *LocalClient) executeRequest(reqBody OpenAIRequest) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to parse response: %v", err)
 	}
+	recordUsage(ctx, localUsage(body))
 
 	return content, nil
 }
@@ -669,13 +753,6 @@ func (c *LocalClient) parseOllamaStreamingResponse(body []byte) (string, error)
 	return content, nil
 }
 
-// supportsNativeTools checks if this local provider supports OpenAI-style function calling
-func (c *LocalClient) supportsNativeTools() bool {
-	// Most local models don't support native function calling yet
-	// This could be made configurable in the future
-	return false
-}
-
 // Interface compliance methods
 
 func (c *LocalClient) GetProviderInfo() ProviderInfo {
@@ -692,7 +769,7 @@ func (c *LocalClient) IsHealthy() bool {
 }
 
 func (c *LocalClient) SupportsFunctionCalling() bool {
-	// Limited support through JSON parsing
+	// Native where the model supports it, otherwise through JSON parsing
 	return true
 }
 
@@ -712,6 +789,11 @@ func (c *LocalClient) GetCapabilities() ClientCapabilities {
 
 // ProcessMessageStream processes a message and streams the response
 func (c *LocalClient) ProcessMessageStream(message string) (<-chan StreamChunk, error) {
+	return c.ProcessMessageStreamContext(context.Background(), message)
+}
+
+// ProcessMessageStreamContext is like ProcessMessageStream but aborts the request when ctx is done
+func (c *LocalClient) ProcessMessageStreamContext(ctx context.Context, message string) (<-chan StreamChunk, error) {
 	if c.provider.Endpoint == "" {
 		return nil, fmt.Errorf("endpoint is required for local provider")
 	}
@@ -725,7 +807,7 @@ func (c *LocalClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 
 		if !isOllamaAPI {
 			// For non-Ollama local models, fall back to non-streaming
-			result, err := c.ProcessMessage(message)
+			result, err := c.ProcessMessageContext(ctx, message)
 			if err != nil {
 				ch <- StreamChunk{Error: err}
 			} else {
@@ -753,7 +835,7 @@ func (c *LocalClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 			return
 		}
 
-		req, err := http.NewRequest("POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
 		if err != nil {
 			ch <- StreamChunk{Error: fmt.Errorf("failed to create request: %v", err)}
 			return
@@ -764,16 +846,16 @@ func (c *LocalClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 			req.Header.Set(HeaderAuthorization, AuthBearerPrefix+c.provider.APIKey)
 		}
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := doWithRetry(c.httpClient, req, c.retry)
 		if err != nil {
-			ch <- StreamChunk{Error: fmt.Errorf("failed to send request: %v", err)}
+			ch <- StreamChunk{Error: fmt.Errorf(ErrFailedToSendRequest, err)}
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			ch <- StreamChunk{Error: fmt.Errorf(ErrAPIRequestFailed, resp.StatusCode, string(body))}
+			ch <- StreamChunk{Error: &StatusError{StatusCode: resp.StatusCode, Body: string(body)}}
 			return
 		}
 
@@ -810,6 +892,7 @@ func (c *LocalClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 
 			if resp.Done {
 				debugPrint("Received done signal\n")
+				recordUsage(ctx, resp.usage())
 				ch <- StreamChunk{Done: true}
 				break
 			}
@@ -821,6 +904,11 @@ func (c *LocalClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 
 // ProcessConversationStream processes conversation and streams the response
 func (c *LocalClient) ProcessConversationStream(session *types.ConversationSession) (<-chan StreamChunk, error) {
+	return c.ProcessConversationStreamContext(context.Background(), session)
+}
+
+// ProcessConversationStreamContext is like ProcessConversationStream but aborts the request when ctx is done
+func (c *LocalClient) ProcessConversationStreamContext(ctx context.Context, session *types.ConversationSession) (<-chan StreamChunk, error) {
 	if c.provider.Endpoint == "" {
 		return nil, fmt.Errorf("endpoint is required for local provider")
 	}
@@ -832,7 +920,7 @@ func (c *LocalClient) ProcessConversationStream(session *types.ConversationSessi
 		isOllamaAPI := c.isOllamaAPI()
 		if !isOllamaAPI {
 			// For non-Ollama local models, fall back to non-streaming
-			result, err := c.ProcessConversation(session)
+			result, err := c.ProcessConversationContext(ctx, session)
 			if err != nil {
 				ch <- StreamChunk{Error: err}
 			} else {
@@ -843,7 +931,7 @@ func (c *LocalClient) ProcessConversationStream(session *types.ConversationSessi
 		}
 
 		// Convert conversation to messages
-		messages := c.convertConversationToLocal(session, 5)
+		messages := c.convertConversationToLocal(fitConversation(session, c.provider, nil))
 
 		// For Ollama, use streaming
 		ollamaReq := OllamaRequest{
@@ -867,7 +955,7 @@ func (c *LocalClient) ProcessConversationStream(session *types.ConversationSessi
 			return
 		}
 
-		req, err := http.NewRequest("POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
+		req, err := http.NewRequestWithContext(ctx, "POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
 		if err != nil {
 			ch <- StreamChunk{Error: fmt.Errorf("failed to create request: %v", err)}
 			return
@@ -878,16 +966,16 @@ func (c *LocalClient) ProcessConversationStream(session *types.ConversationSessi
 			req.Header.Set(HeaderAuthorization, AuthBearerPrefix+c.provider.APIKey)
 		}
 
-		resp, err := c.httpClient.Do(req)
+		resp, err := doWithRetry(c.httpClient, req, c.retry)
 		if err != nil {
-			ch <- StreamChunk{Error: fmt.Errorf("failed to send request: %v", err)}
+			ch <- StreamChunk{Error: fmt.Errorf(ErrFailedToSendRequest, err)}
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			ch <- StreamChunk{Error: fmt.Errorf(ErrAPIRequestFailed, resp.StatusCode, string(body))}
+			ch <- StreamChunk{Error: &StatusError{StatusCode: resp.StatusCode, Body: string(body)}}
 			return
 		}
 
@@ -924,6 +1012,7 @@ func (c *LocalClient) ProcessConversationStream(session *types.ConversationSessi
 
 			if resp.Done {
 				debugPrint("Received done signal\n")
+				recordUsage(ctx, resp.usage())
 				ch <- StreamChunk{Done: true}
 				break
 			}
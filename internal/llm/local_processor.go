@@ -1,7 +1,7 @@
 package llm
 
 import (
-	"fmt"
+	"context"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
 )
@@ -23,74 +23,71 @@ func NewLocalProcessor(provider *types.LLMProvider, promptManager PromptManager)
 
 // ProcessWithTools processes a message with tools using Local LLM
 func (p *LocalProcessor) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return p.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (p *LocalProcessor) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
 	enhancedMessage := p.buildEnhancedMessage(message, len(tools))
 	
 	// Use the new client interface
 	if toolSupport, ok := p.client.(ToolSupport); ok {
-		return toolSupport.ProcessWithTools(enhancedMessage, tools, toolCaller)
+		return toolSupport.ProcessWithToolsContext(ctx, enhancedMessage, tools, toolCaller)
 	}
 	
 	// Fallback to simple message processing
-	return p.client.ProcessMessage(enhancedMessage)
+	return p.client.ProcessMessageContext(ctx, enhancedMessage)
 }
 
 // ProcessConversation processes a conversation with tools using Local LLM
 func (p *LocalProcessor) ProcessConversation(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return p.ProcessConversationContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (p *LocalProcessor) ProcessConversationContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
 	// Use the new client interface
 	if conversationSupport, ok := p.client.(ConversationSupport); ok {
 		if len(tools) == 0 {
-			return conversationSupport.ProcessConversation(session)
+			return conversationSupport.ProcessConversationContext(ctx, session)
 		}
-		return conversationSupport.ProcessConversationWithTools(session, tools, toolCaller)
+		return conversationSupport.ProcessConversationWithToolsContext(ctx, session, tools, toolCaller)
 	}
 	
 	// Fallback for clients that don't support conversation
 	lastMessage := session.GetLastUserMessage()
-	return p.ProcessWithTools(lastMessage, tools, toolCaller)
+	return p.ProcessWithToolsContext(ctx, lastMessage, tools, toolCaller)
 }
 
 // ProcessWithUI processes a message with tools and UI feedback
 func (p *LocalProcessor) ProcessWithUI(message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
-	return p.processWithToolsCommon(p.client, message, tools, toolCaller, display)
+	return p.ProcessWithUIContext(context.Background(), message, tools, toolCaller, display)
+}
+
+// ProcessWithUIContext is like ProcessWithUI but aborts the request when ctx is done
+func (p *LocalProcessor) ProcessWithUIContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.processWithToolsCommon(ctx, p.client, message, tools, toolCaller, display)
 }
 
 // ProcessConversationWithUI processes a conversation with tools and UI feedback
 func (p *LocalProcessor) ProcessConversationWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
-	return p.processConversationCommon(p.client, session, tools, toolCaller, display)
+	return p.ProcessConversationWithUIContext(context.Background(), session, tools, toolCaller, display)
 }
 
-// buildLocalConversationContext creates a minimal conversation context for local models
-func (p *LocalProcessor) buildLocalConversationContext(session *types.ConversationSession) string {
-	if len(session.Messages) <= 1 {
-		return ""
-	}
-	
-	// Keep it simple for local models - just include the basic conversation prompt
-	conversationPrompt := p.getConversationPrompt()
-	
-	// For local models, only include the most recent exchange to avoid overwhelming the context
-	messageCount := len(session.Messages)
-	startIdx := messageCount - 2 // Get last 2 messages only
-	if startIdx < 0 {
-		startIdx = 0
-	}
-	
-	if messageCount > 1 {
-		conversationPrompt += "\n\nPrevious exchange:"
-		for i := startIdx; i < messageCount; i++ {
-			msg := session.Messages[i]
-			// Keep it concise for local models
-			truncatedContent := msg.Content
-			if len(truncatedContent) > LocalContentTruncateLimit {
-				truncatedContent = truncatedContent[:LocalContentTruncateLimit] + "..."
-			}
-			conversationPrompt += fmt.Sprintf("\n%s: %s", msg.Role, truncatedContent)
-		}
-	}
-	
-	return conversationPrompt
+// ProcessConversationWithUIContext is like ProcessConversationWithUI but aborts the request when ctx is done
+func (p *LocalProcessor) ProcessConversationWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.processConversationCommon(ctx, p.client, session, tools, toolCaller, display)
+}
+
+// ProcessConversationStreamWithUI processes a conversation with tools and UI feedback, streaming the response
+func (p *LocalProcessor) ProcessConversationStreamWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	return p.ProcessConversationStreamWithUIContext(context.Background(), session, tools, toolCaller, display)
 }
 
+// ProcessConversationStreamWithUIContext is like ProcessConversationStreamWithUI but aborts the request when ctx is done
+func (p *LocalProcessor) ProcessConversationStreamWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	return p.processConversationStreamCommon(ctx, p.client, session, tools, toolCaller, display)
+}
 
 // Capability methods
 func (p *LocalProcessor) SupportsConversation() bool {
@@ -14,6 +14,7 @@ import (
 
 type Manager struct {
 	providers map[string]*types.LLMProvider
+	fallback  []string // provider IDs tried in order when a provider fails
 	storage   *storage.Storage
 	mu        sync.RWMutex
 	ctx       context.Context
@@ -35,6 +36,9 @@ func NewManager() *Manager {
 	if providers, err := storage.LoadLLMProviders(); err == nil {
 		m.providers = providers
 	}
+	if fallback, err := storage.LoadLLMFallback(); err == nil {
+		m.fallback = fallback
+	}
 
 	return m
 }
@@ -80,6 +84,17 @@ func (m *Manager) RemoveProvider(id string) error {
 		fmt.Printf("Warning: failed to save providers to storage: %v\n", err)
 	}
 
+	// A removed provider can no longer be fallen back to
+	for i, fallbackID := range m.fallback {
+		if fallbackID == id {
+			m.fallback = append(m.fallback[:i], m.fallback[i+1:]...)
+			if err := m.storage.SaveLLMFallback(m.fallback); err != nil {
+				fmt.Printf("Warning: failed to save fallback chain to storage: %v\n", err)
+			}
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -95,6 +110,66 @@ func (m *Manager) GetProvider(id string) (*types.LLMProvider, error) {
 	return provider, nil
 }
 
+// FindProvider returns the provider with the given ID or, failing that, name
+func (m *Manager) FindProvider(ref string) (*types.LLMProvider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findProvider(ref)
+}
+
+func (m *Manager) findProvider(ref string) (*types.LLMProvider, error) {
+	if provider, exists := m.providers[ref]; exists {
+		return provider, nil
+	}
+
+	for _, provider := range m.providers {
+		if provider.Name == ref {
+			return provider, nil
+		}
+	}
+
+	return nil, fmt.Errorf("provider %s not found", ref)
+}
+
+// FallbackChain returns the IDs of the providers tried in order when a
+// provider fails
+func (m *Manager) FallbackChain() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]string(nil), m.fallback...)
+}
+
+// SetFallbackChain sets the providers, given by ID or name, that are tried in
+// order when a provider fails. An empty list clears the chain.
+func (m *Manager) SetFallbackChain(refs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		provider, err := m.findProvider(ref)
+		if err != nil {
+			return err
+		}
+		if seen[provider.ID] {
+			return fmt.Errorf("provider %s is listed twice", ref)
+		}
+		seen[provider.ID] = true
+		ids = append(ids, provider.ID)
+	}
+
+	m.fallback = ids
+
+	if err := m.storage.SaveLLMFallback(m.fallback); err != nil {
+		fmt.Printf("Warning: failed to save fallback chain to storage: %v\n", err)
+	}
+
+	return nil
+}
+
 func (m *Manager) ListProviders() []*types.LLMProvider {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -0,0 +1,296 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OllamaMessage is a chat message of the native Ollama API, including the
+// tool calls of the model and the tool results answering them
+type OllamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // For tool messages
+}
+
+// OllamaToolCall is a function call of the model. Unlike OpenAI, the
+// arguments are a JSON object rather than a string.
+type OllamaToolCall struct {
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+// OllamaToolRequest is a /api/chat request offering tools to the model
+type OllamaToolRequest struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Tools    []Tool          `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+// OllamaToolResponse is a non-streamed /api/chat response
+type OllamaToolResponse struct {
+	Model   string        `json:"model"`
+	Message OllamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+// ollamaShowResponse is the part of the /api/show response used to detect
+// tool support
+type ollamaShowResponse struct {
+	Capabilities []string `json:"capabilities"`
+	Template     string   `json:"template"`
+}
+
+// ollamaToolSupport caches the detected tool support per endpoint and model
+var ollamaToolSupport = struct {
+	sync.Mutex
+	models map[string]ollamaToolSupportEntry
+}{models: make(map[string]ollamaToolSupportEntry)}
+
+// ollamaToolSupportEntry is a detection result. Failed detections expire, so
+// that detection is retried once the server is up.
+type ollamaToolSupportEntry struct {
+	supported bool
+	expires   time.Time // zero for successful detections
+}
+
+// supportsNativeTools checks if this local provider supports native function
+// calling. The native_tools setting of the provider config decides if set;
+// otherwise Ollama models are asked via /api/show and OpenAI-compatible
+// servers are assumed not to support it. Callers check once per request, as
+// the answer may need a request to the server.
+func (c *LocalClient) supportsNativeTools(ctx context.Context) bool {
+	if enabled, ok := c.provider.Config[ConfigNativeTools].(bool); ok {
+		return enabled
+	}
+
+	if !c.isOllamaAPI() {
+		return false
+	}
+
+	key := c.provider.Endpoint + "|" + c.provider.Model
+
+	ollamaToolSupport.Lock()
+	entry, ok := ollamaToolSupport.models[key]
+	ollamaToolSupport.Unlock()
+	if ok && (entry.expires.IsZero() || time.Now().Before(entry.expires)) {
+		return entry.supported
+	}
+
+	// Detected without holding the lock, other models need not wait for it
+	supported, err := c.detectOllamaToolSupport(ctx)
+	if err != nil {
+		debugPrint("Tool support detection failed: %v\n", err)
+		if ctx.Err() != nil {
+			// A cancelled request says nothing about the server
+			return false
+		}
+		entry = ollamaToolSupportEntry{expires: time.Now().Add(OllamaToolSupportRetryDelay)}
+	} else {
+		debugPrint("Model %s supports native tools: %v\n", c.provider.Model, supported)
+		entry = ollamaToolSupportEntry{supported: supported}
+	}
+
+	ollamaToolSupport.Lock()
+	ollamaToolSupport.models[key] = entry
+	ollamaToolSupport.Unlock()
+	return entry.supported
+}
+
+// detectOllamaToolSupport asks Ollama about the model. Recent versions list
+// "tools" among the capabilities; for older ones the chat template tells
+// whether the model knows about tools.
+func (c *LocalClient) detectOllamaToolSupport(ctx context.Context) (bool, error) {
+	endpoint := strings.TrimSuffix(c.provider.Endpoint, "/")
+	endpoint = strings.TrimSuffix(endpoint, OllamaAPIPath) + OllamaShowPath
+
+	jsonData, err := json.Marshal(map[string]string{"model": c.provider.Model})
+	if err != nil {
+		return false, fmt.Errorf(ErrFailedToMarshal, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return false, fmt.Errorf(ErrFailedToCreateRequest, err)
+	}
+	req.Header.Set(HeaderContentType, ContentTypeJSON)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf(ErrFailedToSendRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf(ErrFailedToReadResponse, err)
+	}
+
+	if resp.StatusCode != StatusOK {
+		return false, fmt.Errorf(ErrAPIRequestFailed, resp.StatusCode, string(body))
+	}
+
+	var show ollamaShowResponse
+	if err := json.Unmarshal(body, &show); err != nil {
+		return false, fmt.Errorf(ErrFailedToDecodeResponse, err)
+	}
+
+	if show.Capabilities != nil {
+		for _, capability := range show.Capabilities {
+			if capability == OllamaCapabilityTools {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return strings.Contains(show.Template, ".Tools"), nil
+}
+
+// fitOllamaToolResults shortens the tool messages when the loop's messages
+// no longer fit in budget tokens
+func fitOllamaToolResults(messages []OllamaMessage, budget int) {
+	var results []*string
+	for i := range messages {
+		if messages[i].Role == RoleTool {
+			results = append(results, &messages[i].Content)
+		}
+	}
+	fitToolResults(results, estimateJSONTokens(messages), budget)
+}
+
+// processOllamaToolConversation handles the tool calling loop with the native
+// Ollama protocol: tools are offered in the request, the model answers with
+// message.tool_calls and the results are sent back as tool messages
+func (c *LocalClient) processOllamaToolConversation(ctx context.Context, messages []OllamaMessage, tools []Tool, toolCaller ToolCaller) (string, error) {
+	// Create tool processor for validation and execution
+	toolProcessor := NewToolProcessor(tools, toolCaller)
+
+	// The system prompt is one of the messages
+	budget := messageBudget(c.provider, "", tools)
+
+	for iteration := 0; iteration < LocalMaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		fitOllamaToolResults(messages, budget)
+
+		reqBody := OllamaToolRequest{
+			Model:    c.provider.Model,
+			Messages: messages,
+			Tools:    tools,
+			Stream:   false,
+		}
+
+		message, err := c.sendOllamaToolRequest(ctx, reqBody)
+		if err != nil {
+			return "", err
+		}
+
+		message.Role = RoleAssistant
+		messages = append(messages, message)
+
+		// If no tool calls, we're done
+		if len(message.ToolCalls) == 0 || toolCaller == nil {
+			return message.Content, nil
+		}
+
+		for _, toolCall := range message.ToolCalls {
+			name := toolCall.Function.Name
+			debugPrint("Local LLM (Ollama native) requested tool: %s with params: %v\n", name, toolCall.Function.Arguments)
+
+			args := toolCall.Function.Arguments
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+
+			var content string
+			result, err := toolProcessor.ExecuteTool(name, args)
+			if err != nil {
+				content = fmt.Sprintf("Error: %v", err)
+			} else {
+				// Use utility function to format result
+				content = FormatToolResult(result)
+			}
+
+			messages = append(messages, OllamaMessage{
+				Role:     RoleTool,
+				Content:  content,
+				ToolName: name,
+			})
+		}
+	}
+
+	return "", fmt.Errorf(ErrMaxIterationsExceeded, LocalMaxToolIterations)
+}
+
+// sendOllamaToolRequest sends a chat request and returns the model's message
+func (c *LocalClient) sendOllamaToolRequest(ctx context.Context, reqBody OllamaToolRequest) (OllamaMessage, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return OllamaMessage{}, fmt.Errorf(ErrFailedToMarshal, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.provider.Endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return OllamaMessage{}, fmt.Errorf(ErrFailedToCreateRequest, err)
+	}
+
+	req.Header.Set(HeaderContentType, ContentTypeJSON)
+	if c.provider.APIKey != "" {
+		req.Header.Set(HeaderAuthorization, AuthBearerPrefix+c.provider.APIKey)
+	}
+
+	resp, err := doWithRetry(c.httpClient, req, c.retry)
+	if err != nil {
+		return OllamaMessage{}, fmt.Errorf(ErrFailedToSendRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OllamaMessage{}, fmt.Errorf(ErrFailedToReadResponse, err)
+	}
+
+	debugPrint("Response status: %d\n", resp.StatusCode)
+	debugPrint("Response body: %s\n", string(body))
+
+	if resp.StatusCode != StatusOK {
+		return OllamaMessage{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var ollamaResp OllamaToolResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return OllamaMessage{}, fmt.Errorf("failed to parse Ollama response: %v", err)
+	}
+	recordUsage(ctx, &TokenUsage{InputTokens: ollamaResp.PromptEvalCount, OutputTokens: ollamaResp.EvalCount})
+
+	return ollamaResp.Message, nil
+}
+
+// toOllamaMessages converts messages to the native Ollama format
+func toOllamaMessages(messages []Message) []OllamaMessage {
+	var ollamaMessages []OllamaMessage
+	for _, msg := range messages {
+		ollamaMessages = append(ollamaMessages, OllamaMessage{
+			Role:     msg.Role,
+			Content:  msg.Content,
+			ToolName: msg.Name,
+		})
+	}
+	return ollamaMessages
+}
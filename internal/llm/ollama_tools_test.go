@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// newOllamaStandIn serves /api/show with showStatus and answers /api/chat
+// with a plain message. It counts the /api/show requests.
+func newOllamaStandIn(t *testing.T, showStatus int, capabilities []string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var shows int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case OllamaShowPath:
+			atomic.AddInt32(&shows, 1)
+			if showStatus != http.StatusOK {
+				http.Error(w, "model not loaded", showStatus)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"capabilities": capabilities})
+		case OllamaAPIPath:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"model":   "llama-test",
+				"message": map[string]string{"role": RoleAssistant, "content": "Nothing to do."},
+				"done":    true,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &shows
+}
+
+func newOllamaTestClient(endpoint string) *LocalClient {
+	return NewLocalClient(&types.LLMProvider{
+		Name:     "stand-in",
+		Type:     ProviderLocal,
+		Model:    "llama-test",
+		Endpoint: endpoint + OllamaAPIPath,
+		Config:   map[string]interface{}{ConfigMaxRetries: 0},
+	})
+}
+
+func TestOllamaToolSupportIsCheckedOncePerRequest(t *testing.T) {
+	server, shows := newOllamaStandIn(t, http.StatusOK, []string{"completion", OllamaCapabilityTools})
+
+	session := &types.ConversationSession{Messages: []types.ConversationMessage{{Role: RoleUser, Content: "Hello"}}}
+	answer, err := newOllamaTestClient(server.URL).ProcessConversationWithTools(session, []Tool{echoTool}, nil)
+	if err != nil {
+		t.Fatalf("ProcessConversationWithTools: %v", err)
+	}
+	if answer != "Nothing to do." {
+		t.Errorf("answer = %q", answer)
+	}
+	if got := atomic.LoadInt32(shows); got != 1 {
+		t.Errorf("sent %d /api/show requests, want 1", got)
+	}
+
+	// The result is cached for the next request
+	if _, err := newOllamaTestClient(server.URL).ProcessWithTools("Hello", []Tool{echoTool}, nil); err != nil {
+		t.Fatalf("ProcessWithTools: %v", err)
+	}
+	if got := atomic.LoadInt32(shows); got != 1 {
+		t.Errorf("sent %d /api/show requests, want the cached result", got)
+	}
+}
+
+func TestOllamaToolSupportFailureIsCached(t *testing.T) {
+	server, shows := newOllamaStandIn(t, http.StatusInternalServerError, nil)
+	client := newOllamaTestClient(server.URL)
+
+	if _, err := client.ProcessWithTools("Hello", []Tool{echoTool}, nil); err != nil {
+		t.Fatalf("ProcessWithTools: %v", err)
+	}
+	if _, err := client.ProcessWithTools("Hello again", []Tool{echoTool}, nil); err != nil {
+		t.Fatalf("ProcessWithTools: %v", err)
+	}
+	if got := atomic.LoadInt32(shows); got != 1 {
+		t.Errorf("sent %d /api/show requests, want 1 while the failure is cached", got)
+	}
+}
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -23,6 +24,7 @@ func NewOpenAIClient(provider *types.LLMProvider) *OpenAIClient {
 	httpClient := NewHTTPClient(HTTPConfig{
 		Timeout: DefaultHTTPTimeout,
 		Headers: headers,
+		Retry:   RetryPolicyForProvider(provider),
 	})
 
 	return &OpenAIClient{
@@ -33,6 +35,11 @@ func NewOpenAIClient(provider *types.LLMProvider) *OpenAIClient {
 
 // ProcessMessage processes a simple message
 func (c *OpenAIClient) ProcessMessage(message string) (string, error) {
+	return c.ProcessMessageContext(context.Background(), message)
+}
+
+// ProcessMessageContext is like ProcessMessage but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessMessageContext(ctx context.Context, message string) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
 	}
@@ -49,7 +56,7 @@ func (c *OpenAIClient) ProcessMessage(message string) (string, error) {
 		},
 	}
 
-	resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+	resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 	if err != nil {
 		return "", err
 	}
@@ -59,6 +66,7 @@ func (c *OpenAIClient) ProcessMessage(message string) (string, error) {
 	if err := UnmarshalJSONResponse(resp, &openAIResp); err != nil {
 		return "", err
 	}
+	recordUsage(ctx, openAIUsage(openAIResp.Usage))
 
 	if len(openAIResp.Choices) == 0 {
 		return "", fmt.Errorf(ErrNoResponseChoices)
@@ -69,6 +77,11 @@ func (c *OpenAIClient) ProcessMessage(message string) (string, error) {
 
 // ProcessWithTools processes a message with available tools
 func (c *OpenAIClient) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return c.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
 	}
@@ -86,8 +99,16 @@ func (c *OpenAIClient) ProcessWithTools(message string, tools []Tool, toolCaller
 	// Create tool processor for validation and execution
 	toolProcessor := NewToolProcessor(tools, toolCaller)
 
+	// The system prompt is one of the messages
+	budget := messageBudget(c.provider, "", tools)
+
 	// Iterative conversation with tools (up to max iterations to prevent infinite loops)
 	for iteration := 0; iteration < MaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		fitOpenAIToolResults(messages, budget)
+
 		reqBody := OpenAIRequest{
 			Model:    c.provider.Model,
 			Messages: messages,
@@ -99,7 +120,7 @@ func (c *OpenAIClient) ProcessWithTools(message string, tools []Tool, toolCaller
 			reqBody.ToolChoice = ToolChoiceAuto
 		}
 
-		resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 		if err != nil {
 			return "", err
 		}
@@ -109,6 +130,7 @@ func (c *OpenAIClient) ProcessWithTools(message string, tools []Tool, toolCaller
 			return "", err
 		}
 		resp.Body.Close()
+		recordUsage(ctx, openAIUsage(openAIResp.Usage))
 
 		if len(openAIResp.Choices) == 0 {
 			return "", fmt.Errorf("no response choices received")
@@ -123,43 +145,7 @@ func (c *OpenAIClient) ProcessWithTools(message string, tools []Tool, toolCaller
 		}
 
 		// Execute tool calls using utility functions
-		for _, toolCall := range choice.Message.ToolCalls {
-			if toolCaller == nil {
-				continue
-			}
-
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-				messages = append(messages, Message{
-					Role:       RoleTool,
-					Content:    fmt.Sprintf(ErrParsingArguments, err),
-					ToolCallID: toolCall.ID,
-					Name:       toolCall.Function.Name,
-				})
-				continue
-			}
-
-			result, err := toolProcessor.ExecuteTool(toolCall.Function.Name, args)
-			if err != nil {
-				messages = append(messages, Message{
-					Role:       RoleTool,
-					Content:    fmt.Sprintf("Error: %v", err),
-					ToolCallID: toolCall.ID,
-					Name:       toolCall.Function.Name,
-				})
-				continue
-			}
-
-			// Use utility function to format result
-			resultStr := FormatToolResult(result)
-
-			messages = append(messages, Message{
-				Role:       RoleTool,
-				Content:    resultStr,
-				ToolCallID: toolCall.ID,
-				Name:       toolCall.Function.Name,
-			})
-		}
+		messages = append(messages, executeOpenAIToolCalls(toolProcessor, toolCaller, choice.Message.ToolCalls)...)
 	}
 
 	return "", fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
@@ -167,26 +153,48 @@ func (c *OpenAIClient) ProcessWithTools(message string, tools []Tool, toolCaller
 
 // ProcessConversation processes a message within conversation context
 func (c *OpenAIClient) ProcessConversation(session *types.ConversationSession) (string, error) {
-	messages := ConvertConversationToOpenAI(session)
+	return c.ProcessConversationContext(context.Background(), session)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessConversationContext(ctx context.Context, session *types.ConversationSession) (string, error) {
+	messages := ConvertConversationToOpenAI(fitConversation(session, c.provider, nil))
 
 	reqBody := OpenAIRequest{
 		Model:    c.provider.Model,
 		Messages: messages,
 	}
 
-	return c.executeRequest(reqBody)
+	return c.executeRequest(ctx, reqBody)
 }
 
 // ProcessConversationWithTools processes conversation with tools
 func (c *OpenAIClient) ProcessConversationWithTools(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
-	messages := ConvertConversationToOpenAIWithTools(session, len(tools))
+	return c.ProcessConversationWithToolsContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationWithToolsContext is like ProcessConversationWithTools but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessConversationWithToolsContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	messages := ConvertConversationToOpenAIWithTools(fitConversation(session, c.provider, tools), len(tools))
 
 	// Use the same tool processing logic as ProcessWithTools but with conversation messages
-	return c.processToolConversation(messages, tools, toolCaller)
+	return c.processToolConversation(ctx, messages, tools, toolCaller)
+}
+
+// fitOpenAIToolResults shortens the tool messages when the loop's messages
+// no longer fit in budget tokens
+func fitOpenAIToolResults(messages []Message, budget int) {
+	var results []*string
+	for i := range messages {
+		if messages[i].Role == RoleTool {
+			results = append(results, &messages[i].Content)
+		}
+	}
+	fitToolResults(results, estimateJSONTokens(messages), budget)
 }
 
 // processToolConversation handles the tool calling loop for both simple and conversation messages
-func (c *OpenAIClient) processToolConversation(messages []Message, tools []Tool, toolCaller ToolCaller) (string, error) {
+func (c *OpenAIClient) processToolConversation(ctx context.Context, messages []Message, tools []Tool, toolCaller ToolCaller) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
 	}
@@ -199,8 +207,16 @@ func (c *OpenAIClient) processToolConversation(messages []Message, tools []Tool,
 	// Create tool processor for validation and execution
 	toolProcessor := NewToolProcessor(tools, toolCaller)
 
+	// The system prompt is one of the messages
+	budget := messageBudget(c.provider, "", tools)
+
 	// Iterative conversation with tools
 	for iteration := 0; iteration < MaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		fitOpenAIToolResults(messages, budget)
+
 		reqBody := OpenAIRequest{
 			Model:    c.provider.Model,
 			Messages: messages,
@@ -211,7 +227,7 @@ func (c *OpenAIClient) processToolConversation(messages []Message, tools []Tool,
 			reqBody.ToolChoice = ToolChoiceAuto
 		}
 
-		resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 		if err != nil {
 			return "", err
 		}
@@ -221,6 +237,7 @@ func (c *OpenAIClient) processToolConversation(messages []Message, tools []Tool,
 			return "", err
 		}
 		resp.Body.Close()
+		recordUsage(ctx, openAIUsage(openAIResp.Usage))
 
 		if len(openAIResp.Choices) == 0 {
 			return "", fmt.Errorf("no response choices received")
@@ -235,50 +252,60 @@ func (c *OpenAIClient) processToolConversation(messages []Message, tools []Tool,
 		}
 
 		// Execute tool calls using utility functions
-		for _, toolCall := range choice.Message.ToolCalls {
-			if toolCaller == nil {
-				continue
-			}
-
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
-				messages = append(messages, Message{
-					Role:       RoleTool,
-					Content:    fmt.Sprintf(ErrParsingArguments, err),
-					ToolCallID: toolCall.ID,
-					Name:       toolCall.Function.Name,
-				})
-				continue
-			}
-
-			result, err := toolProcessor.ExecuteTool(toolCall.Function.Name, args)
-			if err != nil {
-				messages = append(messages, Message{
-					Role:       RoleTool,
-					Content:    fmt.Sprintf("Error: %v", err),
-					ToolCallID: toolCall.ID,
-					Name:       toolCall.Function.Name,
-				})
-				continue
-			}
-
-			// Use utility function to format result
-			resultStr := FormatToolResult(result)
+		messages = append(messages, executeOpenAIToolCalls(toolProcessor, toolCaller, choice.Message.ToolCalls)...)
+	}
 
+	return "", fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
+}
+
+// executeOpenAIToolCalls executes the tool calls of an assistant message and
+// returns the tool messages answering them
+func executeOpenAIToolCalls(toolProcessor *ToolProcessor, toolCaller ToolCaller, toolCalls []ToolCall) []Message {
+	var messages []Message
+
+	for _, toolCall := range toolCalls {
+		if toolCaller == nil {
+			continue
+		}
+
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+			messages = append(messages, Message{
+				Role:       RoleTool,
+				Content:    fmt.Sprintf(ErrParsingArguments, err),
+				ToolCallID: toolCall.ID,
+				Name:       toolCall.Function.Name,
+			})
+			continue
+		}
+
+		result, err := toolProcessor.ExecuteTool(toolCall.Function.Name, args)
+		if err != nil {
 			messages = append(messages, Message{
 				Role:       RoleTool,
-				Content:    resultStr,
+				Content:    fmt.Sprintf("Error: %v", err),
 				ToolCallID: toolCall.ID,
 				Name:       toolCall.Function.Name,
 			})
+			continue
 		}
+
+		// Use utility function to format result
+		resultStr := FormatToolResult(result)
+
+		messages = append(messages, Message{
+			Role:       RoleTool,
+			Content:    resultStr,
+			ToolCallID: toolCall.ID,
+			Name:       toolCall.Function.Name,
+		})
 	}
 
-	return "", fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
+	return messages
 }
 
 // executeRequest executes a simple request without tools
-func (c *OpenAIClient) executeRequest(reqBody OpenAIRequest) (string, error) {
+func (c *OpenAIClient) executeRequest(ctx context.Context, reqBody OpenAIRequest) (string, error) {
 	if c.provider.APIKey == "" {
 		return "", fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
 	}
@@ -288,7 +315,7 @@ func (c *OpenAIClient) executeRequest(reqBody OpenAIRequest) (string, error) {
 		endpoint = c.provider.Endpoint
 	}
 
-	resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+	resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 	if err != nil {
 		return "", err
 	}
@@ -298,6 +325,7 @@ func (c *OpenAIClient) executeRequest(reqBody OpenAIRequest) (string, error) {
 	if err := UnmarshalJSONResponse(resp, &openAIResp); err != nil {
 		return "", err
 	}
+	recordUsage(ctx, openAIUsage(openAIResp.Usage))
 
 	if len(openAIResp.Choices) == 0 {
 		return "", fmt.Errorf(ErrNoResponseChoices)
@@ -341,6 +369,11 @@ func (c *OpenAIClient) GetCapabilities() ClientCapabilities {
 
 // ProcessMessageStream processes a message and streams the response
 func (c *OpenAIClient) ProcessMessageStream(message string) (<-chan StreamChunk, error) {
+	return c.ProcessMessageStreamContext(context.Background(), message)
+}
+
+// ProcessMessageStreamContext is like ProcessMessageStream but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessMessageStreamContext(ctx context.Context, message string) (<-chan StreamChunk, error) {
 	if c.provider.APIKey == "" {
 		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
 	}
@@ -359,10 +392,11 @@ func (c *OpenAIClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 			Messages: []Message{
 				{Role: RoleUser, Content: message},
 			},
-			Stream: true,
+			Stream:        true,
+			StreamOptions: &StreamOptions{IncludeUsage: true},
 		}
 
-		resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 		if err != nil {
 			ch <- StreamChunk{Error: err}
 			return
@@ -378,7 +412,7 @@ func (c *OpenAIClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 		streamHandler := NewStreamHandler(resp.Body)
 		parser := &OpenAISSEParser{}
 
-		if err := streamHandler.ProcessSSEStream(ch, parser); err != nil {
+		if err := streamHandler.ProcessSSEStreamContext(ctx, ch, parser); err != nil {
 			ch <- StreamChunk{Error: err}
 		}
 	}()
@@ -388,6 +422,11 @@ func (c *OpenAIClient) ProcessMessageStream(message string) (<-chan StreamChunk,
 
 // ProcessConversationStream processes conversation and streams the response
 func (c *OpenAIClient) ProcessConversationStream(session *types.ConversationSession) (<-chan StreamChunk, error) {
+	return c.ProcessConversationStreamContext(context.Background(), session)
+}
+
+// ProcessConversationStreamContext is like ProcessConversationStream but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessConversationStreamContext(ctx context.Context, session *types.ConversationSession) (<-chan StreamChunk, error) {
 	if c.provider.APIKey == "" {
 		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
 	}
@@ -396,7 +435,7 @@ func (c *OpenAIClient) ProcessConversationStream(session *types.ConversationSess
 	go func() {
 		defer close(ch)
 
-		messages := ConvertConversationToOpenAI(session)
+		messages := ConvertConversationToOpenAI(fitConversation(session, c.provider, nil))
 
 		endpoint := OpenAIChatCompletionsURL
 		if c.provider.Endpoint != "" {
@@ -404,12 +443,13 @@ func (c *OpenAIClient) ProcessConversationStream(session *types.ConversationSess
 		}
 
 		reqBody := OpenAIRequest{
-			Model:    c.provider.Model,
-			Messages: messages,
-			Stream:   true,
+			Model:         c.provider.Model,
+			Messages:      messages,
+			Stream:        true,
+			StreamOptions: &StreamOptions{IncludeUsage: true},
 		}
 
-		resp, err := c.httpClient.PostJSON(endpoint, reqBody)
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
 		if err != nil {
 			ch <- StreamChunk{Error: err}
 			return
@@ -425,7 +465,7 @@ func (c *OpenAIClient) ProcessConversationStream(session *types.ConversationSess
 		streamHandler := NewStreamHandler(resp.Body)
 		parser := &OpenAISSEParser{}
 
-		if err := streamHandler.ProcessSSEStream(ch, parser); err != nil {
+		if err := streamHandler.ProcessSSEStreamContext(ctx, ch, parser); err != nil {
 			ch <- StreamChunk{Error: err}
 		}
 	}()
@@ -438,9 +478,115 @@ func (c *OpenAIClient) SupportsStreaming() bool {
 	return true
 }
 
+// ProcessConversationWithToolsStream processes conversation with tools and
+// streams the response. Tool calls are accumulated from the streamed deltas
+// and executed when the turn ends, then streaming resumes with the results.
+func (c *OpenAIClient) ProcessConversationWithToolsStream(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (<-chan StreamChunk, error) {
+	return c.ProcessConversationWithToolsStreamContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationWithToolsStreamContext is like ProcessConversationWithToolsStream but aborts the request when ctx is done
+func (c *OpenAIClient) ProcessConversationWithToolsStreamContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (<-chan StreamChunk, error) {
+	if c.provider.APIKey == "" {
+		return nil, fmt.Errorf(ErrAPIKeyRequired, ProviderOpenAI)
+	}
+
+	messages := ConvertConversationToOpenAIWithTools(fitConversation(session, c.provider, tools), len(tools))
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+
+		if err := c.streamToolConversation(ctx, messages, tools, toolCaller, ch); err != nil {
+			ch <- StreamChunk{Error: err}
+			return
+		}
+		ch <- StreamChunk{Done: true}
+	}()
+
+	return ch, nil
+}
+
+// streamToolConversation is the streaming counterpart of processToolConversation
+func (c *OpenAIClient) streamToolConversation(ctx context.Context, messages []Message, tools []Tool, toolCaller ToolCaller, ch chan<- StreamChunk) error {
+	endpoint := OpenAIChatCompletionsURL
+	if c.provider.Endpoint != "" {
+		endpoint = c.provider.Endpoint
+	}
+
+	// Create tool processor for validation and execution
+	toolProcessor := NewToolProcessor(tools, toolCaller)
+	streamed := false
+
+	// The system prompt is one of the messages
+	budget := messageBudget(c.provider, "", tools)
+
+	for iteration := 0; iteration < MaxToolIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fitOpenAIToolResults(messages, budget)
+
+		reqBody := OpenAIRequest{
+			Model:         c.provider.Model,
+			Messages:      messages,
+			Stream:        true,
+			StreamOptions: &StreamOptions{IncludeUsage: true},
+		}
+
+		if len(tools) > 0 {
+			reqBody.Tools = tools
+			reqBody.ToolChoice = ToolChoiceAuto
+		}
+
+		resp, err := c.httpClient.PostJSONContext(ctx, endpoint, reqBody)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode >= 400 {
+			body, _ := ReadResponseBody(resp)
+			return CheckStatusCode(resp, body)
+		}
+
+		// Separate the text of this turn from that of the previous ones
+		parser := &OpenAIToolStreamParser{}
+		if streamed {
+			parser.prefix = "\n\n"
+		}
+
+		err = NewStreamHandler(resp.Body).ForwardSSEStream(ch, parser)
+		resp.Body.Close()
+		recordUsage(ctx, parser.Usage())
+		if err != nil {
+			return err
+		}
+
+		message := parser.Message()
+		messages = append(messages, message)
+		streamed = streamed || message.Content != ""
+
+		// If no tool calls, we're done
+		if len(message.ToolCalls) == 0 {
+			return nil
+		}
+
+		messages = append(messages, executeOpenAIToolCalls(toolProcessor, toolCaller, message.ToolCalls)...)
+	}
+
+	return fmt.Errorf(ErrMaxIterationsExceeded, MaxToolIterations)
+}
+
+// SupportsToolStreaming indicates if this client can stream while calling tools
+func (c *OpenAIClient) SupportsToolStreaming() bool {
+	return true
+}
+
 // getMaxTokensForModel returns the maximum token limit for OpenAI models
 func getMaxTokensForModel(model string) int {
 	switch {
+	case strings.Contains(model, ModelGPT4o), strings.Contains(model, ModelGPT4Turbo):
+		return GPT4oTokenLimit
 	case strings.Contains(model, ModelGPT4):
 		return GPT4TokenLimit
 	case strings.Contains(model, ModelGPT35Turbo):
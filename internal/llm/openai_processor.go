@@ -1,6 +1,8 @@
 package llm
 
 import (
+	"context"
+
 	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
@@ -22,40 +24,70 @@ func NewOpenAIProcessor(provider *types.LLMProvider, promptManager PromptManager
 
 // ProcessWithTools processes a message with tools using OpenAI
 func (p *OpenAIProcessor) ProcessWithTools(message string, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return p.ProcessWithToolsContext(context.Background(), message, tools, toolCaller)
+}
+
+// ProcessWithToolsContext is like ProcessWithTools but aborts the request when ctx is done
+func (p *OpenAIProcessor) ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error) {
 	enhancedMessage := p.buildEnhancedMessage(message, len(tools))
 
 	// Use the new client interface
 	if toolSupport, ok := p.client.(ToolSupport); ok {
-		return toolSupport.ProcessWithTools(enhancedMessage, tools, toolCaller)
+		return toolSupport.ProcessWithToolsContext(ctx, enhancedMessage, tools, toolCaller)
 	}
 
 	// Fallback to simple message processing
-	return p.client.ProcessMessage(enhancedMessage)
+	return p.client.ProcessMessageContext(ctx, enhancedMessage)
 }
 
 // ProcessConversation processes a conversation with tools using OpenAI
 func (p *OpenAIProcessor) ProcessConversation(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
+	return p.ProcessConversationContext(context.Background(), session, tools, toolCaller)
+}
+
+// ProcessConversationContext is like ProcessConversation but aborts the request when ctx is done
+func (p *OpenAIProcessor) ProcessConversationContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error) {
 	// Use the new client interface
 	if conversationSupport, ok := p.client.(ConversationSupport); ok {
 		if len(tools) == 0 {
-			return conversationSupport.ProcessConversation(session)
+			return conversationSupport.ProcessConversationContext(ctx, session)
 		}
-		return conversationSupport.ProcessConversationWithTools(session, tools, toolCaller)
+		return conversationSupport.ProcessConversationWithToolsContext(ctx, session, tools, toolCaller)
 	}
 
 	// Fallback for clients that don't support conversation
 	lastMessage := session.GetLastUserMessage()
-	return p.ProcessWithTools(lastMessage, tools, toolCaller)
+	return p.ProcessWithToolsContext(ctx, lastMessage, tools, toolCaller)
 }
 
 // ProcessWithUI processes a message with tools and UI feedback
 func (p *OpenAIProcessor) ProcessWithUI(message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
-	return p.processWithToolsCommon(p.client, message, tools, toolCaller, display)
+	return p.ProcessWithUIContext(context.Background(), message, tools, toolCaller, display)
+}
+
+// ProcessWithUIContext is like ProcessWithUI but aborts the request when ctx is done
+func (p *OpenAIProcessor) ProcessWithUIContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.processWithToolsCommon(ctx, p.client, message, tools, toolCaller, display)
 }
 
 // ProcessConversationWithUI processes a conversation with tools and UI feedback
 func (p *OpenAIProcessor) ProcessConversationWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
-	return p.processConversationCommon(p.client, session, tools, toolCaller, display)
+	return p.ProcessConversationWithUIContext(context.Background(), session, tools, toolCaller, display)
+}
+
+// ProcessConversationWithUIContext is like ProcessConversationWithUI but aborts the request when ctx is done
+func (p *OpenAIProcessor) ProcessConversationWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	return p.processConversationCommon(ctx, p.client, session, tools, toolCaller, display)
+}
+
+// ProcessConversationStreamWithUI processes a conversation with tools and UI feedback, streaming the response
+func (p *OpenAIProcessor) ProcessConversationStreamWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	return p.ProcessConversationStreamWithUIContext(context.Background(), session, tools, toolCaller, display)
+}
+
+// ProcessConversationStreamWithUIContext is like ProcessConversationStreamWithUI but aborts the request when ctx is done
+func (p *OpenAIProcessor) ProcessConversationStreamWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	return p.processConversationStreamCommon(ctx, p.client, session, tools, toolCaller, display)
 }
 
 // Capability methods
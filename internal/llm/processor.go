@@ -1,7 +1,9 @@
 package llm
 
 import (
+	"context"
 	"fmt"
+	"time"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
 )
@@ -23,6 +25,17 @@ type LLMProcessor interface {
 	// ProcessConversationWithUI processes a conversation with UI feedback support
 	ProcessConversationWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error)
 	
+	// ProcessConversationStreamWithUI processes a conversation with UI feedback support and streams the response
+	ProcessConversationStreamWithUI(session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error)
+	
+	// Context variants of the methods above. They abort the request and
+	// stop the tool loop when ctx is done.
+	ProcessWithToolsContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller) (string, error)
+	ProcessConversationContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller) (string, error)
+	ProcessWithUIContext(ctx context.Context, message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error)
+	ProcessConversationWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error)
+	ProcessConversationStreamWithUIContext(ctx context.Context, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error)
+	
 	// Capability queries
 	SupportsConversation() bool
 	SupportsFunctionCalling() bool
@@ -75,9 +88,21 @@ func (bp *BaseProcessor) getConversationPrompt() string {
 	return bp.promptManager.GetConversationPrompt(bp.provider.Type)
 }
 
+// withRetryProgress returns a context that shows retried requests as
+// progress on the display
+func withRetryProgress(ctx context.Context, display ui.ToolDisplayInterface) context.Context {
+	if display == nil {
+		return ctx
+	}
+	return WithRetryNotifier(ctx, func(attempt RetryAttempt) {
+		display.ShowProgress(fmt.Sprintf(ProgressRetrying, attempt.Reason, attempt.Delay.Round(100*time.Millisecond), attempt.Attempt, attempt.MaxRetries))
+	})
+}
+
 // processWithToolsCommon provides common tool processing logic
-func (bp *BaseProcessor) processWithToolsCommon(client LLMClient, message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+func (bp *BaseProcessor) processWithToolsCommon(ctx context.Context, client LLMClient, message string, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
 	enhancedMessage := bp.buildEnhancedMessage(message, len(tools))
+	ctx = withRetryProgress(ctx, display)
 	
 	// Use UI wrapper if display is provided
 	var wrappedToolCaller ToolCaller
@@ -91,7 +116,7 @@ func (bp *BaseProcessor) processWithToolsCommon(client LLMClient, message string
 		if display != nil {
 			display.ShowProgress(fmt.Sprintf(ProgressProcessingWithLLM, bp.provider.Type))
 		}
-		return client.ProcessMessage(enhancedMessage)
+		return client.ProcessMessageContext(ctx, enhancedMessage)
 	}
 	
 	if display != nil {
@@ -100,7 +125,7 @@ func (bp *BaseProcessor) processWithToolsCommon(client LLMClient, message string
 	
 	// Try to use tool support if available
 	if toolSupport, ok := client.(ToolSupport); ok {
-		result, err := toolSupport.ProcessWithTools(enhancedMessage, tools, wrappedToolCaller)
+		result, err := toolSupport.ProcessWithToolsContext(ctx, enhancedMessage, tools, wrappedToolCaller)
 		if err != nil && display != nil {
 			display.ShowError(err)
 		}
@@ -108,11 +133,13 @@ func (bp *BaseProcessor) processWithToolsCommon(client LLMClient, message string
 	}
 	
 	// Fallback to basic processing
-	return client.ProcessMessage(enhancedMessage)
+	return client.ProcessMessageContext(ctx, enhancedMessage)
 }
 
 // processConversationCommon provides common conversation processing logic
-func (bp *BaseProcessor) processConversationCommon(client LLMClient, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+func (bp *BaseProcessor) processConversationCommon(ctx context.Context, client LLMClient, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (string, error) {
+	requestCtx := withRetryProgress(ctx, display)
+	
 	// Use UI wrapper if display is provided
 	var wrappedToolCaller ToolCaller
 	if display != nil && toolCaller != nil {
@@ -126,11 +153,11 @@ func (bp *BaseProcessor) processConversationCommon(client LLMClient, session *ty
 			display.ShowProgress(fmt.Sprintf(ProgressProcessingConversation, bp.provider.Type))
 		}
 		if conversationSupport, ok := client.(ConversationSupport); ok {
-			return conversationSupport.ProcessConversation(session)
+			return conversationSupport.ProcessConversationContext(requestCtx, session)
 		}
 		// Fallback to simple message processing
 		lastMessage := session.GetLastUserMessage()
-		return client.ProcessMessage(lastMessage)
+		return client.ProcessMessageContext(requestCtx, lastMessage)
 	}
 	
 	if display != nil {
@@ -139,7 +166,7 @@ func (bp *BaseProcessor) processConversationCommon(client LLMClient, session *ty
 	
 	// Try conversation with tools
 	if conversationSupport, ok := client.(ConversationSupport); ok {
-		result, err := conversationSupport.ProcessConversationWithTools(session, tools, wrappedToolCaller)
+		result, err := conversationSupport.ProcessConversationWithToolsContext(requestCtx, session, tools, wrappedToolCaller)
 		if err != nil && display != nil {
 			display.ShowError(err)
 		}
@@ -148,7 +175,49 @@ func (bp *BaseProcessor) processConversationCommon(client LLMClient, session *ty
 	
 	// Fallback to tool processing with last message
 	lastMessage := session.GetLastUserMessage()
-	return bp.processWithToolsCommon(client, lastMessage, tools, toolCaller, display)
+	return bp.processWithToolsCommon(ctx, client, lastMessage, tools, toolCaller, display)
+}
+
+// processConversationStreamCommon provides common streaming conversation logic.
+// Clients that can stream through the tool loop, or plain streaming clients
+// when there are no tools, stream the response as it is produced; otherwise
+// the response is processed as a whole and sent as a single chunk.
+func (bp *BaseProcessor) processConversationStreamCommon(ctx context.Context, client LLMClient, session *types.ConversationSession, tools []Tool, toolCaller ToolCaller, display ui.ToolDisplayInterface) (<-chan StreamChunk, error) {
+	requestCtx := withRetryProgress(ctx, display)
+	
+	// Use UI wrapper if display is provided
+	var wrappedToolCaller ToolCaller
+	if display != nil && toolCaller != nil {
+		wrappedToolCaller = WrapToolCallerWithUI(toolCaller, display)
+	} else {
+		wrappedToolCaller = toolCaller
+	}
+	
+	if len(tools) > 0 {
+		if toolStreaming, ok := client.(ToolStreamingSupport); ok && toolStreaming.SupportsToolStreaming() {
+			if display != nil {
+				display.ShowProgress(fmt.Sprintf(ProgressProcessingConversationWithTools, bp.provider.Type))
+			}
+			return toolStreaming.ProcessConversationWithToolsStreamContext(requestCtx, session, tools, wrappedToolCaller)
+		}
+	} else if streaming, ok := client.(StreamingSupport); ok && streaming.SupportsStreaming() {
+		if display != nil {
+			display.ShowProgress(fmt.Sprintf(ProgressProcessingConversation, bp.provider.Type))
+		}
+		return streaming.ProcessConversationStreamContext(requestCtx, session)
+	}
+	
+	// Fallback to processing the whole response
+	result, err := bp.processConversationCommon(ctx, client, session, tools, toolCaller, display)
+	if err != nil {
+		return nil, err
+	}
+	
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Content: result}
+	ch <- StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
 }
 
 // Common capability implementations
@@ -180,6 +249,8 @@ func (f *DefaultProcessorFactory) CreateProcessor(provider *types.LLMProvider) (
 		return NewOpenAIProcessor(provider, f.promptManager), nil
 	case ProviderAnthropic:
 		return NewAnthropicProcessor(provider, f.promptManager), nil
+	case ProviderGoogle:
+		return NewGeminiProcessor(provider, f.promptManager), nil
 	case ProviderLocal:
 		return NewLocalProcessor(provider, f.promptManager), nil
 	default:
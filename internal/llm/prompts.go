@@ -40,11 +40,11 @@ func NewDefaultPromptManager() *DefaultPromptManager {
 func (pm *DefaultPromptManager) initializeTemplates() {
 	// OpenAI templates
 	pm.templates["openai"] = &PromptTemplate{
-		System: `You are a helpful AI assistant with access to system tools. 
-Use the available tools to provide comprehensive and accurate responses. 
+		System: `You are a helpful AI assistant with access to tools provided by MCP servers.
+Use the available tools to provide comprehensive and accurate responses.
 When using tools, explain what you're doing and why.`,
-		ToolContext: `Available tools: %d desktop tools including file operations, system commands, and process management.
-Use multiple tools as needed to provide comprehensive answers.`,
+		ToolContext: `Available tools: %d tools provided by the connected MCP servers.
+Pick the tools whose names and descriptions match the task and use several as needed.`,
 		ErrorHints: map[string]string{
 			"file_not_found":    "The file or directory doesn't exist. Try checking the correct path or suggest alternatives.",
 			"permission_denied": "Permission denied. Consider suggesting alternative approaches or checking file permissions.",
@@ -57,10 +57,9 @@ Use available tools when needed to provide accurate and helpful responses.`,
 
 	// Anthropic templates
 	pm.templates["anthropic"] = &PromptTemplate{
-		System: `You are Claude, a helpful AI assistant. You have access to various system tools
-that allow you to interact with the desktop environment. Use these tools thoughtfully
-to provide accurate and comprehensive responses.`,
-		ToolContext: `You have access to %d system tools for file operations, process management, and system commands.
+		System: `You are Claude, a helpful AI assistant. You have access to tools provided by
+MCP servers. Use these tools thoughtfully to provide accurate and comprehensive responses.`,
+		ToolContext: `You have access to %d tools provided by the connected MCP servers.
 Utilize these tools effectively to gather information and complete tasks.`,
 		ErrorHints: map[string]string{
 			"file_not_found":    "The requested file or directory could not be found. Please verify the path is correct.",
@@ -74,15 +73,15 @@ Leverage available tools to enhance your responses with real-time information.`,
 
 	// Local LLM templates (simplified for better performance)
 	pm.templates["local"] = &PromptTemplate{
-		System: `You are a helpful AI assistant with access to system tools. 
-Use the available tools to provide comprehensive and accurate responses. 
+		System: `You are a helpful AI assistant with access to tools provided by MCP servers.
+Use the available tools to provide comprehensive and accurate responses.
 When using tools, explain what you're doing and why.`,
-		ToolContext: `Available tools: %d desktop tools including file operations, system commands, and process management.
-Use multiple tools as needed to provide comprehensive answers.`,
+		ToolContext: `Available tools: %d tools provided by the connected MCP servers.
+Pick the tools whose names and descriptions match the task and use several as needed.`,
 		ErrorHints: map[string]string{
-			"file_not_found":    "Try list_directory with different path",
+			"file_not_found":    "Try a different path or a tool that lists what exists",
 			"permission_denied": "Try alternative approach or tool",
-			"network_error":     "Use local tools only",
+			"network_error":     "Try tools that do not need the network",
 			"tool_error":        "Tool execution failed. Try alternative tools or approaches to accomplish the task.",
 		},
 		Conversation: `Continue the conversation naturally, maintaining context from previous messages.
@@ -142,3 +141,63 @@ func (pm *DefaultPromptManager) getTemplate(llmType string) *PromptTemplate {
 	}
 	return pm.templates["default"]
 }
+
+// ServerInstructions holds the usage instructions an MCP server returned
+// from initialize, or the user's override for them
+type ServerInstructions struct {
+	Server       string
+	Instructions string
+}
+
+// baseSystemPrompt is the server-independent guidance used when the user has
+// not configured their own system prompt
+const baseSystemPrompt = `You are a system engineer AI assistant with access to tools provided by MCP servers.
+
+TOOL USAGE PRIORITY:
+1. Use tools for system operations instead of describing what you could do:
+   - Network, file, process and system information tasks
+   - Command execution
+   Pick the tool whose name and description best match the task.
+
+2. Use conversation context for follow-up questions:
+   - "What did I just do?" → Answer from conversation history
+   - "Where did you save that file?" → Reference previous actions
+   - "Show me that again" → Use previous results
+
+3. Answer directly WITHOUT tools only for:
+   - Pure greetings (안녕, hello, hi)
+   - General knowledge questions unrelated to system
+   - Questions about previous conversation content
+
+ERROR HANDLING STRATEGY:
+• When a tool call fails, ALWAYS try alternative approaches before giving up
+• If a path doesn't exist, try common alternative locations
+• If a command fails, try variations or related commands
+• Provide helpful suggestions even when tools fail
+
+Remember: Use tools proactively! Don't just describe what you COULD do - actually DO it with the tools available.`
+
+// ComposeSystemPrompt builds the system prompt from the base guidance (or
+// the user's override of it), the number of available tools and the
+// instructions of each server that contributed tools
+func ComposeSystemPrompt(toolCount int, servers []ServerInstructions, override string) string {
+	var builder strings.Builder
+
+	if strings.TrimSpace(override) != "" {
+		builder.WriteString(strings.TrimSpace(override))
+	} else {
+		builder.WriteString(baseSystemPrompt)
+	}
+
+	builder.WriteString(fmt.Sprintf("\n\nCURRENT STATUS: You have %d tools available.", toolCount))
+
+	for _, server := range servers {
+		instructions := strings.TrimSpace(server.Instructions)
+		if instructions == "" {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\n\nINSTRUCTIONS FROM MCP SERVER %q:\n%s", server.Server, instructions))
+	}
+
+	return builder.String()
+}
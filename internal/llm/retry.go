@@ -0,0 +1,278 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// RetryPolicy configures how requests failing with a transient error are
+// retried
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt; 0 disables retrying
+	BaseDelay  time.Duration // backoff before the first retry, doubled for each further one
+	MaxDelay   time.Duration // longest wait for a single retry
+}
+
+// DefaultRetryPolicy returns the retry policy used unless a provider
+// configures its own number of retries
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  RetryBaseDelay,
+		MaxDelay:   RetryMaxDelay,
+	}
+}
+
+// RetryPolicyForProvider returns the default retry policy with the number of
+// retries taken from the max_retries setting of the provider config, if set
+func RetryPolicyForProvider(provider *types.LLMProvider) RetryPolicy {
+	policy := DefaultRetryPolicy()
+
+	// Numbers read back from the JSON config are float64
+	switch retries := provider.Config[ConfigMaxRetries].(type) {
+	case int:
+		policy.MaxRetries = retries
+	case float64:
+		policy.MaxRetries = int(retries)
+	}
+	if policy.MaxRetries < 0 {
+		policy.MaxRetries = 0
+	}
+
+	return policy
+}
+
+// RetryAttempt describes a retry about to be made
+type RetryAttempt struct {
+	Attempt    int           // 1 for the first retry
+	MaxRetries int           // retries allowed by the policy
+	Delay      time.Duration // wait before the retry
+	Reason     string        // why the previous attempt failed
+}
+
+// RetryNotifier is told about each retry before waiting for it
+type RetryNotifier func(attempt RetryAttempt)
+
+type retryNotifierKey struct{}
+
+// WithRetryNotifier returns a context that reports the retries of requests
+// made with it to notify
+func WithRetryNotifier(ctx context.Context, notify RetryNotifier) context.Context {
+	return context.WithValue(ctx, retryNotifierKey{}, notify)
+}
+
+// StatusError is the error of a request the API answered with an error status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf(ErrAPIRequestFailed, e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the request may succeed when tried again
+func (e *StatusError) Temporary() bool {
+	return isRetryableStatus(e.StatusCode)
+}
+
+// rateLimitHeaders pairs the remaining and reset headers of the rate limits
+// OpenAI and Anthropic report. OpenAI resets are durations like "6m0s",
+// Anthropic ones RFC 3339 times.
+var rateLimitHeaders = []struct{ remaining, reset string }{
+	{"x-ratelimit-remaining-requests", "x-ratelimit-reset-requests"},
+	{"x-ratelimit-remaining-tokens", "x-ratelimit-reset-tokens"},
+	{"anthropic-ratelimit-requests-remaining", "anthropic-ratelimit-requests-reset"},
+	{"anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-tokens-reset"},
+	{"anthropic-ratelimit-input-tokens-remaining", "anthropic-ratelimit-input-tokens-reset"},
+	{"anthropic-ratelimit-output-tokens-remaining", "anthropic-ratelimit-output-tokens-reset"},
+}
+
+// doWithRetry sends req, retrying connection errors and transient error
+// statuses with jittered exponential backoff. A wait the server asks for with
+// Retry-After or its rate limit headers is honored, unless it is longer than
+// the policy's MaxDelay. The request body must be replayable, which it is for
+// requests created with a bytes.Buffer or bytes.Reader body.
+func doWithRetry(client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	notify, _ := ctx.Value(retryNotifierKey{}).(RetryNotifier)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+
+		retry, reason := shouldRetry(ctx, resp, err)
+		if !retry || attempt >= policy.MaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay, ok := retryDelay(resp, attempt, policy)
+		if !ok {
+			return resp, err
+		}
+
+		if resp != nil {
+			// Drain the body so that the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		debugPrint("Request failed (%s), retrying in %v\n", reason, delay)
+		if notify != nil {
+			notify(RetryAttempt{Attempt: attempt + 1, MaxRetries: policy.MaxRetries, Delay: delay, Reason: reason})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether a request that got resp or err is worth
+// retrying, and why it failed
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, string) {
+	if err != nil {
+		// Cancelled requests and timeouts are not retried; a request that
+		// timed out would most likely just time out again
+		var netErr net.Error
+		if ctx.Err() != nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return false, ""
+		}
+		return true, err.Error()
+	}
+
+	if isRetryableStatus(resp.StatusCode) {
+		return true, fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return false, ""
+}
+
+// isRetryableStatus reports whether a status means the server could not
+// handle the request right now: timeouts, rate limits, server errors and
+// overload (Anthropic's 529)
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, StatusOverloaded:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryDelay returns how long to wait before the next attempt. It is false
+// if the server asks for a longer wait than the policy allows.
+func retryDelay(resp *http.Response, attempt int, policy RetryPolicy) (time.Duration, bool) {
+	if resp != nil {
+		if delay, ok := serverRetryDelay(resp, time.Now()); ok {
+			return delay, delay <= policy.MaxDelay
+		}
+	}
+
+	backoff := policy.BaseDelay << attempt
+	if backoff <= 0 || backoff > policy.MaxDelay {
+		backoff = policy.MaxDelay
+	}
+
+	// Equal jitter: half of the backoff is fixed, the other half random, so
+	// that clients failing together do not retry together
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)), true
+}
+
+// serverRetryDelay returns the wait the server asked for, if any
+func serverRetryDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	header := resp.Header
+
+	if value := header.Get(HeaderRetryAfterMS); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+
+	if value := header.Get(HeaderRetryAfter); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return nonNegative(at.Sub(now)), true
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	// Without Retry-After, wait for the exhausted rate limits to reset
+	var delay time.Duration
+	found := false
+	for _, limit := range rateLimitHeaders {
+		if strings.TrimSpace(header.Get(limit.remaining)) != "0" {
+			continue
+		}
+		if reset, ok := parseRateLimitReset(header.Get(limit.reset), now); ok {
+			if !found || reset > delay {
+				delay = reset
+			}
+			found = true
+		}
+	}
+
+	return delay, found
+}
+
+// parseRateLimitReset parses a rate limit reset given as a duration or as an
+// RFC 3339 time
+func parseRateLimitReset(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return nonNegative(at.Sub(now)), true
+	}
+	if delay, err := time.ParseDuration(value); err == nil {
+		return nonNegative(delay), true
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// rewind returns a copy of req with a fresh body for sending it again
+func rewind(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf(ErrFailedToCreateRequest, err)
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
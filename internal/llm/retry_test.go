@@ -0,0 +1,292 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// retryStandIn answers the requests in turn with the given statuses and
+// headers, repeating the last one, and records the bodies it received
+type retryStandIn struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+type cannedResponse struct {
+	status int
+	header map[string]string
+}
+
+func newRetryStandIn(t *testing.T, responses ...cannedResponse) *retryStandIn {
+	t.Helper()
+
+	standIn := &retryStandIn{}
+	standIn.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		standIn.mu.Lock()
+		standIn.bodies = append(standIn.bodies, string(body))
+		n := len(standIn.bodies)
+		standIn.mu.Unlock()
+
+		response := responses[len(responses)-1]
+		if n <= len(responses) {
+			response = responses[n-1]
+		}
+		for key, value := range response.header {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(response.status)
+		io.WriteString(w, http.StatusText(response.status))
+	}))
+	t.Cleanup(standIn.Close)
+
+	return standIn
+}
+
+func (s *retryStandIn) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func (s *retryStandIn) post(t *testing.T, ctx context.Context, policy RetryPolicy) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader([]byte(`{"prompt":"hi"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := doWithRetry(s.Client(), req, policy)
+	if resp != nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+var fastRetries = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+func TestRetryRateLimitThenSuccess(t *testing.T) {
+	standIn := newRetryStandIn(t,
+		cannedResponse{status: http.StatusTooManyRequests},
+		cannedResponse{status: http.StatusOK},
+	)
+
+	var attempts []RetryAttempt
+	ctx := WithRetryNotifier(context.Background(), func(attempt RetryAttempt) {
+		attempts = append(attempts, attempt)
+	})
+
+	resp, err := standIn.post(t, ctx, fastRetries)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("response = %v, %v, want 200", resp, err)
+	}
+	if standIn.requests() != 2 {
+		t.Errorf("sent %d requests, want 2", standIn.requests())
+	}
+	if len(attempts) != 1 || attempts[0].Attempt != 1 || attempts[0].Reason != "status 429" {
+		t.Errorf("notified %+v", attempts)
+	}
+
+	// The body is sent again with the retry
+	for i, body := range standIn.bodies {
+		if body != `{"prompt":"hi"}` {
+			t.Errorf("body of request %d = %q", i+1, body)
+		}
+	}
+}
+
+func TestRetryHonorsRetryAfterMS(t *testing.T) {
+	standIn := newRetryStandIn(t,
+		cannedResponse{status: http.StatusServiceUnavailable, header: map[string]string{HeaderRetryAfterMS: "40"}},
+		cannedResponse{status: http.StatusOK},
+	)
+
+	start := time.Now()
+	resp, err := standIn.post(t, context.Background(), fastRetries)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("response = %v, %v, want 200", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("retried after %s, want at least the 40ms asked for", elapsed)
+	}
+}
+
+func TestRetryHonorsRetryAfterSeconds(t *testing.T) {
+	standIn := newRetryStandIn(t,
+		cannedResponse{status: http.StatusTooManyRequests, header: map[string]string{HeaderRetryAfter: "1"}},
+		cannedResponse{status: http.StatusOK},
+	)
+
+	var delay time.Duration
+	ctx := WithRetryNotifier(context.Background(), func(attempt RetryAttempt) {
+		delay = attempt.Delay
+	})
+
+	policy := fastRetries
+	policy.MaxDelay = 2 * time.Second
+	start := time.Now()
+	resp, err := standIn.post(t, ctx, policy)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("response = %v, %v, want 200", resp, err)
+	}
+	if delay != time.Second || time.Since(start) < time.Second {
+		t.Errorf("waited %s (notified %s), want the second asked for", time.Since(start), delay)
+	}
+}
+
+func TestRetryGivesUpOnLongRetryAfter(t *testing.T) {
+	standIn := newRetryStandIn(t,
+		cannedResponse{status: http.StatusTooManyRequests, header: map[string]string{HeaderRetryAfter: "60"}},
+		cannedResponse{status: http.StatusOK},
+	)
+
+	resp, err := standIn.post(t, context.Background(), fastRetries)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("response = %v, %v, want the 429", resp, err)
+	}
+	if standIn.requests() != 1 {
+		t.Errorf("sent %d requests, want 1", standIn.requests())
+	}
+}
+
+func TestRetryStopsAtMaxRetries(t *testing.T) {
+	standIn := newRetryStandIn(t, cannedResponse{status: StatusOverloaded})
+
+	resp, err := standIn.post(t, context.Background(), fastRetries)
+	if err != nil || resp.StatusCode != StatusOverloaded {
+		t.Fatalf("response = %v, %v, want the last 529", resp, err)
+	}
+	if standIn.requests() != fastRetries.MaxRetries+1 {
+		t.Errorf("sent %d requests, want %d", standIn.requests(), fastRetries.MaxRetries+1)
+	}
+}
+
+func TestRetryDoesNotRetryClientErrors(t *testing.T) {
+	standIn := newRetryStandIn(t, cannedResponse{status: http.StatusBadRequest})
+
+	resp, err := standIn.post(t, context.Background(), fastRetries)
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("response = %v, %v, want the 400", resp, err)
+	}
+	if standIn.requests() != 1 {
+		t.Errorf("sent %d requests, want 1", standIn.requests())
+	}
+}
+
+func TestRetryCancelledDuringBackoff(t *testing.T) {
+	standIn := newRetryStandIn(t, cannedResponse{status: http.StatusServiceUnavailable, header: map[string]string{HeaderRetryAfter: "5"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = WithRetryNotifier(ctx, func(RetryAttempt) { cancel() })
+
+	policy := fastRetries
+	policy.MaxDelay = time.Minute
+	start := time.Now()
+	_, err := standIn.post(t, ctx, policy)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %s, want right after the cancel", elapsed)
+	}
+	if standIn.requests() != 1 {
+		t.Errorf("sent %d requests, want 1", standIn.requests())
+	}
+}
+
+func TestServerRetryDelay(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status int
+		header map[string]string
+		delay  time.Duration
+		found  bool
+	}{
+		{"none", http.StatusServiceUnavailable, nil, 0, false},
+		{"seconds", http.StatusServiceUnavailable, map[string]string{HeaderRetryAfter: "7"}, 7 * time.Second, true},
+		{"HTTP date", http.StatusServiceUnavailable, map[string]string{HeaderRetryAfter: now.Add(90 * time.Second).Format(http.TimeFormat)}, 90 * time.Second, true},
+		{"HTTP date in the past", http.StatusServiceUnavailable, map[string]string{HeaderRetryAfter: now.Add(-time.Minute).Format(http.TimeFormat)}, 0, true},
+		{"milliseconds first", http.StatusTooManyRequests, map[string]string{HeaderRetryAfterMS: "1500", HeaderRetryAfter: "7"}, 1500 * time.Millisecond, true},
+		{"invalid", http.StatusServiceUnavailable, map[string]string{HeaderRetryAfter: "soon"}, 0, false},
+		{
+			"exhausted OpenAI limits",
+			http.StatusTooManyRequests,
+			map[string]string{
+				"x-ratelimit-remaining-requests": "0",
+				"x-ratelimit-reset-requests":     "2s",
+				"x-ratelimit-remaining-tokens":   "0",
+				"x-ratelimit-reset-tokens":       "6m0s",
+			},
+			6 * time.Minute, true,
+		},
+		{
+			"only exhausted limits count",
+			http.StatusTooManyRequests,
+			map[string]string{
+				"anthropic-ratelimit-requests-remaining": "10",
+				"anthropic-ratelimit-requests-reset":     now.Add(time.Hour).Format(time.RFC3339),
+				"anthropic-ratelimit-tokens-remaining":   "0",
+				"anthropic-ratelimit-tokens-reset":       now.Add(20 * time.Second).Format(time.RFC3339),
+			},
+			20 * time.Second, true,
+		},
+		{
+			"rate limit headers only for 429",
+			http.StatusServiceUnavailable,
+			map[string]string{"x-ratelimit-remaining-requests": "0", "x-ratelimit-reset-requests": "2s"},
+			0, false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.header {
+				resp.Header.Set(key, value)
+			}
+			delay, found := serverRetryDelay(resp, now)
+			if delay != tt.delay || found != tt.found {
+				t.Errorf("serverRetryDelay() = %s, %v, want %s, %v", delay, found, tt.delay, tt.found)
+			}
+		})
+	}
+}
+
+func TestParseRateLimitReset(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"1s", time.Second, true},
+		{"6m0s", 6 * time.Minute, true},
+		{"250ms", 250 * time.Millisecond, true},
+		{" 2s ", 2 * time.Second, true},
+		{"2025-01-02T03:04:35Z", 30 * time.Second, true},
+		{"2025-01-02T03:04:00Z", 0, true},
+		{"-5s", 0, true},
+		{"tomorrow", 0, false},
+	}
+
+	for _, tt := range tests {
+		delay, ok := parseRateLimitReset(tt.value, now)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("parseRateLimitReset(%q) = %s, %v, want %s, %v", tt.value, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
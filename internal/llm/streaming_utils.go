@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,18 +21,42 @@ func NewStreamHandler(reader io.Reader) *StreamHandler {
 	}
 }
 
-// ProcessSSEStream processes Server-Sent Events stream
+// ProcessSSEStream processes Server-Sent Events stream and signals the end
+// of the stream with a Done chunk. The caller owns the channel and reports
+// the returned error.
 func (s *StreamHandler) ProcessSSEStream(ch chan<- StreamChunk, parser SSEParser) error {
-	defer close(ch)
-	
+	return s.ProcessSSEStreamContext(context.Background(), ch, parser)
+}
+
+// ProcessSSEStreamContext is like ProcessSSEStream but also reports the token
+// usage the parser collected to the usage recorder of ctx, before the end of
+// the stream is signalled
+func (s *StreamHandler) ProcessSSEStreamContext(ctx context.Context, ch chan<- StreamChunk, parser SSEParser) error {
+	err := s.ForwardSSEStream(ch, parser)
+	if usage, ok := parser.(usageParser); ok {
+		recordUsage(ctx, usage.Usage())
+	}
+	if err != nil {
+		return err
+	}
+
+	ch <- StreamChunk{Done: true}
+	return nil
+}
+
+// ForwardSSEStream passes the content of a Server-Sent Events stream on
+// until the parser reports its end, without signalling completion. Tool
+// loops use it to stream several responses into one channel. A stream that
+// ends before the parser saw its end event was cut off, which is reported
+// as io.ErrUnexpectedEOF.
+func (s *StreamHandler) ForwardSSEStream(ch chan<- StreamChunk, parser SSEParser) error {
 	for {
 		line, err := s.reader.ReadString('\n')
-		if err != nil {
+		if err != nil && (err != io.EOF || strings.TrimSpace(line) == "") {
 			if err == io.EOF {
-				break
+				return fmt.Errorf(ErrStreamEndedEarly, io.ErrUnexpectedEOF)
 			}
-			ch <- StreamChunk{Error: err}
-			return err
+			return fmt.Errorf(ErrStreamingReadError, err)
 		}
 		
 		line = strings.TrimSpace(line)
@@ -41,21 +66,17 @@ func (s *StreamHandler) ProcessSSEStream(ch chan<- StreamChunk, parser SSEParser
 		
 		chunk, done, err := parser.ParseLine(line)
 		if err != nil {
-			ch <- StreamChunk{Error: err}
 			return err
 		}
 		
-		if chunk.Content != "" || chunk.Error != nil {
+		if chunk.Content != "" || chunk.Error != nil || chunk.StopReason != "" {
 			ch <- chunk
 		}
 		
 		if done {
-			ch <- StreamChunk{Done: true}
-			break
+			return nil
 		}
 	}
-	
-	return nil
 }
 
 // SSEParser defines interface for parsing different SSE formats
@@ -63,8 +84,16 @@ type SSEParser interface {
 	ParseLine(line string) (StreamChunk, bool, error)
 }
 
+// usageParser is implemented by parsers that collect the token usage the
+// stream reports
+type usageParser interface {
+	Usage() *TokenUsage
+}
+
 // OpenAISSEParser parses OpenAI-style SSE
-type OpenAISSEParser struct{}
+type OpenAISSEParser struct {
+	usage *TokenUsage
+}
 
 func (p *OpenAISSEParser) ParseLine(line string) (StreamChunk, bool, error) {
 	if strings.HasPrefix(line, StreamDataPrefix) {
@@ -78,6 +107,10 @@ func (p *OpenAISSEParser) ParseLine(line string) (StreamChunk, bool, error) {
 			return StreamChunk{Error: fmt.Errorf(ErrFailedToParseResponse, err)}, false, err
 		}
 		
+		if response.Usage != nil {
+			p.usage = openAIUsage(response.Usage)
+		}
+		
 		if len(response.Choices) > 0 && response.Choices[0].Delta.Content != "" {
 			return StreamChunk{Content: response.Choices[0].Delta.Content}, false, nil
 		}
@@ -86,29 +119,287 @@ func (p *OpenAISSEParser) ParseLine(line string) (StreamChunk, bool, error) {
 	return StreamChunk{}, false, nil
 }
 
-// AnthropicSSEParser parses Anthropic-style SSE
-type AnthropicSSEParser struct{}
+// Usage returns the token usage sent at the end of the stream, or nil
+func (p *OpenAISSEParser) Usage() *TokenUsage {
+	return p.usage
+}
+
+// OpenAIToolStreamParser parses an OpenAI-style SSE stream that may contain
+// tool calls. Content is passed on as it arrives while the tool call
+// fragments are accumulated into the assistant message of the turn.
+type OpenAIToolStreamParser struct {
+	// prefix is put in front of the first content of the turn
+	prefix    string
+	content   strings.Builder
+	toolCalls []ToolCall
+	usage     *TokenUsage
+}
+
+func (p *OpenAIToolStreamParser) ParseLine(line string) (StreamChunk, bool, error) {
+	if !strings.HasPrefix(line, StreamDataPrefix) {
+		return StreamChunk{}, false, nil
+	}
+
+	data := strings.TrimPrefix(line, StreamDataPrefix)
+	if data == StreamDoneMarker {
+		return StreamChunk{}, true, nil
+	}
+
+	var response OpenAIStreamResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		return StreamChunk{}, false, fmt.Errorf(ErrFailedToParseResponse, err)
+	}
+
+	if response.Usage != nil {
+		p.usage = openAIUsage(response.Usage)
+	}
+
+	if len(response.Choices) == 0 {
+		return StreamChunk{}, false, nil
+	}
+
+	delta := response.Choices[0].Delta
+	for _, fragment := range delta.ToolCalls {
+		for len(p.toolCalls) <= fragment.Index {
+			p.toolCalls = append(p.toolCalls, ToolCall{Type: "function"})
+		}
+		toolCall := &p.toolCalls[fragment.Index]
+		if fragment.ID != "" {
+			toolCall.ID = fragment.ID
+		}
+		if fragment.Type != "" {
+			toolCall.Type = fragment.Type
+		}
+		toolCall.Function.Name += fragment.Function.Name
+		toolCall.Function.Arguments += fragment.Function.Arguments
+	}
+
+	if delta.Content == "" {
+		return StreamChunk{}, false, nil
+	}
+
+	content := delta.Content
+	if p.content.Len() == 0 {
+		content = p.prefix + content
+	}
+	p.content.WriteString(delta.Content)
+	return StreamChunk{Content: content}, false, nil
+}
+
+// Message returns the assistant message streamed so far
+func (p *OpenAIToolStreamParser) Message() Message {
+	return Message{
+		Role:      RoleAssistant,
+		Content:   p.content.String(),
+		ToolCalls: p.toolCalls,
+	}
+}
+
+// Usage returns the token usage sent at the end of the stream, or nil
+func (p *OpenAIToolStreamParser) Usage() *TokenUsage {
+	return p.usage
+}
+
+// AnthropicSSEParser parses Anthropic-style SSE. Text deltas are passed on as
+// they arrive; the content blocks of the message, including tool_use blocks
+// assembled from their partial input JSON, are collected for tool loops.
+type AnthropicSSEParser struct {
+	// prefix is put in front of the first text of the message
+	prefix   string
+	streamed bool
+	blocks   []AnthropicContent
+	inputs   map[int]*strings.Builder
+	usage    *TokenUsage
+}
 
 func (p *AnthropicSSEParser) ParseLine(line string) (StreamChunk, bool, error) {
-	if strings.HasPrefix(line, StreamDataPrefix) {
-		data := strings.TrimPrefix(line, StreamDataPrefix)
-		
-		var event AnthropicStreamEvent
-		if err := json.Unmarshal([]byte(data), &event); err != nil {
-			return StreamChunk{Error: fmt.Errorf(ErrFailedToParseResponse, err)}, false, err
+	if !strings.HasPrefix(line, StreamDataPrefix) {
+		return StreamChunk{}, false, nil
+	}
+
+	data := strings.TrimPrefix(line, StreamDataPrefix)
+
+	var event AnthropicStreamEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return StreamChunk{}, false, fmt.Errorf(ErrFailedToParseResponse, err)
+	}
+
+	switch event.Type {
+	case AnthropicEventMessageStart:
+		if event.Message != nil {
+			p.addUsage(event.Message.Usage)
 		}
-		
-		switch event.Type {
-		case AnthropicEventContentBlock:
-			if event.Delta != nil && event.Delta.Text != "" {
-				return StreamChunk{Content: event.Delta.Text}, false, nil
+	case AnthropicEventContentBlockStart:
+		if event.ContentBlock != nil {
+			block := *event.ContentBlock
+			// The input of a tool_use block arrives in input_json_delta events
+			if block.Type == ContentTypeToolUse {
+				block.Input = nil
 			}
-		case "message_stop":
-			return StreamChunk{}, true, nil
+			p.setBlock(event.Index, block)
+		}
+	case AnthropicEventContentBlock:
+		if event.Delta == nil {
+			break
+		}
+		switch event.Delta.Type {
+		case AnthropicDeltaInputJSON:
+			if p.inputs == nil {
+				p.inputs = make(map[int]*strings.Builder)
+			}
+			if p.inputs[event.Index] == nil {
+				p.inputs[event.Index] = &strings.Builder{}
+			}
+			p.inputs[event.Index].WriteString(event.Delta.PartialJSON)
+		default:
+			if event.Delta.Text == "" {
+				break
+			}
+			if block := p.block(event.Index); block != nil {
+				block.Text += event.Delta.Text
+			} else {
+				p.setBlock(event.Index, AnthropicContent{Type: ContentTypeText, Text: event.Delta.Text})
+			}
+			text := event.Delta.Text
+			if !p.streamed {
+				text = p.prefix + text
+				p.streamed = true
+			}
+			return StreamChunk{Content: text}, false, nil
+		}
+	case AnthropicEventContentBlockStop:
+		if block := p.block(event.Index); block != nil && block.Type == ContentTypeToolUse {
+			p.finishToolUse(event.Index, block)
+		}
+	case AnthropicEventMessage:
+		p.addUsage(event.Usage)
+		if event.Delta != nil && event.Delta.StopReason != "" {
+			return StreamChunk{StopReason: event.Delta.StopReason, Usage: p.usage}, false, nil
+		}
+	case AnthropicEventMessageStop:
+		return StreamChunk{}, true, nil
+	case AnthropicEventError:
+		if event.Error != nil {
+			return StreamChunk{}, false, fmt.Errorf("Anthropic stream error (%s): %s", event.Error.Type, event.Error.Message)
 		}
+		return StreamChunk{}, false, fmt.Errorf("Anthropic stream error: %s", data)
 	}
-	
+
 	return StreamChunk{}, false, nil
 }
 
+// Content returns the content blocks of the message streamed so far
+func (p *AnthropicSSEParser) Content() []AnthropicContent {
+	var content []AnthropicContent
+	for i, block := range p.blocks {
+		// Empty text blocks are rejected when sent back
+		if block.Type == "" || (block.Type == ContentTypeText && block.Text == "") {
+			continue
+		}
+		// A tool_use block the stream ended in
+		if block.Type == ContentTypeToolUse && block.Input == nil {
+			p.finishToolUse(i, &block)
+		}
+		content = append(content, block)
+	}
+	return content
+}
+
+// finishToolUse sets the input of a tool_use block from its partial JSON.
+// Input cut off by max_tokens is not valid JSON and cannot be sent back, so
+// it is replaced by an empty object and the block is marked incomplete.
+func (p *AnthropicSSEParser) finishToolUse(index int, block *AnthropicContent) {
+	input := "{}"
+	if partial, ok := p.inputs[index]; ok && partial.Len() > 0 {
+		input = partial.String()
+	}
+	if !json.Valid([]byte(input)) {
+		input = "{}"
+		block.incomplete = true
+	}
+	block.Input = json.RawMessage(input)
+}
+
+// setBlock stores the content block with the given index
+func (p *AnthropicSSEParser) setBlock(index int, block AnthropicContent) {
+	for len(p.blocks) <= index {
+		p.blocks = append(p.blocks, AnthropicContent{})
+	}
+	p.blocks[index] = block
+}
 
+// block returns the content block with the given index, or nil
+func (p *AnthropicSSEParser) block(index int) *AnthropicContent {
+	if index < 0 || index >= len(p.blocks) || p.blocks[index].Type == "" {
+		return nil
+	}
+	return &p.blocks[index]
+}
+
+// addUsage records token counts. message_delta reports the cumulative
+// output tokens, so later counts replace earlier ones.
+func (p *AnthropicSSEParser) addUsage(usage *AnthropicUsage) {
+	if usage == nil {
+		return
+	}
+	if p.usage == nil {
+		p.usage = &TokenUsage{}
+	}
+	if input := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens; input > 0 {
+		p.usage.InputTokens = usage.InputTokens
+		p.usage.CacheReadTokens = usage.CacheReadInputTokens
+		p.usage.CacheWriteTokens = usage.CacheCreationInputTokens
+	}
+	if usage.OutputTokens > 0 {
+		p.usage.OutputTokens = usage.OutputTokens
+	}
+}
+
+// Usage returns the token usage of the message streamed so far, or nil
+func (p *AnthropicSSEParser) Usage() *TokenUsage {
+	return p.usage
+}
+
+// GeminiSSEParser parses the SSE stream of streamGenerateContent, in which
+// every event is a complete response holding the next part of the answer
+type GeminiSSEParser struct {
+	usage *TokenUsage
+}
+
+func (p *GeminiSSEParser) ParseLine(line string) (StreamChunk, bool, error) {
+	if !strings.HasPrefix(line, StreamDataPrefix) {
+		return StreamChunk{}, false, nil
+	}
+
+	data := strings.TrimPrefix(line, StreamDataPrefix)
+
+	var response GeminiResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		return StreamChunk{Error: fmt.Errorf(ErrFailedToParseResponse, err)}, false, err
+	}
+
+	// Every event reports the usage of the response so far
+	if response.UsageMetadata != nil {
+		p.usage = geminiUsage(response.UsageMetadata)
+	}
+
+	if len(response.Candidates) == 0 {
+		if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+			return StreamChunk{Error: fmt.Errorf("prompt was blocked by Gemini: %s", response.PromptFeedback.BlockReason)}, true, nil
+		}
+		return StreamChunk{}, false, nil
+	}
+
+	candidate := response.Candidates[0]
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		text.WriteString(part.Text)
+	}
+
+	return StreamChunk{Content: text.String()}, candidate.FinishReason != "", nil
+}
+
+// Usage returns the token usage of the response streamed so far, or nil
+func (p *GeminiSSEParser) Usage() *TokenUsage {
+	return p.usage
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestForwardSSEStreamEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  func() SSEParser
+		stream  string
+		content string
+		cutOff  bool
+	}{
+		{
+			name:    "anthropic complete",
+			parser:  func() SSEParser { return &AnthropicSSEParser{} },
+			stream:  "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\ndata: {\"type\":\"message_stop\"}",
+			content: "Hi",
+		},
+		{
+			name:    "anthropic without message_stop",
+			parser:  func() SSEParser { return &AnthropicSSEParser{} },
+			stream:  "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n",
+			content: "Hi",
+			cutOff:  true,
+		},
+		{
+			name:    "openai tools complete",
+			parser:  func() SSEParser { return &OpenAIToolStreamParser{} },
+			stream:  "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n",
+			content: "Hi",
+		},
+		{
+			name:   "openai tools without [DONE]",
+			parser: func() SSEParser { return &OpenAIToolStreamParser{} },
+			stream: "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"echo\",\"arguments\":\"{\\\"te\"}}]}}]}\n",
+			cutOff: true,
+		},
+		{
+			name:    "gemini complete",
+			parser:  func() SSEParser { return &GeminiSSEParser{} },
+			stream:  "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hi\"}]},\"finishReason\":\"STOP\"}]}\n\n",
+			content: "Hi",
+		},
+		{
+			name:    "gemini without finish reason",
+			parser:  func() SSEParser { return &GeminiSSEParser{} },
+			stream:  "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hi\"}]}}]}\n\n",
+			content: "Hi",
+			cutOff:  true,
+		},
+		{
+			name:   "empty stream",
+			parser: func() SSEParser { return &OpenAISSEParser{} },
+			cutOff: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan StreamChunk, 10)
+			err := NewStreamHandler(strings.NewReader(tt.stream)).ForwardSSEStream(ch, tt.parser())
+			close(ch)
+
+			var content strings.Builder
+			for chunk := range ch {
+				content.WriteString(chunk.Content)
+			}
+			if content.String() != tt.content {
+				t.Errorf("content = %q, want %q", content.String(), tt.content)
+			}
+
+			if tt.cutOff {
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("error = %v, want io.ErrUnexpectedEOF", err)
+				}
+			} else if err != nil {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
@@ -96,6 +96,11 @@ func FormatToolResult(result interface{}) string {
 		return "null"
 	}
 	
+	// Plain text (e.g. an already truncated excerpt) is passed through as-is
+	if text, ok := result.(string); ok {
+		return text
+	}
+	
 	// Try to convert to JSON for structured data
 	if jsonBytes, err := json.Marshal(result); err == nil {
 		return string(jsonBytes)
@@ -0,0 +1,254 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// UsageRecorder is told about the tokens of each LLM call made with a
+// context, including every turn of a tool loop
+type UsageRecorder func(usage TokenUsage)
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder returns a context that reports the token usage of the
+// calls made with it to record
+func WithUsageRecorder(ctx context.Context, record UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, record)
+}
+
+// recordUsage reports usage to the recorder of ctx, if there is one
+func recordUsage(ctx context.Context, usage *TokenUsage) {
+	if usage == nil {
+		return
+	}
+	if record, ok := ctx.Value(usageRecorderKey{}).(UsageRecorder); ok {
+		record(*usage)
+	}
+}
+
+// openAIUsage converts the usage of an OpenAI-style response. OpenAI counts
+// cached tokens as part of the prompt tokens.
+func openAIUsage(usage *OpenAIUsage) *TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	cached := 0
+	if usage.PromptTokensDetails != nil {
+		cached = usage.PromptTokensDetails.CachedTokens
+	}
+	return &TokenUsage{
+		InputTokens:     usage.PromptTokens - cached,
+		OutputTokens:    usage.CompletionTokens,
+		CacheReadTokens: cached,
+	}
+}
+
+// anthropicUsage converts the usage of an Anthropic response
+func anthropicUsage(usage *AnthropicUsage) *TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	return &TokenUsage{
+		InputTokens:      usage.InputTokens,
+		OutputTokens:     usage.OutputTokens,
+		CacheReadTokens:  usage.CacheReadInputTokens,
+		CacheWriteTokens: usage.CacheCreationInputTokens,
+	}
+}
+
+// geminiUsage converts the usage metadata of a Gemini response, whose prompt
+// token count includes the cached tokens
+func geminiUsage(usage *GeminiUsageMetadata) *TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	return &TokenUsage{
+		InputTokens:     usage.PromptTokenCount - usage.CachedContentTokenCount,
+		OutputTokens:    usage.CandidatesTokenCount,
+		CacheReadTokens: usage.CachedContentTokenCount,
+	}
+}
+
+// defaultPrices are the list prices in USD per million tokens of common
+// models. Keys match the model name or a prefix of it.
+var defaultPrices = map[string]types.ModelPrice{
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6, CacheRead: 0.075},
+	"gpt-4o":            {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"gpt-4-turbo":       {Input: 10, Output: 30},
+	"gpt-4":             {Input: 30, Output: 60},
+	"gpt-3.5-turbo":     {Input: 0.5, Output: 1.5},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-3-sonnet":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheRead: 0.03, CacheWrite: 0.3},
+	"gemini-1.5-pro":    {Input: 1.25, Output: 5, CacheRead: 0.3125},
+	"gemini-1.5-flash":  {Input: 0.075, Output: 0.3, CacheRead: 0.01875},
+	"gemini-pro":        {Input: 0.5, Output: 1.5},
+}
+
+// PriceTable looks up the price of models by name
+type PriceTable struct {
+	prices map[string]types.ModelPrice
+}
+
+// NewPriceTable returns the built-in prices with overrides applied on top.
+// Override keys are model names or prefixes, as in the built-in table.
+func NewPriceTable(overrides map[string]types.ModelPrice) *PriceTable {
+	prices := make(map[string]types.ModelPrice, len(defaultPrices)+len(overrides))
+	for model, price := range defaultPrices {
+		prices[model] = price
+	}
+	for model, price := range overrides {
+		prices[strings.ToLower(model)] = price
+	}
+	return &PriceTable{prices: prices}
+}
+
+// Lookup returns the price of model: the price of the exact name, or else
+// that of the longest prefix of it in the table that is followed by a "-",
+// as in dated versions like gpt-4o-2024-08-06. Other models of a family,
+// such as gpt-4.1 for gpt-4, do not match.
+func (t *PriceTable) Lookup(model string) (types.ModelPrice, bool) {
+	model = strings.ToLower(model)
+	if price, ok := t.prices[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for prefix := range t.prices {
+		if strings.HasPrefix(model, prefix+"-") && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return types.ModelPrice{}, false
+	}
+	return t.prices[best], true
+}
+
+// Entry turns the usage of a call to provider into a ledger entry with its
+// cost. Local providers are free; calls of models without a price have no
+// cost and are marked as unpriced.
+func (t *PriceTable) Entry(provider *types.LLMProvider, usage TokenUsage) types.UsageEntry {
+	entry := types.UsageEntry{
+		ProviderID:       provider.ID,
+		ProviderName:     provider.Name,
+		Model:            provider.Model,
+		InputTokens:      usage.InputTokens,
+		OutputTokens:     usage.OutputTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheWriteTokens,
+	}
+
+	if provider.Type == ProviderLocal {
+		entry.Priced = true
+		return entry
+	}
+
+	price, ok := t.Lookup(provider.Model)
+	if !ok {
+		return entry
+	}
+
+	cacheRead, cacheWrite := price.CacheRead, price.CacheWrite
+	if cacheRead == 0 {
+		cacheRead = price.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = price.Input
+	}
+
+	entry.Cost = (float64(usage.InputTokens)*price.Input +
+		float64(usage.OutputTokens)*price.Output +
+		float64(usage.CacheReadTokens)*cacheRead +
+		float64(usage.CacheWriteTokens)*cacheWrite) / 1e6
+	entry.Priced = true
+	return entry
+}
+
+// UsageReport aggregates the recorded LLM calls of one provider and model
+type UsageReport struct {
+	ProviderID   string `json:"provider_id"`
+	ProviderName string `json:"provider_name"`
+	Model        string `json:"model"`
+	types.UsageTotals
+}
+
+// UsageFilter selects the calls that are aggregated
+type UsageFilter struct {
+	Provider string    // provider ID or name, empty for all providers
+	Since    time.Time // zero for all recorded calls
+}
+
+// RecordUsage adds an LLM call to the usage ledger
+func (m *Manager) RecordUsage(entry types.UsageEntry) {
+	if err := m.storage.AppendUsage(entry); err != nil {
+		fmt.Printf("Warning: failed to record token usage: %v\n", err)
+	}
+}
+
+// UsageReport returns the tokens and cost of the recorded LLM calls per
+// provider and model, sorted by provider name and model
+func (m *Manager) UsageReport(filter UsageFilter) ([]UsageReport, error) {
+	providerID, providerName := "", ""
+	if filter.Provider != "" {
+		// Providers that were removed since can still be reported by name
+		if provider, err := m.FindProvider(filter.Provider); err == nil {
+			providerID = provider.ID
+		} else {
+			providerName = filter.Provider
+		}
+	}
+
+	entries, err := m.storage.LoadUsage(filter.Since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage: %w", err)
+	}
+
+	type key struct{ provider, model string }
+	reports := make(map[key]*UsageReport)
+	lastSeen := make(map[key]time.Time)
+
+	for _, entry := range entries {
+		if providerID != "" && entry.ProviderID != providerID {
+			continue
+		}
+		if providerName != "" && entry.ProviderName != providerName && entry.ProviderID != providerName {
+			continue
+		}
+
+		k := key{entry.ProviderID, entry.Model}
+		report, exists := reports[k]
+		if !exists {
+			report = &UsageReport{ProviderID: entry.ProviderID, Model: entry.Model}
+			reports[k] = report
+		}
+		if !entry.Time.Before(lastSeen[k]) {
+			// Providers may have been renamed since
+			report.ProviderName = entry.ProviderName
+			lastSeen[k] = entry.Time
+		}
+		report.Add(entry)
+	}
+
+	result := make([]UsageReport, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, *report)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ProviderName != result[j].ProviderName {
+			return result[i].ProviderName < result[j].ProviderName
+		}
+		return result[i].Model < result[j].Model
+	})
+
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+func TestPriceTableLookup(t *testing.T) {
+	table := NewPriceTable(map[string]types.ModelPrice{
+		"GPT-4o":     {Input: 2, Output: 8},
+		"my-model":   {Input: 1, Output: 2},
+		"my-model-x": {Input: 3, Output: 4},
+	})
+
+	tests := []struct {
+		model string
+		input float64
+		found bool
+	}{
+		{"gpt-4", 30, true},
+		{"gpt-4-0613", 30, true},
+		{"GPT-4-Turbo-2024-04-09", 10, true},
+		{"gpt-4o", 2, true}, // overridden, case-insensitively
+		{"gpt-4o-2024-08-06", 2, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, true},
+		{"gpt-4.1", 0, false},
+		{"gpt-4.5-preview", 0, false},
+		{"gpt-4o1", 0, false},
+		{"claude-3-5-sonnet-20241022", 3, true},
+		{"claude-3-5-sonnet-latest", 3, true},
+		{"my-model-x-2", 3, true}, // the longest entry wins
+		{"my-model-y", 1, true},
+		{"o3-mini", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		price, found := table.Lookup(tt.model)
+		if found != tt.found || price.Input != tt.input {
+			t.Errorf("Lookup(%q) = %+v, %v, want input %v, %v", tt.model, price, found, tt.input, tt.found)
+		}
+	}
+}
+
+func TestPriceTableEntry(t *testing.T) {
+	table := NewPriceTable(map[string]types.ModelPrice{
+		"cached-model": {Input: 2, Output: 10, CacheRead: 0.5, CacheWrite: 4},
+		"plain-model":  {Input: 2, Output: 10},
+	})
+	usage := TokenUsage{InputTokens: 1000, OutputTokens: 500, CacheReadTokens: 2000, CacheWriteTokens: 100}
+
+	tests := []struct {
+		name     string
+		provider types.LLMProvider
+		cost     float64
+		priced   bool
+	}{
+		{"cache prices", types.LLMProvider{Type: ProviderOpenAI, Model: "cached-model"}, (1000*2 + 500*10 + 2000*0.5 + 100*4) / 1e6, true},
+		{"cache at the input price", types.LLMProvider{Type: ProviderOpenAI, Model: "plain-model"}, (1000*2 + 500*10 + 2000*2 + 100*2) / 1e6, true},
+		{"local is free", types.LLMProvider{Type: ProviderLocal, Model: "llama3"}, 0, true},
+		{"unknown model", types.LLMProvider{Type: ProviderAnthropic, Model: "claude-next"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.provider.ID, tt.provider.Name = "provider-id", "provider"
+			entry := table.Entry(&tt.provider, usage)
+
+			if math.Abs(entry.Cost-tt.cost) > 1e-12 || entry.Priced != tt.priced {
+				t.Errorf("cost = %v, priced = %v, want %v, %v", entry.Cost, entry.Priced, tt.cost, tt.priced)
+			}
+			if entry.ProviderID != "provider-id" || entry.ProviderName != "provider" || entry.Model != tt.provider.Model {
+				t.Errorf("entry = %+v", entry)
+			}
+			if entry.InputTokens != 1000 || entry.OutputTokens != 500 || entry.CacheReadTokens != 2000 || entry.CacheWriteTokens != 100 {
+				t.Errorf("tokens = %+v", entry)
+			}
+		})
+	}
+}
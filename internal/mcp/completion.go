@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// ParseCompletionRef parses the reference forms accepted on the command line:
+// "prompt:<name>", "resource:<uri-template>" and "tool:<name>"
+func ParseCompletionRef(ref string) (CompletionRef, error) {
+	kind, value, found := strings.Cut(ref, ":")
+	if !found || value == "" {
+		return CompletionRef{}, fmt.Errorf("invalid reference %q (expected prompt:<name>, resource:<uri-template> or tool:<name>)", ref)
+	}
+
+	switch strings.ToLower(kind) {
+	case "prompt":
+		return CompletionRef{Type: RefPrompt, Name: value}, nil
+	case "resource":
+		return CompletionRef{Type: RefResource, URI: value}, nil
+	case "tool":
+		return CompletionRef{Type: RefTool, Name: value}, nil
+	default:
+		return CompletionRef{}, fmt.Errorf("unknown reference type %q (expected prompt, resource or tool)", kind)
+	}
+}
+
+// ResolveServer finds a server by ID or, failing that, by name
+func (m *Manager) ResolveServer(idOrName string) (*types.MCPServer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if server, exists := m.servers[idOrName]; exists {
+		return server, nil
+	}
+
+	for _, server := range m.servers {
+		if strings.EqualFold(server.Name, idOrName) {
+			return server, nil
+		}
+	}
+
+	return nil, fmt.Errorf("MCP server %s not found", idOrName)
+}
+
+// Complete returns suggested values for an argument. Prompt and resource
+// template arguments are completed by the server; tool arguments are
+// completed locally from the enum and default values in the tool's schema,
+// since MCP has no completion reference for tools.
+func (m *Manager) Complete(serverID string, ref CompletionRef, argument, value string, context map[string]string) (*CompletionResult, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Type == RefTool {
+		return m.completeToolArgument(serverID, ref.Name, argument, value)
+	}
+
+	if !server.HasCapability("completions") {
+		return nil, fmt.Errorf("server %s does not support completions", server.Name)
+	}
+
+	process, release, err := m.acquireProcess(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return process.Complete(ref, argument, value, context)
+}
+
+// completeToolArgument suggests schema values for a tool argument that
+// start with the given prefix
+func (m *Manager) completeToolArgument(serverID, toolName, argument, prefix string) (*CompletionResult, error) {
+	tools, err := m.GetServerTools(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tool := range tools {
+		if tool.Name != toolName {
+			continue
+		}
+
+		property, _ := schemaProperties(tool.Schema)[argument].(map[string]interface{})
+		if property == nil {
+			return nil, fmt.Errorf("tool %s has no argument %s", toolName, argument)
+		}
+
+		seen := make(map[string]bool)
+		var values []string
+		candidates, _ := property["enum"].([]interface{})
+		if def, exists := property["default"]; exists {
+			candidates = append(candidates, def)
+		}
+		if examples, ok := property["examples"].([]interface{}); ok {
+			candidates = append(candidates, examples...)
+		}
+		for _, candidate := range candidates {
+			text := fmt.Sprintf("%v", candidate)
+			if seen[text] || !strings.HasPrefix(text, prefix) {
+				continue
+			}
+			seen[text] = true
+			values = append(values, text)
+		}
+
+		return &CompletionResult{Values: values, Total: len(values)}, nil
+	}
+
+	return nil, fmt.Errorf("tool %s not found", toolName)
+}
+
+// GetServerPrompt renders one of the server's prompt templates
+func (m *Manager) GetServerPrompt(serverID, name string, arguments map[string]string) ([]PromptMessage, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !server.HasCapability("prompts") {
+		return nil, fmt.Errorf("server %s does not support prompts", server.Name)
+	}
+
+	process, release, err := m.acquireProcess(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return process.GetPrompt(name, arguments)
+}
+
+// ReadServerResource reads a resource from the server
+func (m *Manager) ReadServerResource(serverID, uri string) ([]ResourceContents, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !server.HasCapability("resources") {
+		return nil, fmt.Errorf("server %s does not support resources", server.Name)
+	}
+
+	process, release, err := m.acquireProcess(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return process.ReadResource(uri)
+}
+
+// ToolArgumentNames returns the sorted argument names of a tool's schema
+func ToolArgumentNames(tool Tool) []string {
+	var names []string
+	for name := range schemaProperties(tool.Schema) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaProperties returns the properties of a JSON schema object
+func schemaProperties(schema map[string]interface{}) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	return properties
+}
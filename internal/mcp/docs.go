@@ -0,0 +1,343 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// ServerDoc documents the tools of one server
+type ServerDoc struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Transport    string    `json:"transport"`
+	Enabled      bool      `json:"enabled"`
+	Instructions string    `json:"instructions,omitempty"`
+	Tools        []ToolDoc `json:"tools"`
+}
+
+// ToolDoc documents a tool: its parameters and an example call
+type ToolDoc struct {
+	Name        string                 `json:"name"`
+	ExposedName string                 `json:"exposed_name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  []ParameterDoc         `json:"parameters"`
+	Example     map[string]interface{} `json:"example"`
+}
+
+// ParameterDoc describes one input parameter. Nested object properties are
+// flattened with dotted names, array items with a [] suffix.
+type ParameterDoc struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Required    bool          `json:"required"`
+	Description string        `json:"description,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+}
+
+// ToolDocs documents the discovered tools of all servers, or of the server
+// given by ID or name, sorted by server and tool name
+func (m *Manager) ToolDocs(serverFilter string) ([]ServerDoc, error) {
+	var servers []*types.MCPServer
+	if serverFilter != "" {
+		server, err := m.ResolveServer(serverFilter)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	} else {
+		servers = m.ListServers()
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	var docs []ServerDoc
+	for _, server := range servers {
+		if len(server.Tools) == 0 {
+			continue
+		}
+
+		doc := ServerDoc{
+			ID:           server.ID,
+			Name:         server.Name,
+			Transport:    server.Transport,
+			Enabled:      server.IsEnabled(),
+			Instructions: server.Instructions,
+		}
+		for _, tool := range server.Tools {
+			doc.Tools = append(doc.Tools, documentTool(server.Name, tool))
+		}
+		sort.Slice(doc.Tools, func(i, j int) bool {
+			return doc.Tools[i].Name < doc.Tools[j].Name
+		})
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// documentTool describes a tool from its input schema
+func documentTool(serverName string, tool types.Tool) ToolDoc {
+	return ToolDoc{
+		Name:        tool.Name,
+		ExposedName: ExposedToolName(serverName, tool.Name),
+		Description: tool.Description,
+		Parameters:  schemaParameters("", tool.Schema),
+		Example:     exampleObject(tool.Schema),
+	}
+}
+
+// schemaParameters flattens the properties of an object schema
+func schemaParameters(prefix string, schema map[string]interface{}) []ParameterDoc {
+	properties := schemaProperties(schema)
+	required := requiredProperties(schema)
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	// Required parameters first, then alphabetical
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	parameters := []ParameterDoc{}
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		parameter := ParameterDoc{
+			Name:     prefix + name,
+			Type:     schemaType(property),
+			Required: required[name],
+		}
+		if property != nil {
+			parameter.Description, _ = property["description"].(string)
+			parameter.Enum, _ = property["enum"].([]interface{})
+			parameter.Default = property["default"]
+		}
+		if parameter.Type == "array" && itemsType(property) != "" {
+			parameter.Type = "array of " + itemsType(property)
+		}
+		parameters = append(parameters, parameter)
+
+		// Describe the fields of nested objects, including those in arrays
+		switch parameter.Type {
+		case "object":
+			parameters = append(parameters, schemaParameters(parameter.Name+".", property)...)
+		case "array of object":
+			items, _ := property["items"].(map[string]interface{})
+			parameters = append(parameters, schemaParameters(parameter.Name+"[].", items)...)
+		}
+	}
+
+	return parameters
+}
+
+// requiredProperties returns the set of required property names
+func requiredProperties(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	entries, _ := schema["required"].([]interface{})
+	for _, entry := range entries {
+		if name, ok := entry.(string); ok {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+// schemaType returns the type of a property schema; unions such as
+// ["string", "null"] are joined with |, ignoring null
+func schemaType(schema map[string]interface{}) string {
+	if schema == nil {
+		return "any"
+	}
+
+	switch value := schema["type"].(type) {
+	case string:
+		return value
+	case []interface{}:
+		// e.g. ["string", "null"]
+		var names []string
+		for _, entry := range value {
+			if name, ok := entry.(string); ok && name != "null" {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			return strings.Join(names, "|")
+		}
+	}
+
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["enum"]; ok {
+		return "enum"
+	}
+	return "any"
+}
+
+// itemsType describes the element type of an array schema
+func itemsType(schema map[string]interface{}) string {
+	items, _ := schema["items"].(map[string]interface{})
+	if items == nil {
+		return ""
+	}
+	return schemaType(items)
+}
+
+// exampleObject builds example arguments for an object schema from the
+// examples, defaults and enums in it, falling back to placeholders. Only
+// required properties are included unless there are none.
+func exampleObject(schema map[string]interface{}) map[string]interface{} {
+	properties := schemaProperties(schema)
+	required := requiredProperties(schema)
+
+	example := make(map[string]interface{})
+	for name, raw := range properties {
+		if len(required) > 0 && !required[name] {
+			continue
+		}
+		property, _ := raw.(map[string]interface{})
+		example[name] = exampleValue(name, property)
+	}
+	return example
+}
+
+// exampleValue returns an example value for a property
+func exampleValue(name string, property map[string]interface{}) interface{} {
+	if property == nil {
+		return "<" + name + ">"
+	}
+
+	if examples, ok := property["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if def, exists := property["default"]; exists {
+		return def
+	}
+	if enum, ok := property["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	switch strings.Split(schemaType(property), "|")[0] {
+	case "string":
+		return "<" + name + ">"
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	case "array":
+		items, _ := property["items"].(map[string]interface{})
+		return []interface{}{exampleValue(name, items)}
+	case "object":
+		return exampleObject(property)
+	default:
+		return "<" + name + ">"
+	}
+}
+
+// RenderToolDocsMarkdown renders tool documentation as Markdown
+func RenderToolDocsMarkdown(docs []ServerDoc) string {
+	var b strings.Builder
+
+	b.WriteString("# MCP Tools\n\n")
+	if len(docs) == 0 {
+		b.WriteString("No tools have been discovered.\n")
+		return b.String()
+	}
+
+	for _, server := range docs {
+		fmt.Fprintf(&b, "## %s\n\n", server.Name)
+		fmt.Fprintf(&b, "Transport: `%s`", server.Transport)
+		if !server.Enabled {
+			b.WriteString(" (disabled)")
+		}
+		fmt.Fprintf(&b, " · %d tools\n\n", len(server.Tools))
+		if server.Instructions != "" {
+			fmt.Fprintf(&b, "%s\n\n", server.Instructions)
+		}
+
+		for _, tool := range server.Tools {
+			fmt.Fprintf(&b, "### %s\n\n", tool.Name)
+			fmt.Fprintf(&b, "Exposed to the model as `%s`.\n\n", tool.ExposedName)
+			if tool.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", tool.Description)
+			}
+
+			if len(tool.Parameters) == 0 {
+				b.WriteString("Takes no parameters.\n\n")
+			} else {
+				b.WriteString("| Parameter | Type | Required | Description |\n")
+				b.WriteString("|-----------|------|----------|-------------|\n")
+				for _, parameter := range tool.Parameters {
+					required := "no"
+					if parameter.Required {
+						required = "yes"
+					}
+					fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n",
+						parameter.Name, parameter.Type, required, markdownCell(parameterDetails(parameter)))
+				}
+				b.WriteString("\n")
+			}
+
+			if example, err := marshalExample(tool.Example); err == nil {
+				fmt.Fprintf(&b, "Example arguments:\n\n```json\n%s\n```\n\n", example)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// marshalExample formats example arguments without escaping the <name>
+// placeholders
+func marshalExample(example map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(example); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// parameterDetails combines the description, allowed values and default of
+// a parameter
+func parameterDetails(parameter ParameterDoc) string {
+	var parts []string
+	if parameter.Description != "" {
+		parts = append(parts, parameter.Description)
+	}
+	if len(parameter.Enum) > 0 {
+		var values []string
+		for _, value := range parameter.Enum {
+			values = append(values, fmt.Sprintf("`%v`", value))
+		}
+		parts = append(parts, "One of: "+strings.Join(values, ", ")+".")
+	}
+	if parameter.Default != nil {
+		if data, err := json.Marshal(parameter.Default); err == nil {
+			parts = append(parts, fmt.Sprintf("Default: `%s`.", data))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// markdownCell makes text safe to put in a table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.Join(strings.Fields(text), " ")
+}
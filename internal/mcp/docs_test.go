@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema map[string]interface{}
+		want   []ParameterDoc
+	}{
+		{
+			name:   "no properties",
+			schema: map[string]interface{}{"type": "object"},
+			want:   []ParameterDoc{},
+		},
+		{
+			name: "required first, then alphabetical",
+			schema: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"verbose": map[string]interface{}{"type": "boolean", "default": false},
+					"path":    map[string]interface{}{"type": "string", "description": "File to read"},
+					"mode":    map[string]interface{}{"type": "string", "enum": []interface{}{"text", "binary"}},
+				},
+				"required": []interface{}{"path"},
+			},
+			want: []ParameterDoc{
+				{Name: "path", Type: "string", Required: true, Description: "File to read"},
+				{Name: "mode", Type: "string", Enum: []interface{}{"text", "binary"}},
+				{Name: "verbose", Type: "boolean", Default: false},
+			},
+		},
+		{
+			name: "unions ignore null",
+			schema: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"limit": map[string]interface{}{"type": []interface{}{"integer", "null"}},
+				},
+			},
+			want: []ParameterDoc{{Name: "limit", Type: "integer"}},
+		},
+		{
+			name: "nested objects and arrays of objects",
+			schema: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"options": map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"depth": map[string]interface{}{"type": "integer"}},
+					},
+					"files": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type":       "object",
+							"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+							"required":   []interface{}{"name"},
+						},
+					},
+					"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				},
+			},
+			want: []ParameterDoc{
+				{Name: "files", Type: "array of object"},
+				{Name: "files[].name", Type: "string", Required: true},
+				{Name: "options", Type: "object"},
+				{Name: "options.depth", Type: "integer"},
+				{Name: "tags", Type: "array of string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schemaParameters("", tt.schema); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("schemaParameters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		property map[string]interface{}
+		want     interface{}
+	}{
+		{"no schema", nil, "<path>"},
+		{"examples first", map[string]interface{}{"type": "string", "examples": []interface{}{"/etc/hosts"}, "default": "/tmp"}, "/etc/hosts"},
+		{"default", map[string]interface{}{"type": "string", "default": "/tmp"}, "/tmp"},
+		{"enum", map[string]interface{}{"enum": []interface{}{"text", "binary"}}, "text"},
+		{"string", map[string]interface{}{"type": "string"}, "<path>"},
+		{"integer", map[string]interface{}{"type": "integer"}, 1},
+		{"number", map[string]interface{}{"type": "number"}, 1.5},
+		{"boolean", map[string]interface{}{"type": "boolean"}, true},
+		{"nullable", map[string]interface{}{"type": []interface{}{"null", "integer"}}, 1},
+		{"array", map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}}, []interface{}{1}},
+		{
+			"object with required properties only",
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":  map[string]interface{}{"type": "string"},
+					"force": map[string]interface{}{"type": "boolean"},
+				},
+				"required": []interface{}{"name"},
+			},
+			map[string]interface{}{"name": "<name>"},
+		},
+		{"unknown type", map[string]interface{}{"description": "anything"}, "<path>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exampleValue("path", tt.property); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exampleValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderToolDocsMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		docs     []ServerDoc
+		contains []string
+	}{
+		{
+			name:     "no servers",
+			docs:     nil,
+			contains: []string{"# MCP Tools\n\nNo tools have been discovered.\n"},
+		},
+		{
+			name: "tool with parameters",
+			docs: []ServerDoc{{
+				Name:         "files",
+				Transport:    "stdio",
+				Enabled:      false,
+				Instructions: "Read-only access.",
+				Tools: []ToolDoc{{
+					Name:        "read_file",
+					ExposedName: "files_read_file",
+					Description: "Reads a file",
+					Parameters: []ParameterDoc{
+						{Name: "path", Type: "string", Required: true, Description: "A | separated\nlist"},
+						{Name: "mode", Type: "string", Enum: []interface{}{"text", "binary"}, Default: "text"},
+					},
+					Example: map[string]interface{}{"path": "<path>"},
+				}},
+			}},
+			contains: []string{
+				"## files\n\nTransport: `stdio` (disabled) · 1 tools\n\nRead-only access.\n\n",
+				"### read_file\n\nExposed to the model as `files_read_file`.\n\nReads a file\n\n",
+				"| `path` | string | yes | A \\| separated list |\n",
+				"| `mode` | string | no | One of: `text`, `binary`. Default: `\"text\"`. |\n",
+				"```json\n{\n  \"path\": \"<path>\"\n}\n```",
+			},
+		},
+		{
+			name: "tool without parameters",
+			docs: []ServerDoc{{
+				Name:      "clock",
+				Transport: "http",
+				Enabled:   true,
+				Tools:     []ToolDoc{{Name: "now", ExposedName: "clock_now", Example: map[string]interface{}{}}},
+			}},
+			contains: []string{"Transport: `http` · 1 tools\n\n", "Takes no parameters.\n\n", "```json\n{}\n```"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := RenderToolDocsMarkdown(tt.docs)
+			for _, want := range tt.contains {
+				if !strings.Contains(markdown, want) {
+					t.Errorf("markdown does not contain %q:\n%s", want, markdown)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// Doctor step results
+const (
+	StepOK      = "ok"
+	StepWarning = "warn"
+	StepFailed  = "fail"
+	StepSkipped = "skip"
+)
+
+// maxToolNameLength is the longest function name accepted by the OpenAI and
+// Anthropic APIs. Tools are exposed to the model as <server>_<tool>.
+const maxToolNameLength = 64
+
+var providerToolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// DoctorStep is the outcome of one phase of the handshake
+type DoctorStep struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Detail   string        `json:"detail,omitempty"`
+	Fix      string        `json:"fix,omitempty"`
+}
+
+// DoctorReport collects the steps run against one server
+type DoctorReport struct {
+	Server *types.MCPServer `json:"server"`
+	Steps  []DoctorStep     `json:"steps"`
+	Stderr string           `json:"stderr,omitempty"`
+}
+
+// FailedStep returns the step that stopped the diagnosis, or nil
+func (r *DoctorReport) FailedStep() *DoctorStep {
+	for i := range r.Steps {
+		if r.Steps[i].Status == StepFailed {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Doctor runs the connection handshake with a server step by step in a
+// fresh process and reports where and why it fails
+func (m *Manager) Doctor(serverID string) (*DoctorReport, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	process := NewMCPProcess(server)
+	if process.transportErr != nil {
+		return nil, process.transportErr
+	}
+
+	// The server's stderr is part of the report instead of being echoed
+	stdio, isStdio := process.transport.(*stdioTransport)
+	if isStdio {
+		stdio.echoStderr = false
+	}
+
+	report := &DoctorReport{Server: server}
+	connected := false
+	defer func() {
+		process.Stop()
+		if isStdio {
+			if connected {
+				// Give the reader a moment to collect what the server wrote last
+				select {
+				case <-stdio.stderrDone:
+				case <-time.After(time.Second):
+				}
+			}
+			report.Stderr = stdio.Stderr()
+		}
+	}()
+
+	run := func(name string, step func() (string, error), fix func(error) string) bool {
+		start := time.Now()
+		detail, err := step()
+		result := DoctorStep{Name: name, Status: StepOK, Duration: time.Since(start), Detail: detail}
+		if err != nil {
+			result.Status = StepFailed
+			result.Detail = err.Error()
+			result.Fix = fix(err)
+		}
+		report.Steps = append(report.Steps, result)
+		return err == nil
+	}
+
+	connectStep := "connect"
+	if isStdio {
+		connectStep = "spawn"
+	}
+	if !run(connectStep, func() (string, error) {
+		if err := process.connect(); err != nil {
+			return "", err
+		}
+		connected = true
+		return process.transport.describe(), nil
+	}, connectFix) {
+		return report, nil
+	}
+
+	var result *InitializeResult
+	if !run("initialize", func() (string, error) {
+		var err error
+		result, err = process.requestInitialize()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("server %s %s", result.ServerInfo.Name, result.ServerInfo.Version), nil
+	}, process.initializeFix) {
+		return report, nil
+	}
+
+	if !run("protocol version", func() (string, error) {
+		if err := checkProtocolVersion(result.ProtocolVersion); err != nil {
+			return "", err
+		}
+		return result.ProtocolVersion, process.completeInitialize(result)
+	}, func(error) string {
+		return "Upgrade the server to an MCP SDK that supports one of " + strings.Join(SupportedProtocolVersions, ", ")
+	}) {
+		return report, nil
+	}
+
+	report.Steps = append(report.Steps, capabilitiesStep(result))
+	if !result.HasCapability("tools") && len(result.Capabilities) > 0 {
+		report.Steps = append(report.Steps,
+			DoctorStep{Name: "tools/list", Status: StepSkipped, Detail: "server does not advertise tools"},
+			DoctorStep{Name: "schema sanity", Status: StepSkipped})
+		return report, nil
+	}
+
+	if !run("tools/list", func() (string, error) {
+		if err := process.discoverTools(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d tools", len(process.GetTools())), nil
+	}, process.initializeFix) {
+		return report, nil
+	}
+
+	if len(process.GetTools()) == 0 {
+		report.Steps[len(report.Steps)-1].Status = StepWarning
+		report.Steps[len(report.Steps)-1].Fix = "The server returned no tools; check its configuration or arguments"
+	}
+
+	report.Steps = append(report.Steps, schemaStep(server.Name, process.GetTools()))
+
+	return report, nil
+}
+
+// capabilitiesStep summarizes the capabilities the server advertised
+func capabilitiesStep(result *InitializeResult) DoctorStep {
+	var names []string
+	for name := range result.Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	step := DoctorStep{Name: "capabilities", Status: StepOK, Detail: strings.Join(names, ", ")}
+	if len(names) == 0 {
+		step.Status = StepWarning
+		step.Detail = "none advertised"
+		step.Fix = "The server predates capability negotiation; tools are still requested from it"
+	}
+	return step
+}
+
+// schemaStep checks that every tool can be passed to the LLM providers
+func schemaStep(serverName string, tools []Tool) DoctorStep {
+	start := time.Now()
+	var problems []string
+	seen := make(map[string]bool)
+
+	for _, tool := range tools {
+		if seen[tool.Name] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate tool name", tool.Name))
+		}
+		seen[tool.Name] = true
+		problems = append(problems, toolSchemaProblems(serverName, tool)...)
+	}
+	sort.Strings(problems)
+
+	step := DoctorStep{Name: "schema sanity", Status: StepOK, Duration: time.Since(start),
+		Detail: fmt.Sprintf("%d tools checked", len(tools))}
+	if len(problems) > 0 {
+		step.Status = StepWarning
+		step.Detail = strings.Join(problems, "; ")
+		step.Fix = "Rename the affected tools or fix their inputSchema; providers reject invalid tool definitions"
+	}
+	return step
+}
+
+// toolSchemaProblems lists what providers would reject about a tool
+func toolSchemaProblems(serverName string, tool Tool) []string {
+	var problems []string
+
+	exposedName := ExposedToolName(serverName, tool.Name)
+
+	if !providerToolNamePattern.MatchString(exposedName) {
+		problems = append(problems, fmt.Sprintf("%s: exposed name %s may only contain letters, digits, _ and -", tool.Name, exposedName))
+	}
+	if len(exposedName) > maxToolNameLength {
+		problems = append(problems, fmt.Sprintf("%s: exposed name %s is longer than %d characters", tool.Name, exposedName, maxToolNameLength))
+	}
+	if tool.Description == "" {
+		problems = append(problems, fmt.Sprintf("%s: missing description", tool.Name))
+	}
+
+	if tool.Schema == nil {
+		return append(problems, fmt.Sprintf("%s: missing inputSchema", tool.Name))
+	}
+	if schemaType, _ := tool.Schema["type"].(string); schemaType != "object" {
+		problems = append(problems, fmt.Sprintf("%s: inputSchema type must be \"object\"", tool.Name))
+	}
+
+	properties := map[string]interface{}{}
+	if raw, exists := tool.Schema["properties"]; exists {
+		var ok bool
+		if properties, ok = raw.(map[string]interface{}); !ok {
+			problems = append(problems, fmt.Sprintf("%s: properties must be an object", tool.Name))
+		}
+	}
+	for name, property := range properties {
+		if _, ok := property.(map[string]interface{}); !ok {
+			problems = append(problems, fmt.Sprintf("%s: property %s must be a schema object", tool.Name, name))
+		}
+	}
+
+	if raw, exists := tool.Schema["required"]; exists {
+		required, ok := raw.([]interface{})
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: required must be an array", tool.Name))
+		}
+		for _, entry := range required {
+			name, ok := entry.(string)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: required entries must be strings", tool.Name))
+				continue
+			}
+			if _, exists := properties[name]; !exists {
+				problems = append(problems, fmt.Sprintf("%s: required property %s is not defined", tool.Name, name))
+			}
+		}
+	}
+
+	return problems
+}
+
+// connectFix suggests how to fix a server that could not be started or
+// connected to
+func connectFix(err error) string {
+	if fix := authorizationFix(err); fix != "" {
+		return fix
+	}
+
+	switch {
+	case strings.Contains(err.Error(), "dial"), strings.Contains(err.Error(), "handshake"):
+		return "The server could not be reached; check the URL and that the server is running"
+	case errors.Is(err, exec.ErrNotFound):
+		return "The command was not found; install it or use an absolute path in the server URL"
+	case strings.Contains(err.Error(), "permission denied"):
+		return "The command is not executable by this user (or the sandbox uid); check its permissions"
+	case strings.Contains(err.Error(), "sandbox"):
+		return "Fix the sandbox settings (working directory, uid/gid, limits) and add the server again"
+	default:
+		return "Check that the server URL is a valid command line"
+	}
+}
+
+// initializeFix suggests how to fix a server that does not answer requests
+func (p *MCPProcess) initializeFix(err error) string {
+	p.mu.RLock()
+	nonJSONLines := p.nonJSONLines
+	p.mu.RUnlock()
+
+	if fix := authorizationFix(err); fix != "" {
+		return fix
+	}
+
+	switch {
+	case strings.Contains(err.Error(), "environment variable"):
+		return "Export the environment variable the server's headers or token refer to before starting the agent"
+	case strings.Contains(err.Error(), "401 Unauthorized"), strings.Contains(err.Error(), "403 Forbidden"):
+		return "The server rejected the credentials; check the configured headers and token and the environment variables they read"
+	case nonJSONLines > 0:
+		return fmt.Sprintf("The server wrote non JSON-RPC output to stdout (%d lines); it must log to stderr only", nonJSONLines)
+	case strings.Contains(err.Error(), "closed its output"):
+		return "The server closed the connection during the handshake; see its stderr or logs"
+	case strings.Contains(err.Error(), "timeout") && p.server.Transport == "stdio":
+		return "The server did not answer within 10s; make sure it speaks MCP over stdio rather than HTTP/SSE"
+	case strings.Contains(err.Error(), "timeout"):
+		return "The server did not answer within 10s; check that the URL is its MCP endpoint and the transport matches"
+	default:
+		return "The server rejected the request; see the error and its stderr below"
+	}
+}
+
+// authorizationFix suggests logging in when the server rejected the request
+// for lack of a valid token
+func authorizationFix(err error) string {
+	var authErr *AuthorizationRequiredError
+	if !errors.As(err, &authErr) {
+		return ""
+	}
+	return fmt.Sprintf("The server requires OAuth authorization; run 'syseng-agent mcp login %s'", authErr.ServerID)
+}
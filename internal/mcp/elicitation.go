@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// ElicitationHandler asks the user for the input an MCP server requested
+type ElicitationHandler func(request types.ElicitationRequest) (types.ElicitationResponse, error)
+
+// serverRequestResponse answers a request the server sent to the client. The
+// ID is echoed as sent, servers may use strings.
+type serverRequestResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+// CallToolWithElicitation calls a tool and routes the server's elicitation
+// requests to handler while it runs. Elicitation requests do not name the
+// call they belong to, so calls with a handler take turns on a shared
+// connection. Without a handler it is CallTool.
+func (p *MCPProcess) CallToolWithElicitation(name string, arguments map[string]interface{}, handler ElicitationHandler) (interface{}, error) {
+	if handler == nil {
+		return p.CallTool(name, arguments)
+	}
+
+	p.elicitationCalls.Lock()
+	defer p.elicitationCalls.Unlock()
+
+	p.mu.Lock()
+	p.elicitationHandler = handler
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.elicitationHandler = nil
+		p.mu.Unlock()
+	}()
+
+	return p.CallTool(name, arguments)
+}
+
+// awaitingInput reports whether the user is filling in an elicitation form,
+// during which requests do not time out
+func (p *MCPProcess) awaitingInput() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pendingElicitations > 0
+}
+
+// handleServerRequest answers a request the server sent to the client
+func (p *MCPProcess) handleServerRequest(id json.RawMessage, method string, params json.RawMessage) {
+	var result interface{}
+	var rpcErr *MCPError
+
+	switch method {
+	case "elicitation/create":
+		result, rpcErr = p.elicit(params)
+	case "ping":
+		result = map[string]interface{}{}
+	default:
+		rpcErr = &MCPError{Code: -32601, Message: "method not found: " + method}
+	}
+
+	data, err := json.Marshal(serverRequestResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+	if err != nil {
+		debugPrint("Failed to marshal response to %s from %s: %v\n", method, p.server.Name, err)
+		return
+	}
+	if err := p.transport.send(data); err != nil {
+		debugPrint("Failed to answer %s from %s: %v\n", method, p.server.Name, err)
+	}
+}
+
+// elicit asks the user for the input described by an elicitation/create
+// request
+func (p *MCPProcess) elicit(params json.RawMessage) (interface{}, *MCPError) {
+	var request struct {
+		Message         string                 `json:"message"`
+		RequestedSchema map[string]interface{} `json:"requestedSchema"`
+	}
+	if err := json.Unmarshal(params, &request); err != nil {
+		return nil, &MCPError{Code: -32602, Message: "invalid elicitation request: " + err.Error()}
+	}
+	if err := checkElicitationSchema(request.RequestedSchema); err != nil {
+		return nil, &MCPError{Code: -32602, Message: err.Error()}
+	}
+
+	p.mu.Lock()
+	handler := p.elicitationHandler
+	if handler != nil {
+		p.pendingElicitations++
+	}
+	p.mu.Unlock()
+
+	if handler == nil {
+		debugPrint("Declining elicitation from %s: nobody to ask\n", p.server.Name)
+		return types.ElicitationResponse{Action: types.ElicitationDecline}, nil
+	}
+
+	defer func() {
+		p.mu.Lock()
+		p.pendingElicitations--
+		p.mu.Unlock()
+	}()
+
+	response, err := handler(types.ElicitationRequest{
+		ServerName: p.server.Name,
+		Message:    request.Message,
+		Schema:     request.RequestedSchema,
+	})
+	if err != nil {
+		debugPrint("Elicitation from %s failed: %v\n", p.server.Name, err)
+		return types.ElicitationResponse{Action: types.ElicitationCancel}, nil
+	}
+
+	if response.Action != types.ElicitationAccept {
+		response.Content = nil
+	}
+	return response, nil
+}
+
+// checkElicitationSchema returns an error unless the schema is a flat object
+// of primitive properties, the only form elicitation allows
+func checkElicitationSchema(schema map[string]interface{}) error {
+	if schemaType, _ := schema["type"].(string); schemaType != "object" {
+		return fmt.Errorf("requested schema must be an object")
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, raw := range properties {
+		property, _ := raw.(map[string]interface{})
+		switch propertyType, _ := property["type"].(string); propertyType {
+		case "string", "number", "integer", "boolean":
+		default:
+			return fmt.Errorf("property %s of the requested schema must be a string, number, integer or boolean", name)
+		}
+	}
+
+	return nil
+}
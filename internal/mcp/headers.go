@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// serverHeaders returns the static headers sent to a remote server, with
+// environment variable references resolved. A token from TokenEnv is sent
+// as a bearer token unless an Authorization header is configured.
+func serverHeaders(server *types.MCPServer) (http.Header, error) {
+	headers := make(http.Header)
+
+	for name, value := range server.Headers {
+		var missing []string
+		expanded := os.Expand(value, func(variable string) string {
+			resolved, ok := os.LookupEnv(variable)
+			if !ok {
+				missing = append(missing, variable)
+			}
+			return resolved
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("environment variable %s used by header %s is not set", strings.Join(missing, ", "), name)
+		}
+		headers.Set(name, expanded)
+	}
+
+	if server.TokenEnv != "" && headers.Get("Authorization") == "" {
+		token := os.Getenv(server.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("environment variable %s holding the token of %s is not set", server.TokenEnv, server.Name)
+		}
+		headers.Set("Authorization", "Bearer "+token)
+	}
+
+	return headers, nil
+}
+
+// hasStaticAuthorization reports whether the server is authorized with a
+// configured token rather than OAuth
+func hasStaticAuthorization(server *types.MCPServer) bool {
+	if server.TokenEnv != "" {
+		return true
+	}
+	for name := range server.Headers {
+		if strings.EqualFold(name, "Authorization") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseHeader parses a header given as "Name: value"
+func ParseHeader(header string) (string, string, error) {
+	name, value, found := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (expected \"Name: value\")", header)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
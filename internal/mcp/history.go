@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// StatusFailure is a period in which a server was not usable
+type StatusFailure struct {
+	Time        time.Time     `json:"time"`
+	Status      string        `json:"status"`
+	Reason      string        `json:"reason,omitempty"`
+	RecoveredAt *time.Time    `json:"recovered_at,omitempty"`
+	Downtime    time.Duration `json:"downtime"` // until recovery, or until now
+}
+
+// StatusHistory summarizes the recorded status transitions of a server
+type StatusHistory struct {
+	ServerID     string                   `json:"server_id"`
+	ServerName   string                   `json:"server_name"`
+	Current      string                   `json:"current"`
+	Since        time.Time                `json:"since"`
+	Uptime       float64                  `json:"uptime"` // percent of the observed up and down time the server was up
+	FailureCount int                      `json:"failure_count"`
+	MTTR         time.Duration            `json:"mttr"`        // mean time to recover
+	Failures     []StatusFailure          `json:"failures"`    // most recent first
+	Transitions  []types.StatusTransition `json:"transitions"` // most recent first
+}
+
+// unmonitoredStatus marks the start and end of the periods in which the
+// agent ran health checks for a server. The status is not known in between.
+const unmonitoredStatus = "unmonitored"
+
+// isUpStatus reports whether a server in the status is offered to the agent
+func isUpStatus(status string) bool {
+	return status == "available" || status == "connected"
+}
+
+// isDownStatus reports whether the status means the server failed. Pending
+// servers are neither up nor down.
+func isDownStatus(status string) bool {
+	return status == "error" || status == "unhealthy"
+}
+
+// StatusHistory computes uptime, failures and mean time to recover from the
+// recorded transitions of a server. Periods in which no agent monitored the
+// server do not count towards the uptime. At most limit failures and
+// transitions are returned.
+func (m *Manager) StatusHistory(serverID string, limit int) (*StatusHistory, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, err := m.storage.LoadStatusTransitions(server.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load status history: %w", err)
+	}
+
+	history := &StatusHistory{
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		Current:    server.Status,
+		Failures:   []StatusFailure{},
+	}
+	if len(transitions) == 0 {
+		history.Transitions = []types.StatusTransition{}
+		return history, nil
+	}
+	history.Since = transitions[0].Time
+
+	var up, down, recovery time.Duration
+	var recovered int
+	var failures []StatusFailure
+	var open *StatusFailure
+
+	// The status of a monitored server is only known up to now while this
+	// process checks it
+	m.mu.RLock()
+	observedNow := m.monitored[server.ID] || !hasMonitoringMarkers(transitions)
+	m.mu.RUnlock()
+
+	for i, transition := range transitions {
+		end := time.Now()
+		switch {
+		case i+1 < len(transitions) && transitions[i+1].From == unmonitoredStatus && transition.To != unmonitoredStatus:
+			// Monitoring started again without a recorded stop, so the
+			// agent exited at some unknown point of this period
+			end = transition.Time
+		case i+1 < len(transitions):
+			end = transitions[i+1].Time
+		case !observedNow:
+			end = transition.Time
+		}
+		switch {
+		case isUpStatus(transition.To):
+			up += end.Sub(transition.Time)
+		case isDownStatus(transition.To):
+			down += end.Sub(transition.Time)
+		}
+
+		switch {
+		case isDownStatus(transition.To) && open == nil:
+			failures = append(failures, StatusFailure{Time: transition.Time, Status: transition.To, Reason: transition.Reason})
+			open = &failures[len(failures)-1]
+		case isUpStatus(transition.To) && open != nil:
+			recoveredAt := transition.Time
+			open.RecoveredAt = &recoveredAt
+			open.Downtime = recoveredAt.Sub(open.Time)
+			recovery += open.Downtime
+			recovered++
+			open = nil
+		}
+	}
+	if open != nil {
+		open.Downtime = time.Since(open.Time)
+	}
+
+	if up+down > 0 {
+		history.Uptime = float64(up) / float64(up+down) * 100
+	}
+	if recovered > 0 {
+		history.MTTR = recovery / time.Duration(recovered)
+	}
+	history.FailureCount = len(failures)
+
+	for i := len(failures) - 1; i >= 0 && len(history.Failures) < limit; i-- {
+		history.Failures = append(history.Failures, failures[i])
+	}
+	history.Transitions = latestTransitions(transitions, limit)
+
+	return history, nil
+}
+
+// hasMonitoringMarkers reports whether health checks were recorded for the
+// transitions' server
+func hasMonitoringMarkers(transitions []types.StatusTransition) bool {
+	for _, transition := range transitions {
+		if transition.From == unmonitoredStatus || transition.To == unmonitoredStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// RecentStatusTransitions returns the latest status transitions of all
+// servers, most recent first
+func (m *Manager) RecentStatusTransitions(limit int) ([]types.StatusTransition, error) {
+	transitions, err := m.storage.LoadStatusTransitions("")
+	if err != nil {
+		return nil, err
+	}
+	return latestTransitions(transitions, limit), nil
+}
+
+// latestTransitions returns the last limit transitions in reverse order
+func latestTransitions(transitions []types.StatusTransition, limit int) []types.StatusTransition {
+	latest := []types.StatusTransition{}
+	for i := len(transitions) - 1; i >= 0 && len(latest) < limit; i-- {
+		latest = append(latest, transitions[i])
+	}
+	return latest
+}
@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+func TestStatusHistory(t *testing.T) {
+	base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	at := func(hours float64) time.Time {
+		return base.Add(time.Duration(hours * float64(time.Hour)))
+	}
+
+	tests := []struct {
+		name        string
+		transitions [][3]interface{} // hours after base, from, to
+		uptime      float64
+		mttr        time.Duration
+		failures    int
+		recovered   int
+	}{
+		{
+			name:   "no transitions",
+			uptime: 0,
+		},
+		{
+			name: "recovered failure",
+			transitions: [][3]interface{}{
+				{0.0, unmonitoredStatus, "available"},
+				{2.0, "available", "error"},
+				{3.0, "error", "available"},
+				{4.0, "available", unmonitoredStatus},
+			},
+			uptime:    75,
+			mttr:      time.Hour,
+			failures:  1,
+			recovered: 1,
+		},
+		{
+			name: "pending counts as neither up nor down",
+			transitions: [][3]interface{}{
+				{0.0, unmonitoredStatus, "pending"},
+				{1.0, "pending", "available"},
+				{3.0, "available", unmonitoredStatus},
+			},
+			uptime: 100,
+		},
+		{
+			name: "stopped monitoring is left out",
+			transitions: [][3]interface{}{
+				{0.0, unmonitoredStatus, "available"},
+				{1.0, "available", unmonitoredStatus},
+				{5.0, unmonitoredStatus, "error"},
+				{6.0, "error", "available"},
+				{7.0, "available", unmonitoredStatus},
+			},
+			uptime:    200.0 / 3,
+			mttr:      time.Hour,
+			failures:  1,
+			recovered: 1,
+		},
+		{
+			name: "period cut short by an unclean exit is left out",
+			transitions: [][3]interface{}{
+				{0.0, unmonitoredStatus, "available"},
+				{1.0, "available", "error"},
+				{5.0, unmonitoredStatus, "available"},
+				{6.0, "available", unmonitoredStatus},
+			},
+			uptime:    100,
+			mttr:      4 * time.Hour,
+			failures:  1,
+			recovered: 1,
+		},
+		{
+			name: "failure not recovered while monitored",
+			transitions: [][3]interface{}{
+				{0.0, unmonitoredStatus, "available"},
+				{1.0, "available", "unhealthy"},
+				{2.0, "unhealthy", "error"},
+				{3.0, "error", unmonitoredStatus},
+			},
+			uptime:   100.0 / 3,
+			failures: 1,
+		},
+		{
+			name: "without markers the last status lasts until now",
+			transitions: [][3]interface{}{
+				{20.0, "pending", "available"},
+				{22.0, "available", "error"},
+				{23.0, "error", "available"},
+			},
+			uptime:    75,
+			mttr:      time.Hour,
+			failures:  1,
+			recovered: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := useTempHome(t)
+			server := &types.MCPServer{ID: "files-id", Name: "files", Status: "available"}
+			m := newTestManager(store, server)
+
+			for _, entry := range tt.transitions {
+				if err := store.AppendStatusTransition(types.StatusTransition{
+					Time:     at(entry[0].(float64)),
+					ServerID: server.ID,
+					From:     entry[1].(string),
+					To:       entry[2].(string),
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			history, err := m.StatusHistory(server.ID, 10)
+			if err != nil {
+				t.Fatalf("StatusHistory: %v", err)
+			}
+
+			// The open-ended cases run until now, so allow for the test's
+			// own running time
+			if math.Abs(history.Uptime-tt.uptime) > 0.01 {
+				t.Errorf("uptime = %.3f%%, want %.3f%%", history.Uptime, tt.uptime)
+			}
+			if history.MTTR != tt.mttr {
+				t.Errorf("MTTR = %s, want %s", history.MTTR, tt.mttr)
+			}
+			if history.FailureCount != tt.failures || len(history.Failures) != tt.failures {
+				t.Errorf("failures = %d (%d listed), want %d", history.FailureCount, len(history.Failures), tt.failures)
+			}
+			recovered := 0
+			for _, failure := range history.Failures {
+				if failure.RecoveredAt != nil {
+					recovered++
+				}
+			}
+			if recovered != tt.recovered {
+				t.Errorf("%d failures recovered, want %d", recovered, tt.recovered)
+			}
+			if len(history.Transitions) != len(tt.transitions) {
+				t.Errorf("%d transitions, want %d", len(history.Transitions), len(tt.transitions))
+			}
+		})
+	}
+}
+
+func TestStatusHistoryLimit(t *testing.T) {
+	store := useTempHome(t)
+	server := &types.MCPServer{ID: "files-id", Name: "files", Status: "available"}
+	m := newTestManager(store, server)
+
+	start := time.Now().Add(-time.Hour)
+	statuses := []string{"available", "error", "available", "error", "available"}
+	for i := 1; i < len(statuses); i++ {
+		store.AppendStatusTransition(types.StatusTransition{
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			ServerID: server.ID,
+			From:     statuses[i-1],
+			To:       statuses[i],
+		})
+	}
+
+	history, err := m.StatusHistory(server.ID, 1)
+	if err != nil {
+		t.Fatalf("StatusHistory: %v", err)
+	}
+	if history.FailureCount != 2 || len(history.Failures) != 1 || len(history.Transitions) != 1 {
+		t.Fatalf("history = %+v, want 2 failures and one of each listed", history)
+	}
+	// Most recent first
+	if !history.Failures[0].Time.Equal(start.Add(3*time.Minute)) || history.Transitions[0].To != "available" {
+		t.Errorf("listed %+v and %+v, want the latest", history.Failures[0], history.Transitions[0])
+	}
+	if history.MTTR != time.Minute {
+		t.Errorf("MTTR = %s, want 1m", history.MTTR)
+	}
+}
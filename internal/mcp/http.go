@@ -0,0 +1,264 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+const (
+	httpMaxMessageSize = 16 * 1024 * 1024
+	httpCloseTimeout   = 5 * time.Second
+)
+
+// httpTransport speaks the streamable HTTP transport: every message is
+// POSTed to the server's endpoint, which answers with a JSON body or an
+// event stream carrying the response. The session is identified by the
+// Mcp-Session-Id header the server assigns during initialize.
+type httpTransport struct {
+	server    *types.MCPServer
+	client    *http.Client
+	handler   transportHandler
+	sessionID string
+	version   string
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newHTTPTransport(server *types.MCPServer) *httpTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &httpTransport{
+		server: server,
+		client: newHTTPClient(server),
+		ctx:    ctx,
+		cancel: cancel,
+		closed: make(chan struct{}),
+	}
+}
+
+// open only records the handler, the first request opens the session
+func (t *httpTransport) open(handler transportHandler) error {
+	t.handler = handler
+	return nil
+}
+
+func (t *httpTransport) send(data []byte) error {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.server.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid server URL %s: %w", t.server.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setSessionHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if authErr := authorizationError(err); authErr != nil {
+			return authErr
+		}
+		return fmt.Errorf("failed to post to %s: %w", t.server.URL, err)
+	}
+
+	t.mu.Lock()
+	expired := resp.StatusCode == http.StatusNotFound && t.sessionID != ""
+	if expired {
+		t.sessionID = ""
+	}
+	t.mu.Unlock()
+
+	if expired {
+		resp.Body.Close()
+		return fmt.Errorf("session with %s expired", t.server.URL)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return httpStatusError(t.server.URL, resp)
+	}
+
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		resp.Body.Close()
+	case mediaType == "text/event-stream":
+		// The response, and any requests the server makes while handling
+		// ours, arrive on the stream
+		go func() {
+			defer resp.Body.Close()
+			if err := readSSE(resp.Body, func(event, data string) {
+				if event == "" || event == "message" {
+					t.deliver([]byte(data))
+				}
+			}); err != nil && t.ctx.Err() == nil {
+				debugPrint("Event stream from %s failed: %v\n", t.server.Name, err)
+			}
+		}()
+	default:
+		defer resp.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, httpMaxMessageSize))
+		if err != nil {
+			return fmt.Errorf("failed to read response from %s: %w", t.server.URL, err)
+		}
+		t.deliverBody(body)
+	}
+
+	return nil
+}
+
+// setSessionHeaders adds the session and protocol version negotiated
+// during initialize
+func (t *httpTransport) setSessionHeaders(req *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.version != "" {
+		req.Header.Set("MCP-Protocol-Version", t.version)
+	}
+}
+
+// deliverBody passes a JSON response body, a single message or a batch, to
+// the handler
+func (t *httpTransport) deliverBody(body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+
+	if body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err == nil {
+			for _, message := range batch {
+				t.deliver(message)
+			}
+			return
+		}
+	}
+
+	t.deliver(body)
+}
+
+// deliver passes a message to the handler, remembering the protocol version
+// from the initialize result for the headers of later requests
+func (t *httpTransport) deliver(data []byte) {
+	t.mu.Lock()
+	if t.version == "" {
+		var initResult struct {
+			Result struct {
+				ProtocolVersion string `json:"protocolVersion"`
+			} `json:"result"`
+		}
+		if json.Unmarshal(data, &initResult) == nil {
+			t.version = initResult.Result.ProtocolVersion
+		}
+	}
+	t.mu.Unlock()
+
+	t.handler.handleMessage(data)
+}
+
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.sessionID = ""
+	t.mu.Unlock()
+
+	// Tell the server the session can be dropped
+	if sessionID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), httpCloseTimeout)
+		defer cancel()
+		if req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.server.URL, nil); err == nil {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+
+	t.cancel()
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+	return nil
+}
+
+func (t *httpTransport) done() <-chan struct{} {
+	return t.closed
+}
+
+func (t *httpTransport) describe() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessionID != "" {
+		return fmt.Sprintf("%s (session %s)", t.server.URL, t.sessionID)
+	}
+	return t.server.URL
+}
+
+// httpStatusError describes an unsuccessful response, including the start
+// of its body
+func httpStatusError(url string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if text := strings.TrimSpace(string(body)); text != "" {
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, text)
+	}
+	return fmt.Errorf("%s returned %s", url, resp.Status)
+}
+
+// readSSE reads a server-sent event stream and calls dispatch for every
+// complete event
+func readSSE(r io.Reader, dispatch func(event, data string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), httpMaxMessageSize)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if len(data) > 0 {
+				dispatch(event, strings.Join(data, "\n"))
+			}
+			event = ""
+			data = nil
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue // comment, used as keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return scanner.Err()
+}
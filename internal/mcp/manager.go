@@ -24,6 +24,14 @@ type MCPProcessInterface interface {
 	Stop() error
 	CallTool(name string, arguments map[string]interface{}) (interface{}, error)
 	GetTools() []Tool
+	Ping() error
+	GetInitializeResult() *InitializeResult
+	ListResources() ([]Resource, error)
+	ListPrompts() ([]Prompt, error)
+	Complete(ref CompletionRef, argument, value string, context map[string]string) (*CompletionResult, error)
+	GetPrompt(name string, arguments map[string]string) ([]PromptMessage, error)
+	ReadResource(uri string) ([]ResourceContents, error)
+	CallToolWithElicitation(name string, arguments map[string]interface{}, handler ElicitationHandler) (interface{}, error)
 }
 
 type Manager struct {
@@ -31,6 +39,7 @@ type Manager struct {
 	processes map[string]MCPProcessInterface
 	storage   *storage.Storage
 	mu        sync.RWMutex
+	monitored map[string]bool // servers this process health checks
 	ctx       context.Context
 	cancel    context.CancelFunc
 }
@@ -66,7 +75,7 @@ func (m *Manager) AddServer(server *types.MCPServer) error {
 
 	server.CreatedAt = time.Now()
 	server.UpdatedAt = time.Now()
-	server.Status = "pending"
+	m.setStatus(server, "pending", "server added")
 
 	m.servers[server.ID] = server
 
@@ -75,9 +84,9 @@ func (m *Manager) AddServer(server *types.MCPServer) error {
 		fmt.Printf("Warning: failed to save servers to storage: %v\n", err)
 	}
 
-	// For stdio servers, test connection and update status immediately
-	if server.Transport == "stdio" {
-		m.testStdioServer(server)
+	// For stdio and remote servers, test connection and update status immediately
+	if server.Transport == "stdio" || isPersistentTransport(server.Transport) {
+		m.testServer(server)
 	} else {
 		// For other transports, connect in background
 		go m.connectToServer(server)
@@ -102,6 +111,10 @@ func (m *Manager) RemoveServer(id string) error {
 
 	delete(m.servers, id)
 
+	if err := m.storage.DeleteMCPCredentials(id); err != nil {
+		fmt.Printf("Warning: failed to delete credentials: %v\n", err)
+	}
+
 	// Save to storage
 	if err := m.storage.SaveMCPServers(m.servers); err != nil {
 		fmt.Printf("Warning: failed to save servers to storage: %v\n", err)
@@ -134,7 +147,38 @@ func (m *Manager) ListServers() []*types.MCPServer {
 	return servers
 }
 
-func (m *Manager) UpdateServerStatus(id, status string) error {
+// SetServerEnabled enables or disables a server. Disabling it stops its
+// running process.
+func (m *Manager) SetServerEnabled(id string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	server, exists := m.servers[id]
+	if !exists {
+		return fmt.Errorf("server %s not found", id)
+	}
+
+	server.Enabled = &enabled
+	server.UpdatedAt = time.Now()
+
+	if !enabled {
+		if process, exists := m.processes[id]; exists {
+			process.Stop()
+			delete(m.processes, id)
+		}
+	}
+
+	// Save to storage
+	if err := m.storage.SaveMCPServers(m.servers); err != nil {
+		fmt.Printf("Warning: failed to save servers to storage: %v\n", err)
+	}
+
+	return nil
+}
+
+// UpdateServerStatus changes a server's status. Transitions are recorded in
+// the status history together with the reason.
+func (m *Manager) UpdateServerStatus(id, status, reason string) error {
 	debugPrint("UpdateServerStatus: Called for server %s with status %s\n", id, status)
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -148,7 +192,7 @@ func (m *Manager) UpdateServerStatus(id, status string) error {
 	oldStatus := server.Status
 	debugPrint("UpdateServerStatus: Changing server %s status from %s to %s\n", server.Name, oldStatus, status)
 
-	server.Status = status
+	m.setStatus(server, status, reason)
 	server.UpdatedAt = time.Now()
 
 	// Only update LastPing for non-unhealthy status changes
@@ -165,22 +209,55 @@ func (m *Manager) UpdateServerStatus(id, status string) error {
 	return nil
 }
 
+// setStatus changes a server's status and records the transition. The
+// caller must hold m.mu or own the server, as AddServer does.
+func (m *Manager) setStatus(server *types.MCPServer, status, reason string) {
+	oldStatus := server.Status
+	server.Status = status
+	if oldStatus == status {
+		return
+	}
+	m.recordTransition(server, oldStatus, status, reason)
+}
+
+// recordTransition appends a status change of the server to its history
+func (m *Manager) recordTransition(server *types.MCPServer, from, to, reason string) {
+	transition := types.StatusTransition{
+		Time:       time.Now(),
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		From:       from,
+		To:         to,
+		Reason:     reason,
+	}
+	if err := m.storage.AppendStatusTransition(transition); err != nil {
+		fmt.Printf("Warning: failed to record status change: %v\n", err)
+	}
+}
+
 func (m *Manager) connectToServer(server *types.MCPServer) {
 	switch server.Transport {
 	case "stdio":
 		m.connectStdio(server)
-	case "sse":
-		m.connectSSE(server)
-	case "http":
-		m.connectHTTP(server)
+	case "websocket", "http", "sse":
+		m.testServer(server)
 	default:
-		m.UpdateServerStatus(server.ID, "error")
+		m.UpdateServerStatus(server.ID, "error", "unsupported transport "+server.Transport)
 	}
 }
 
-// testStdioServer tests stdio server without mutex complications
-func (m *Manager) testStdioServer(server *types.MCPServer) {
-	debugPrint("Testing stdio server %s\n", server.Name)
+// testServer connects to a server, discovers its tools and records the
+// result, without mutex complications. Persistent connections are kept for
+// later calls.
+func (m *Manager) testServer(server *types.MCPServer) {
+	process, tools, err := startServerProcess(server)
+	m.recordServerTest(server, process, tools, err)
+}
+
+// startServerProcess starts a server and discovers its tools. It only does
+// I/O, so it can run without holding m.mu.
+func startServerProcess(server *types.MCPServer) (MCPProcessInterface, []Tool, error) {
+	debugPrint("Testing %s server %s\n", server.Transport, server.Name)
 
 	var process MCPProcessInterface
 
@@ -191,9 +268,8 @@ func (m *Manager) testStdioServer(server *types.MCPServer) {
 	// Test if we can start the process and discover tools
 	debugPrint("Starting process for %s\n", server.Name)
 	if err := process.Start(); err != nil {
-		debugPrint("Failed to start process for %s: %v\n", server.Name, err)
-		server.Status = "error"
-		return
+		process.Stop()
+		return nil, nil, err
 	}
 	debugPrint("Process started successfully for %s\n", server.Name)
 
@@ -202,6 +278,26 @@ func (m *Manager) testStdioServer(server *types.MCPServer) {
 	tools := process.GetTools()
 	debugPrint("Got %d tools for %s\n", len(tools), server.Name)
 
+	return process, tools, nil
+}
+
+// recordServerTest records the outcome of startServerProcess on the server.
+// The caller holds m.mu.
+func (m *Manager) recordServerTest(server *types.MCPServer, process MCPProcessInterface, tools []Tool, err error) {
+	if err != nil {
+		m.setStatus(server, "error", fmt.Sprintf("failed to start: %v", err))
+		fmt.Printf("Server %s failed to start: %v\n", server.Name, err)
+		if !IsAuthorizationRequired(err) {
+			fmt.Printf("Run 'syseng-agent mcp doctor %s' for a step-by-step diagnosis\n", server.ID)
+		}
+		if err := m.storage.SaveMCPServers(m.servers); err != nil {
+			fmt.Printf("Warning: failed to save servers to storage: %v\n", err)
+		}
+		return
+	}
+
+	applyInitializeResult(server, process.GetInitializeResult())
+
 	// If we successfully got tools, the server is working
 	if len(tools) > 0 {
 		// Store the process
@@ -227,7 +323,11 @@ func (m *Manager) testStdioServer(server *types.MCPServer) {
 		server.Tools = serverTools
 
 		// Update status directly (we already have the lock from AddServer)
-		server.Status = "available"
+		status := "available"
+		if isPersistentTransport(server.Transport) {
+			status = "connected"
+		}
+		m.setStatus(server, status, fmt.Sprintf("%d tools discovered", len(tools)))
 		server.UpdatedAt = time.Now()
 		server.LastPing = time.Now()
 
@@ -238,25 +338,35 @@ func (m *Manager) testStdioServer(server *types.MCPServer) {
 			fmt.Printf("Warning: failed to save servers to storage: %v\n", err)
 		}
 	} else {
-		debugPrint("No tools found for %s, marking as error\n", server.Name)
-		server.Status = "error"
+		process.Stop()
+		m.setStatus(server, "error", "started but reported no tools")
+		fmt.Printf("Server %s started but reported no tools\n", server.Name)
+		fmt.Printf("Run 'syseng-agent mcp doctor %s' for a step-by-step diagnosis\n", server.ID)
+		if err := m.storage.SaveMCPServers(m.servers); err != nil {
+			fmt.Printf("Warning: failed to save servers to storage: %v\n", err)
+		}
 	}
 
-	debugPrint("stdio server test completed for %s\n", server.Name)
+	debugPrint("%s server test completed for %s\n", server.Transport, server.Name)
 }
 
-func (m *Manager) connectStdio(server *types.MCPServer) {
-	// This method is now only used for background connections from connectToServer
-	// For immediate stdio testing during AddServer, use testStdioServer instead
-	m.testStdioServer(server)
-}
+// applyInitializeResult records the negotiated protocol details on the server
+func applyInitializeResult(server *types.MCPServer, result *InitializeResult) {
+	if result == nil {
+		return
+	}
 
-func (m *Manager) connectSSE(server *types.MCPServer) {
-	m.UpdateServerStatus(server.ID, "connected")
+	serverInfo := result.ServerInfo
+	server.ProtocolVersion = result.ProtocolVersion
+	server.ServerInfo = &serverInfo
+	server.ServerCapabilities = result.Capabilities
+	server.Instructions = result.Instructions
 }
 
-func (m *Manager) connectHTTP(server *types.MCPServer) {
-	m.UpdateServerStatus(server.ID, "connected")
+func (m *Manager) connectStdio(server *types.MCPServer) {
+	// This method is now only used for background connections from connectToServer
+	// For immediate stdio testing during AddServer, use testServer instead
+	m.testServer(server)
 }
 
 func (m *Manager) healthCheckLoop() {
@@ -277,15 +387,51 @@ func (m *Manager) performHealthChecks() {
 	m.mu.RLock()
 	servers := make([]*types.MCPServer, 0, len(m.servers))
 	for _, server := range m.servers {
+		if !server.IsEnabled() {
+			continue
+		}
 		servers = append(servers, server)
 	}
 	m.mu.RUnlock()
 
 	for _, server := range servers {
+		m.startMonitoring(server)
 		go m.healthCheck(server)
 	}
 }
 
+// startMonitoring records that the server's status is observed from now on,
+// so that StatusHistory does not count the time before as up or down. Only
+// servers with a connection to check are marked.
+func (m *Manager) startMonitoring(server *types.MCPServer) {
+	if !isPersistentTransport(server.Transport) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.monitored[server.ID] || m.ctx.Err() != nil {
+		return
+	}
+	if m.monitored == nil {
+		m.monitored = make(map[string]bool)
+	}
+	m.monitored[server.ID] = true
+	m.recordTransition(server, unmonitoredStatus, server.Status, "monitoring started")
+}
+
+// stopMonitoring records the end of health checks for all servers this
+// process observed. The caller must hold m.mu.
+func (m *Manager) stopMonitoring() {
+	for id := range m.monitored {
+		if server, exists := m.servers[id]; exists {
+			m.recordTransition(server, server.Status, unmonitoredStatus, "monitoring stopped")
+		}
+	}
+	m.monitored = nil
+}
+
 func (m *Manager) healthCheck(server *types.MCPServer) {
 	timeSinceLastPing := time.Since(server.LastPing)
 	debugPrint("HealthCheck: Server %s (transport: %s) - LastPing: %v ago\n",
@@ -299,85 +445,113 @@ func (m *Manager) healthCheck(server *types.MCPServer) {
 		// the server should remain available
 		debugPrint("HealthCheck: Skipping stdio server %s (no persistent connection, health check not applicable)\n", server.Name)
 		return
-	case "sse", "http":
-		// For persistent connections, use shorter timeout but add connection test
-		if timeSinceLastPing > 60*time.Second {
-			debugPrint("HealthCheck: Persistent server %s may be unhealthy (no ping for %v)\n",
-				server.Name, timeSinceLastPing)
-			// TODO: Add actual connection test for persistent servers
-			// For now, mark as unhealthy after 60 seconds
-			m.UpdateServerStatus(server.ID, "unhealthy")
-		} else {
-			debugPrint("HealthCheck: Persistent server %s is healthy (last ping: %v ago)\n",
-				server.Name, timeSinceLastPing)
+	case "websocket", "http", "sse":
+		// Ping the shared connection; servers without one are connected on
+		// their next use
+		m.mu.RLock()
+		process, exists := m.processes[server.ID]
+		m.mu.RUnlock()
+		if !exists {
+			// Unhealthy servers are not offered to the agent, so they are
+			// reconnected here instead of on their next use
+			if server.Status != "unhealthy" {
+				return
+			}
+			if _, err := m.connectPersistent(server); err != nil {
+				debugPrint("HealthCheck: Reconnecting to %s failed: %v\n", server.Name, err)
+				return
+			}
+			m.UpdateServerStatus(server.ID, "connected", "reconnected")
+			return
+		}
+
+		if err := process.Ping(); err != nil {
+			debugPrint("HealthCheck: %s server %s did not answer ping: %v\n", server.Transport, server.Name, err)
+			m.UpdateServerStatus(server.ID, "unhealthy", fmt.Sprintf("ping failed: %v", err))
+
+			// Drop the connection so that the next check starts a new one
+			m.mu.Lock()
+			if m.processes[server.ID] == process {
+				delete(m.processes, server.ID)
+			}
+			m.mu.Unlock()
+			process.Stop()
+			return
 		}
+
+		m.UpdateServerStatus(server.ID, "connected", "ping answered")
 	default:
 		// Unknown transport, use conservative approach
 		if timeSinceLastPing > 2*time.Minute {
 			debugPrint("HealthCheck: Unknown transport server %s marked unhealthy\n", server.Name)
-			m.UpdateServerStatus(server.ID, "unhealthy")
+			m.UpdateServerStatus(server.ID, "unhealthy", fmt.Sprintf("no activity for %s", timeSinceLastPing.Round(time.Second)))
 		}
 	}
 }
 
 func (m *Manager) Shutdown() {
+	m.cancel()
+
 	m.mu.Lock()
+	m.stopMonitoring()
+
 	// Stop all processes
 	for _, process := range m.processes {
 		process.Stop()
 	}
 	m.processes = make(map[string]MCPProcessInterface)
 	m.mu.Unlock()
-
-	m.cancel()
 }
 
 // CallTool calls a tool on the specified MCP server
 func (m *Manager) CallTool(serverID, toolName string, arguments map[string]interface{}) (interface{}, error) {
-	m.mu.RLock()
-	server, serverExists := m.servers[serverID]
-	process, processExists := m.processes[serverID]
-	m.mu.RUnlock()
+	return m.CallToolWithElicitation(serverID, toolName, arguments, nil)
+}
 
-	if !serverExists {
-		return nil, fmt.Errorf("MCP server %s not found", serverID)
+// CallToolWithElicitation calls a tool and routes the input the server
+// requests during the call to handler. Without a handler such requests are
+// declined.
+func (m *Manager) CallToolWithElicitation(serverID, toolName string, arguments map[string]interface{}, handler ElicitationHandler) (interface{}, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
 	}
 
 	debugPrint("CallTool: Executing %s on server %s (transport: %s)\n", toolName, server.Name, server.Transport)
 
-	var result interface{}
-	var err error
-
-	// For stdio servers, we might need to start a fresh process for each call
-	if server.Transport == "stdio" {
-		// For Mock servers, use the existing process
-		if server.URL == "echo" || server.URL == "mock" {
-			if !processExists {
-				return nil, fmt.Errorf("MCP server %s not available", serverID)
-			}
-			result, err = process.CallTool(toolName, arguments)
-		} else {
-			// For real stdio servers, start a fresh process
-			var freshProcess MCPProcessInterface
-			freshProcess = NewMCPProcess(server)
-
-			if startErr := freshProcess.Start(); startErr != nil {
-				debugPrint("CallTool: Failed to start fresh process for %s: %v\n", server.Name, startErr)
-				return nil, fmt.Errorf("failed to start MCP process: %w", startErr)
-			}
-			defer freshProcess.Stop()
+	start := time.Now()
 
-			result, err = freshProcess.CallTool(toolName, arguments)
-		}
-	} else {
-		// For other transports (SSE, HTTP), use persistent connection
-		if !processExists {
-			return nil, fmt.Errorf("MCP server %s not connected", serverID)
+	// Real stdio servers get a fresh process for each call, other transports
+	// share their persistent connection
+	process, release, err := m.acquireProcess(serverID)
+	if err != nil {
+		debugPrint("CallTool: Failed to get a process for %s: %v\n", server.Name, err)
+		m.recordToolCall(server, toolName, start, 0, nil, err)
+		return nil, err
+	}
+	defer release()
+
+	// The time the user spends on elicitation forms is not the server's
+	var inputWait time.Duration
+	var inputWaitMu sync.Mutex
+	if handler != nil {
+		ask := handler
+		handler = func(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+			asked := time.Now()
+			defer func() {
+				inputWaitMu.Lock()
+				inputWait += time.Since(asked)
+				inputWaitMu.Unlock()
+			}()
+			return ask(request)
 		}
-
-		result, err = process.CallTool(toolName, arguments)
 	}
 
+	result, err := process.CallToolWithElicitation(toolName, arguments, handler)
+	inputWaitMu.Lock()
+	m.recordToolCall(server, toolName, start, inputWait, result, err)
+	inputWaitMu.Unlock()
+
 	// Update LastPing on successful tool execution to keep server healthy
 	if err == nil {
 		debugPrint("CallTool: Tool execution successful, updating LastPing for server %s\n", server.Name)
@@ -406,8 +580,8 @@ func (m *Manager) GetServerTools(serverID string) ([]Tool, error) {
 		return nil, fmt.Errorf("MCP server %s not found", serverID)
 	}
 
-	// For stdio servers, use stored tools if available
-	if server.Transport == "stdio" && len(server.Tools) > 0 {
+	// Use the tools stored when the server was added, if available
+	if len(server.Tools) > 0 {
 		var tools []Tool
 		for _, tool := range server.Tools {
 			tools = append(tools, Tool{
@@ -443,9 +617,13 @@ func (m *Manager) GetAllTools() map[string][]Tool {
 
 	// Include all available servers
 	for serverID, server := range m.servers {
+		if !server.IsEnabled() {
+			debugPrint("GetAllTools: Skipping disabled server %s\n", server.Name)
+			continue
+		}
 		if server.Status == "available" || server.Status == "connected" {
-			// For stdio servers, use stored tools
-			if server.Transport == "stdio" && len(server.Tools) > 0 {
+			// Use the tools stored when the server was added
+			if len(server.Tools) > 0 {
 				var tools []Tool
 				for _, tool := range server.Tools {
 					tools = append(tools, Tool{
@@ -455,8 +633,8 @@ func (m *Manager) GetAllTools() map[string][]Tool {
 					})
 				}
 				allTools[server.Name] = tools
-				debugPrint("GetAllTools: Added %d tools from stdio server %s (status: %s)\n", 
-					len(tools), server.Name, server.Status)
+				debugPrint("GetAllTools: Added %d tools from %s server %s (status: %s)\n", 
+					len(tools), server.Transport, server.Name, server.Status)
 			} else if process, exists := m.processes[serverID]; exists {
 				// For other servers, use process
 				allTools[server.Name] = process.GetTools()
@@ -470,3 +648,126 @@ func (m *Manager) GetAllTools() map[string][]Tool {
 
 	return allTools
 }
+
+// GetServerInstructions returns the usage instructions reported by each
+// available server during initialize, keyed by server name
+func (m *Manager) GetServerInstructions() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	instructions := make(map[string]string)
+	for _, server := range m.servers {
+		if !server.IsEnabled() || (server.Status != "available" && server.Status != "connected") {
+			continue
+		}
+		if server.Instructions != "" {
+			instructions[server.Name] = server.Instructions
+		}
+	}
+
+	return instructions
+}
+
+// acquireProcess returns a process for talking to the server and a function
+// to release it. Real stdio servers get a fresh process per use, other
+// transports share their persistent connection.
+func (m *Manager) acquireProcess(serverID string) (MCPProcessInterface, func(), error) {
+	m.mu.RLock()
+	server, serverExists := m.servers[serverID]
+	process, processExists := m.processes[serverID]
+	m.mu.RUnlock()
+
+	if !serverExists {
+		return nil, nil, fmt.Errorf("MCP server %s not found", serverID)
+	}
+
+	if !server.IsEnabled() {
+		return nil, nil, fmt.Errorf("MCP server %s is disabled; run 'syseng-agent mcp enable %s'", server.Name, server.ID)
+	}
+
+	if server.Transport == "stdio" && server.URL != "echo" && server.URL != "mock" {
+		freshProcess := NewMCPProcess(server)
+		if err := freshProcess.Start(); err != nil {
+			return nil, nil, fmt.Errorf("failed to start MCP process: %w", err)
+		}
+		return freshProcess, func() { freshProcess.Stop() }, nil
+	}
+
+	if !processExists && isPersistentTransport(server.Transport) {
+		connected, err := m.connectPersistent(server)
+		if err != nil {
+			return nil, nil, err
+		}
+		return connected, func() {}, nil
+	}
+
+	if !processExists {
+		return nil, nil, fmt.Errorf("MCP server %s not connected", serverID)
+	}
+
+	return process, func() {}, nil
+}
+
+// connectPersistent opens the shared connection to a server whose
+// connection was not established in this run, e.g. after a restart
+func (m *Manager) connectPersistent(server *types.MCPServer) (MCPProcessInterface, error) {
+	process := NewMCPProcess(server)
+	if err := process.Start(); err != nil {
+		process.Stop()
+		return nil, fmt.Errorf("failed to connect to MCP server %s: %w", server.Name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Another caller may have connected in the meantime
+	if existing, exists := m.processes[server.ID]; exists {
+		process.Stop()
+		return existing, nil
+	}
+	m.processes[server.ID] = process
+
+	return process, nil
+}
+
+// ListServerResources returns the resources exposed by a server, if it
+// advertises the resources capability
+func (m *Manager) ListServerResources(serverID string) ([]Resource, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !server.HasCapability("resources") {
+		return nil, fmt.Errorf("server %s does not support resources", server.Name)
+	}
+
+	process, release, err := m.acquireProcess(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return process.ListResources()
+}
+
+// ListServerPrompts returns the prompt templates exposed by a server, if it
+// advertises the prompts capability
+func (m *Manager) ListServerPrompts(serverID string) ([]Prompt, error) {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !server.HasCapability("prompts") {
+		return nil, fmt.Errorf("server %s does not support prompts", server.Name)
+	}
+
+	process, release, err := m.acquireProcess(serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return process.ListPrompts()
+}
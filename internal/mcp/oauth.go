@@ -0,0 +1,853 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/storage"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+const (
+	oauthRequestTimeout = 30 * time.Second
+	oauthLoginTimeout   = 5 * time.Minute
+	oauthCallbackPath   = "/callback"
+)
+
+// oauthClient talks to authorization servers. It must not be the client of
+// the transports, whose requests carry the MCP server's token.
+var oauthClient = &http.Client{Timeout: oauthRequestTimeout}
+
+// oauthRefreshMu serializes token refreshes, a refresh token may only be
+// used once when the authorization server rotates them
+var oauthRefreshMu sync.Mutex
+
+// AuthorizationRequiredError is returned when a server answers 401 and no
+// valid token is available
+type AuthorizationRequiredError struct {
+	ServerID   string
+	ServerName string
+	Reason     string
+}
+
+func (e *AuthorizationRequiredError) Error() string {
+	message := fmt.Sprintf("MCP server %s requires authorization", e.ServerName)
+	if e.Reason != "" {
+		message += " (" + e.Reason + ")"
+	}
+	return fmt.Sprintf("%s; run 'syseng-agent mcp login %s'", message, e.ServerID)
+}
+
+// IsAuthorizationRequired reports whether err was caused by a missing or
+// rejected OAuth token
+func IsAuthorizationRequired(err error) bool {
+	return authorizationError(err) != nil
+}
+
+// authorizationError returns the AuthorizationRequiredError wrapped in err,
+// or nil
+func authorizationError(err error) error {
+	var authErr *AuthorizationRequiredError
+	if errors.As(err, &authErr) {
+		return authErr
+	}
+	return nil
+}
+
+// newHTTPClient returns the client used by the HTTP based transports. It
+// adds the server's configured headers and its stored OAuth token to every
+// request, and refreshes the token when it expired or was rejected.
+func newHTTPClient(server *types.MCPServer) *http.Client {
+	return &http.Client{
+		Transport: &oauthRoundTripper{
+			server:  server,
+			storage: storage.New(""),
+			base:    http.DefaultTransport,
+		},
+	}
+}
+
+type oauthRoundTripper struct {
+	server  *types.MCPServer
+	storage *storage.Storage
+	base    http.RoundTripper
+
+	// The stored credentials are read once and kept in memory; they are
+	// read again on refresh and after the server rejected them
+	mu          sync.Mutex
+	credentials *types.MCPOAuthCredentials
+	loaded      bool
+}
+
+// currentCredentials returns the cached credentials, loading them from
+// storage on first use
+func (rt *oauthRoundTripper) currentCredentials() (*types.MCPOAuthCredentials, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if !rt.loaded {
+		credentials, err := rt.storage.LoadMCPCredentials(rt.server.ID)
+		if err != nil {
+			return nil, err
+		}
+		rt.credentials, rt.loaded = credentials, true
+	}
+	return rt.credentials, nil
+}
+
+// setCredentials replaces the cached credentials; nil makes the next
+// request load them from storage again, e.g. after mcp login in another
+// process
+func (rt *oauthRoundTripper) setCredentials(credentials *types.MCPOAuthCredentials) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.credentials, rt.loaded = credentials, credentials != nil
+}
+
+func (rt *oauthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers, err := serverHeaders(rt.server)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) > 0 {
+		req = req.Clone(req.Context())
+		for name, values := range headers {
+			req.Header[name] = values
+		}
+	}
+
+	credentials, err := rt.currentCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials for %s: %w", rt.server.Name, err)
+	}
+
+	if credentials != nil && credentials.Expired() && credentials.RefreshToken != "" {
+		if refreshed, err := rt.refresh(credentials); err == nil {
+			credentials = refreshed
+		} else {
+			debugPrint("Refreshing the token of %s failed: %v\n", rt.server.Name, err)
+		}
+	}
+
+	resp, err := rt.base.RoundTrip(authorizeRequest(req, credentials))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// A rejected static token is reported as it is, logging in would not help
+	if credentials == nil && hasStaticAuthorization(rt.server) {
+		return resp, nil
+	}
+	reason := bearerChallenge(resp.Header.Get("WWW-Authenticate"))["error_description"]
+
+	// The token may have been revoked early; retry once with a new one
+	if credentials != nil && credentials.RefreshToken != "" && (req.Body == nil || req.GetBody != nil) {
+		refreshed, refreshErr := rt.refresh(credentials)
+		if refreshErr == nil {
+			retry := req.Clone(req.Context())
+			if req.GetBody != nil {
+				if retry.Body, err = req.GetBody(); err != nil {
+					return resp, nil
+				}
+			}
+
+			resp.Body.Close()
+			resp, err = rt.base.RoundTrip(authorizeRequest(retry, refreshed))
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			reason = bearerChallenge(resp.Header.Get("WWW-Authenticate"))["error_description"]
+		} else {
+			reason = refreshErr.Error()
+		}
+	} else if credentials == nil {
+		reason = "not logged in"
+	}
+
+	// Read the stored credentials again next time, they may have changed
+	rt.setCredentials(nil)
+
+	resp.Body.Close()
+	return nil, &AuthorizationRequiredError{ServerID: rt.server.ID, ServerName: rt.server.Name, Reason: reason}
+}
+
+// refresh exchanges the refresh token for a new access token, stores it and
+// caches it
+func (rt *oauthRoundTripper) refresh(credentials *types.MCPOAuthCredentials) (*types.MCPOAuthCredentials, error) {
+	oauthRefreshMu.Lock()
+	defer oauthRefreshMu.Unlock()
+
+	// Another process or connection may have refreshed it already
+	if stored, err := rt.storage.LoadMCPCredentials(rt.server.ID); err == nil && stored != nil &&
+		stored.AccessToken != credentials.AccessToken && !stored.Expired() {
+		rt.setCredentials(stored)
+		return stored, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {credentials.RefreshToken},
+		"client_id":     {credentials.ClientID},
+	}
+	if credentials.Resource != "" {
+		form.Set("resource", credentials.Resource)
+	}
+
+	token, err := requestToken(credentials, form)
+	if err != nil {
+		return nil, fmt.Errorf("token refresh failed: %w", err)
+	}
+
+	refreshed := *credentials
+	applyToken(&refreshed, token)
+	if err := rt.storage.SaveMCPCredentials(rt.server.ID, &refreshed); err != nil {
+		fmt.Printf("Warning: failed to save credentials to storage: %v\n", err)
+	}
+	rt.setCredentials(&refreshed)
+
+	debugPrint("Refreshed the access token of %s\n", rt.server.Name)
+	return &refreshed, nil
+}
+
+// authorizeRequest returns a copy of the request carrying the access token
+func authorizeRequest(req *http.Request, credentials *types.MCPOAuthCredentials) *http.Request {
+	if credentials == nil || credentials.AccessToken == "" {
+		return req
+	}
+
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", "Bearer "+credentials.AccessToken)
+	return authorized
+}
+
+// bearerChallenge parses the parameters of a Bearer WWW-Authenticate header
+func bearerChallenge(header string) map[string]string {
+	params := make(map[string]string)
+
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return params
+	}
+
+	for rest != "" {
+		var pair string
+		rest = strings.TrimLeft(rest, " ,")
+		name, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		name = strings.TrimSpace(name)
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				break
+			}
+			pair, rest = value[1:end+1], value[end+2:]
+		} else {
+			pair, rest, _ = strings.Cut(value, ",")
+		}
+		params[strings.ToLower(name)] = strings.TrimSpace(pair)
+	}
+
+	return params
+}
+
+// tokenResponse is the answer of the token endpoint
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Scope            string `json:"scope"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts a grant to the token endpoint
+func requestToken(credentials *types.MCPOAuthCredentials, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequest(http.MethodPost, credentials.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if credentials.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(credentials.ClientID), url.QueryEscape(credentials.ClientSecret))
+	}
+
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("invalid token response (%s): %w", resp.Status, err)
+	}
+
+	if token.Error != "" {
+		if token.ErrorDescription != "" {
+			return nil, fmt.Errorf("%s: %s", token.Error, token.ErrorDescription)
+		}
+		return nil, fmt.Errorf("%s", token.Error)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned %s without an access token", resp.Status)
+	}
+
+	return &token, nil
+}
+
+// applyToken records a token response in the credentials
+func applyToken(credentials *types.MCPOAuthCredentials, token *tokenResponse) {
+	credentials.AccessToken = token.AccessToken
+	credentials.TokenType = token.TokenType
+	// Servers that do not rotate refresh tokens omit them from the response
+	if token.RefreshToken != "" {
+		credentials.RefreshToken = token.RefreshToken
+	}
+	if token.Scope != "" {
+		credentials.Scope = token.Scope
+	}
+	credentials.ExpiresAt = time.Time{}
+	if token.ExpiresIn > 0 {
+		credentials.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	credentials.UpdatedAt = time.Now()
+}
+
+// protectedResourceMetadata is the OAuth protected resource metadata
+// (RFC 9728) published by an MCP server
+type protectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported"`
+}
+
+// authorizationServerMetadata is the OAuth authorization server metadata
+// (RFC 8414)
+type authorizationServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint"`
+	ScopesSupported               []string `json:"scopes_supported"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// oauthDiscovery is what mcp login needs to know to authorize a server
+type oauthDiscovery struct {
+	Resource            string
+	AuthorizationServer string
+	Metadata            authorizationServerMetadata
+	Scopes              []string
+}
+
+// discoverAuthorization finds the authorization server of an MCP server.
+// The server's protected resource metadata names it; servers without that
+// metadata are their own authorization server.
+func discoverAuthorization(server *types.MCPServer) (*oauthDiscovery, error) {
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL %s: %w", server.URL, err)
+	}
+
+	challenge := probeServer(server)
+
+	discovery := &oauthDiscovery{Resource: canonicalResource(serverURL)}
+	resource, err := fetchResourceMetadata(serverURL, challenge["resource_metadata"])
+	if err == nil && len(resource.AuthorizationServers) > 0 {
+		discovery.AuthorizationServer = resource.AuthorizationServers[0]
+		discovery.Scopes = resource.ScopesSupported
+		if resource.Resource != "" {
+			discovery.Resource = resource.Resource
+		}
+	} else {
+		debugPrint("No protected resource metadata for %s: %v\n", server.Name, err)
+		discovery.AuthorizationServer = serverURL.Scheme + "://" + serverURL.Host
+	}
+
+	issuer, err := url.Parse(discovery.AuthorizationServer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization server %s: %w", discovery.AuthorizationServer, err)
+	}
+	if err := requireSecureURL(issuer); err != nil {
+		return nil, err
+	}
+
+	metadata, err := fetchAuthorizationServerMetadata(issuer)
+	if err != nil {
+		if resource != nil {
+			return nil, err
+		}
+		// Default endpoints of servers that predate metadata discovery
+		debugPrint("No authorization server metadata for %s, using default endpoints: %v\n", server.Name, err)
+		metadata = &authorizationServerMetadata{
+			Issuer:                discovery.AuthorizationServer,
+			AuthorizationEndpoint: discovery.AuthorizationServer + "/authorize",
+			TokenEndpoint:         discovery.AuthorizationServer + "/token",
+			RegistrationEndpoint:  discovery.AuthorizationServer + "/register",
+		}
+	}
+
+	for _, endpoint := range []string{metadata.AuthorizationEndpoint, metadata.TokenEndpoint, metadata.RegistrationEndpoint} {
+		if endpoint == "" {
+			continue
+		}
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %s in authorization server metadata: %w", endpoint, err)
+		}
+		if err := requireSecureURL(endpointURL); err != nil {
+			return nil, err
+		}
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" {
+		return nil, fmt.Errorf("authorization server %s does not publish its authorization and token endpoints", discovery.AuthorizationServer)
+	}
+	if methods := metadata.CodeChallengeMethodsSupported; len(methods) > 0 && !containsString(methods, "S256") {
+		return nil, fmt.Errorf("authorization server %s does not support PKCE with S256", discovery.AuthorizationServer)
+	}
+
+	if len(discovery.Scopes) == 0 {
+		discovery.Scopes = metadata.ScopesSupported
+	}
+	discovery.Metadata = *metadata
+	return discovery, nil
+}
+
+// probeServer makes an unauthenticated request to the server and returns
+// the parameters of its Bearer challenge, if it sent one
+func probeServer(server *types.MCPServer) map[string]string {
+	method, body := http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	if server.Transport == "sse" {
+		method, body = http.MethodGet, ""
+	}
+
+	req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	return bearerChallenge(resp.Header.Get("WWW-Authenticate"))
+}
+
+// fetchResourceMetadata fetches the protected resource metadata from the
+// URL given in the server's challenge, or from its well-known locations
+func fetchResourceMetadata(serverURL *url.URL, metadataURL string) (*protectedResourceMetadata, error) {
+	candidates := wellKnownURLs(serverURL, "oauth-protected-resource")
+	if metadataURL != "" {
+		candidates = []string{metadataURL}
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		var metadata protectedResourceMetadata
+		if lastErr = fetchJSON(candidate, &metadata); lastErr == nil {
+			return &metadata, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// fetchAuthorizationServerMetadata fetches the OAuth or OpenID Connect
+// metadata of an authorization server
+func fetchAuthorizationServerMetadata(issuer *url.URL) (*authorizationServerMetadata, error) {
+	candidates := append(wellKnownURLs(issuer, "oauth-authorization-server"), wellKnownURLs(issuer, "openid-configuration")...)
+	if path := strings.TrimSuffix(issuer.Path, "/"); path != "" {
+		candidates = append(candidates, issuer.Scheme+"://"+issuer.Host+path+"/.well-known/openid-configuration")
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		var metadata authorizationServerMetadata
+		if lastErr = fetchJSON(candidate, &metadata); lastErr == nil {
+			return &metadata, nil
+		}
+	}
+	return nil, fmt.Errorf("no authorization server metadata found for %s: %w", issuer, lastErr)
+}
+
+// wellKnownURLs returns the well-known locations of a metadata document for
+// a URL: inserted before its path first, then at the root of its host
+func wellKnownURLs(base *url.URL, name string) []string {
+	origin := base.Scheme + "://" + base.Host
+	var urls []string
+	if path := strings.TrimSuffix(base.Path, "/"); path != "" {
+		urls = append(urls, origin+"/.well-known/"+name+path)
+	}
+	return append(urls, origin+"/.well-known/"+name)
+}
+
+// fetchJSON gets a JSON document
+func fetchJSON(location string, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", location, resp.Status)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(target); err != nil {
+		return fmt.Errorf("invalid JSON from %s: %w", location, err)
+	}
+	return nil
+}
+
+// canonicalResource returns the resource identifier of an MCP server URL
+func canonicalResource(serverURL *url.URL) string {
+	resource := *serverURL
+	resource.Fragment = ""
+	resource.RawQuery = ""
+	return resource.String()
+}
+
+// requireSecureURL rejects authorization endpoints that are not HTTPS.
+// Loopback addresses are allowed so that a local authorization server can
+// be used for testing.
+func requireSecureURL(endpoint *url.URL) error {
+	if endpoint.Scheme == "https" {
+		return nil
+	}
+	if endpoint.Scheme == "http" && isLoopbackHost(endpoint.Hostname()) {
+		return nil
+	}
+	return fmt.Errorf("authorization endpoint %s must use https", endpoint)
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginOptions configure mcp login
+type LoginOptions struct {
+	// Pre-registered client, used instead of dynamic client registration
+	ClientID     string
+	ClientSecret string
+
+	// Scopes to request, by default those the server advertises
+	Scopes []string
+
+	// Port of the loopback redirect listener, 0 picks a free one
+	Port int
+
+	// OpenURL is called with the authorization URL the user has to visit
+	OpenURL func(authURL string)
+
+	Timeout time.Duration
+}
+
+// Login runs the OAuth authorization code flow with PKCE for a remote MCP
+// server, stores the tokens and reconnects to the server with them
+func (m *Manager) Login(serverID string, options LoginOptions) error {
+	server, err := m.GetServer(serverID)
+	if err != nil {
+		return err
+	}
+
+	if server.Transport != "http" && server.Transport != "sse" {
+		return fmt.Errorf("server %s uses the %s transport; OAuth is only used with http and sse", server.Name, server.Transport)
+	}
+
+	discovery, err := discoverAuthorization(server)
+	if err != nil {
+		return fmt.Errorf("authorization discovery failed: %w", err)
+	}
+
+	existing, err := m.storage.LoadMCPCredentials(server.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load credentials: %w", err)
+	}
+
+	credentials := &types.MCPOAuthCredentials{
+		AuthorizationServer: discovery.Metadata.Issuer,
+		TokenEndpoint:       discovery.Metadata.TokenEndpoint,
+		Resource:            discovery.Resource,
+	}
+	if credentials.AuthorizationServer == "" {
+		credentials.AuthorizationServer = discovery.AuthorizationServer
+	}
+
+	// Reuse a dynamic registration, which is bound to its redirect URI
+	reuse := options.ClientID == "" && existing != nil && existing.ClientID != "" &&
+		existing.AuthorizationServer == credentials.AuthorizationServer
+	port := options.Port
+	if reuse && port == 0 {
+		if redirect, err := url.Parse(existing.RedirectURI); err == nil {
+			fmt.Sscanf(redirect.Port(), "%d", &port)
+		}
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil && reuse && options.Port == 0 {
+		reuse = false
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start the redirect listener: %w", err)
+	}
+	defer listener.Close()
+
+	redirectURI := fmt.Sprintf("http://%s%s", listener.Addr(), oauthCallbackPath)
+	reuse = reuse && existing.RedirectURI == redirectURI
+
+	switch {
+	case options.ClientID != "":
+		credentials.ClientID = options.ClientID
+		credentials.ClientSecret = options.ClientSecret
+	case reuse:
+		credentials.ClientID = existing.ClientID
+		credentials.ClientSecret = existing.ClientSecret
+	default:
+		if discovery.Metadata.RegistrationEndpoint == "" {
+			return fmt.Errorf("authorization server %s does not support dynamic client registration; pass --client-id", credentials.AuthorizationServer)
+		}
+		if err := registerClient(discovery.Metadata.RegistrationEndpoint, redirectURI, credentials); err != nil {
+			return fmt.Errorf("client registration failed: %w", err)
+		}
+	}
+	credentials.RedirectURI = redirectURI
+
+	scopes := options.Scopes
+	if len(scopes) == 0 {
+		scopes = discovery.Scopes
+	}
+
+	verifier := randomToken(32)
+	state := randomToken(16)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := url.Parse(discovery.Metadata.AuthorizationEndpoint)
+	if err != nil {
+		return fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", credentials.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	query.Set("state", state)
+	query.Set("resource", credentials.Resource)
+	if len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+	authURL.RawQuery = query.Encode()
+
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = oauthLoginTimeout
+	}
+
+	codes := make(chan callbackResult, 1)
+	callbackServer := &http.Server{
+		Handler:           oauthCallbackHandler(state, codes),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go callbackServer.Serve(listener)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		callbackServer.Shutdown(ctx)
+	}()
+
+	if options.OpenURL != nil {
+		options.OpenURL(authURL.String())
+	}
+
+	var result callbackResult
+	select {
+	case result = <-codes:
+	case <-time.After(timeout):
+		return fmt.Errorf("no authorization received within %s", timeout)
+	}
+	if result.err != nil {
+		return result.err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {credentials.ClientID},
+		"code_verifier": {verifier},
+		"resource":      {credentials.Resource},
+	}
+	token, err := requestToken(credentials, form)
+	if err != nil {
+		return fmt.Errorf("token exchange failed: %w", err)
+	}
+	applyToken(credentials, token)
+
+	if err := m.storage.SaveMCPCredentials(server.ID, credentials); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+
+	// Reconnect with the new token. The lock is only held to swap the
+	// connection, not while connecting.
+	m.mu.Lock()
+	old, exists := m.processes[server.ID]
+	delete(m.processes, server.ID)
+	m.mu.Unlock()
+	if exists {
+		old.Stop()
+	}
+
+	process, tools, err := startServerProcess(server)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordServerTest(server, process, tools, err)
+	if err != nil {
+		return fmt.Errorf("the credentials were saved, but reconnecting to %s failed: %w", server.Name, err)
+	}
+
+	return nil
+}
+
+// ServerAuthorization returns the stored OAuth credentials of a server, or
+// nil if it has none
+func (m *Manager) ServerAuthorization(serverID string) (*types.MCPOAuthCredentials, error) {
+	if _, err := m.GetServer(serverID); err != nil {
+		return nil, err
+	}
+	return m.storage.LoadMCPCredentials(serverID)
+}
+
+// registerClient registers the agent as a public client (RFC 7591)
+func registerClient(endpoint, redirectURI string, credentials *types.MCPOAuthCredentials) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"client_name":                "syseng-agent",
+		"redirect_uris":              []string{redirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := oauthClient.Post(endpoint, "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return httpStatusError(endpoint, resp)
+	}
+
+	var registration struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024*1024)).Decode(&registration); err != nil {
+		return fmt.Errorf("invalid registration response: %w", err)
+	}
+	if registration.ClientID == "" {
+		return fmt.Errorf("registration response has no client_id")
+	}
+
+	credentials.ClientID = registration.ClientID
+	credentials.ClientSecret = registration.ClientSecret
+	return nil
+}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// oauthCallbackHandler receives the redirect from the authorization server
+// on the loopback listener
+func oauthCallbackHandler(state string, results chan<- callbackResult) http.Handler {
+	var once sync.Once
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != oauthCallbackPath {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		var result callbackResult
+		switch {
+		case query.Get("state") != state:
+			// Not the answer to our request; keep waiting for it
+			http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("code") == "":
+			result.err = fmt.Errorf("authorization response has no code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if result.err != nil {
+			fmt.Fprintf(w, "<html><body><h3>Authorization failed</h3><p>You can close this window.</p></body></html>")
+		} else {
+			fmt.Fprintf(w, "<html><body><h3>Authorization complete</h3><p>You can close this window and return to syseng-agent.</p></body></html>")
+		}
+
+		once.Do(func() {
+			results <- result
+		})
+	})
+}
+
+// randomToken returns a URL-safe random string of n random bytes
+func randomToken(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,508 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/storage"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// authStandIn is a local MCP server together with its authorization server.
+// The MCP endpoint is /mcp, the authorization server's issuer is /auth.
+type authStandIn struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	clients       map[string][]string // client ID to redirect URIs
+	codes         map[string]authCode
+	accessToken   string
+	refreshToken  string
+	issued        int
+	refreshes     int
+	registrations int
+	unavailable   bool // the MCP endpoint fails even with a valid token
+}
+
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	resource    string
+}
+
+func newAuthStandIn(t *testing.T) *authStandIn {
+	t.Helper()
+
+	standIn := &authStandIn{
+		clients: make(map[string][]string),
+		codes:   make(map[string]authCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", standIn.handleMCP)
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", standIn.handleResourceMetadata)
+	mux.HandleFunc("/.well-known/oauth-authorization-server/auth", standIn.handleServerMetadata)
+	mux.HandleFunc("/auth/register", standIn.handleRegister)
+	mux.HandleFunc("/auth/authorize", standIn.handleAuthorize)
+	mux.HandleFunc("/auth/token", standIn.handleToken)
+
+	standIn.Server = httptest.NewServer(mux)
+	t.Cleanup(standIn.Close)
+
+	return standIn
+}
+
+func (s *authStandIn) mcpURL() string {
+	return s.URL + "/mcp"
+}
+
+// issueToken hands out a new access and refresh token; the old ones stop
+// working
+func (s *authStandIn) issueToken(w http.ResponseWriter) {
+	s.issued++
+	s.accessToken = fmt.Sprintf("access-%d", s.issued)
+	s.refreshToken = fmt.Sprintf("refresh-%d", s.issued)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  s.accessToken,
+		"refresh_token": s.refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"scope":         "mcp",
+	})
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func (s *authStandIn) handleMCP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	authorized := s.accessToken != "" && r.Header.Get("Authorization") == "Bearer "+s.accessToken
+	unavailable := s.unavailable
+	s.mu.Unlock()
+
+	if !authorized {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer resource_metadata="%s/.well-known/oauth-protected-resource/mcp", error_description="token required"`, s.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if unavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		ID     *json.RawMessage `json:"id"`
+		Method string           `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if request.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var result interface{} = map[string]interface{}{}
+	switch request.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": SupportedProtocolVersions[0],
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "stand-in", "version": "1.0"},
+		}
+	case "tools/list":
+		result = map[string]interface{}{
+			"tools": []map[string]interface{}{{
+				"name":        "echo",
+				"description": "Echoes its input",
+				"inputSchema": map[string]interface{}{"type": "object"},
+			}},
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+}
+
+func (s *authStandIn) handleResourceMetadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"resource":              s.mcpURL(),
+		"authorization_servers": []string{s.URL + "/auth"},
+		"scopes_supported":      []string{"mcp"},
+	})
+}
+
+func (s *authStandIn) handleServerMetadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                           s.URL + "/auth",
+		"authorization_endpoint":           s.URL + "/auth/authorize",
+		"token_endpoint":                   s.URL + "/auth/token",
+		"registration_endpoint":            s.URL + "/auth/register",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+func (s *authStandIn) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var registration struct {
+		RedirectURIs            []string `json:"redirect_uris"`
+		TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil || len(registration.RedirectURIs) == 0 {
+		http.Error(w, "invalid registration", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.registrations++
+	clientID := fmt.Sprintf("client-%d", s.registrations)
+	s.clients[clientID] = registration.RedirectURIs
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"client_id": clientID})
+}
+
+// handleAuthorize approves every valid request at once, like a user who
+// clicks allow, and redirects back with a code
+func (s *authStandIn) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	redirectURIs, known := s.clients[query.Get("client_id")]
+	switch {
+	case !known:
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	case !containsString(redirectURIs, query.Get("redirect_uri")):
+		http.Error(w, "unregistered redirect_uri", http.StatusBadRequest)
+		return
+	case query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		http.Error(w, "PKCE with S256 required", http.StatusBadRequest)
+		return
+	case query.Get("state") == "":
+		http.Error(w, "state required", http.StatusBadRequest)
+		return
+	}
+
+	code := fmt.Sprintf("code-%d", len(s.codes)+1)
+	s.codes[code] = authCode{
+		clientID:    query.Get("client_id"),
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		resource:    query.Get("resource"),
+	}
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *authStandIn) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, ok := s.codes[r.PostForm.Get("code")]
+		if !ok {
+			tokenError(w, "invalid_grant", "unknown code")
+			return
+		}
+		delete(s.codes, r.PostForm.Get("code"))
+
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		switch {
+		case code.clientID != r.PostForm.Get("client_id") || code.redirectURI != r.PostForm.Get("redirect_uri"):
+			tokenError(w, "invalid_grant", "client or redirect_uri mismatch")
+		case base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge:
+			tokenError(w, "invalid_grant", "code_verifier does not match the challenge")
+		case code.resource != s.mcpURL() || r.PostForm.Get("resource") != s.mcpURL():
+			tokenError(w, "invalid_target", "wrong resource")
+		default:
+			s.issueToken(w)
+		}
+	case "refresh_token":
+		if r.PostForm.Get("refresh_token") != s.refreshToken || s.refreshToken == "" {
+			tokenError(w, "invalid_grant", "refresh token was already used")
+			return
+		}
+		s.refreshes++
+		s.issueToken(w)
+	default:
+		tokenError(w, "unsupported_grant_type", r.PostForm.Get("grant_type"))
+	}
+}
+
+// useTempHome points the storage used by the OAuth code at a fresh
+// directory
+func useTempHome(t *testing.T) *storage.Storage {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return storage.New("")
+}
+
+func newTestManager(store *storage.Storage, servers ...*types.MCPServer) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		servers:   make(map[string]*types.MCPServer),
+		processes: make(map[string]MCPProcessInterface),
+		storage:   store,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for _, server := range servers {
+		m.servers[server.ID] = server
+	}
+	return m
+}
+
+func TestDiscoverAuthorization(t *testing.T) {
+	standIn := newAuthStandIn(t)
+
+	discovery, err := discoverAuthorization(&types.MCPServer{Name: "remote", URL: standIn.mcpURL(), Transport: "http"})
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+
+	if discovery.Resource != standIn.mcpURL() {
+		t.Errorf("resource = %s, want %s", discovery.Resource, standIn.mcpURL())
+	}
+	if discovery.AuthorizationServer != standIn.URL+"/auth" {
+		t.Errorf("authorization server = %s", discovery.AuthorizationServer)
+	}
+	if discovery.Metadata.TokenEndpoint != standIn.URL+"/auth/token" || discovery.Metadata.RegistrationEndpoint != standIn.URL+"/auth/register" {
+		t.Errorf("endpoints = %+v", discovery.Metadata)
+	}
+	if strings.Join(discovery.Scopes, " ") != "mcp" {
+		t.Errorf("scopes = %v, want [mcp]", discovery.Scopes)
+	}
+}
+
+func TestLoginWithDynamicRegistrationAndPKCE(t *testing.T) {
+	store := useTempHome(t)
+	standIn := newAuthStandIn(t)
+
+	server := &types.MCPServer{ID: "remote-id", Name: "remote", URL: standIn.mcpURL(), Transport: "http"}
+	m := newTestManager(store, server)
+	defer m.Shutdown()
+
+	// Stands in for the browser: the authorization endpoint redirects to
+	// the loopback listener of Login
+	var openErr error
+	options := LoginOptions{
+		Timeout: 10 * time.Second,
+		OpenURL: func(authURL string) {
+			resp, err := http.Get(authURL)
+			if err != nil {
+				openErr = err
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				openErr = fmt.Errorf("callback returned %s", resp.Status)
+			}
+		},
+	}
+
+	if err := m.Login(server.ID, options); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if openErr != nil {
+		t.Fatalf("authorization: %v", openErr)
+	}
+
+	credentials, err := store.LoadMCPCredentials(server.ID)
+	if err != nil || credentials == nil {
+		t.Fatalf("stored credentials = %v, %v", credentials, err)
+	}
+	if credentials.ClientID != "client-1" || credentials.AccessToken != "access-1" || credentials.RefreshToken != "refresh-1" {
+		t.Errorf("credentials = %+v", credentials)
+	}
+	if credentials.TokenEndpoint != standIn.URL+"/auth/token" || credentials.Resource != standIn.mcpURL() {
+		t.Errorf("endpoints = %s, %s", credentials.TokenEndpoint, credentials.Resource)
+	}
+	if !strings.HasPrefix(credentials.RedirectURI, "http://127.0.0.1:") {
+		t.Errorf("redirect URI = %s", credentials.RedirectURI)
+	}
+
+	// The server was reconnected with the new token
+	if server.Status != "connected" {
+		t.Errorf("status = %s, want connected", server.Status)
+	}
+	if len(server.Tools) != 1 || server.Tools[0].Name != "echo" {
+		t.Errorf("tools = %+v", server.Tools)
+	}
+
+	// A second login reuses the registration
+	if err := m.Login(server.ID, options); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if standIn.registrations != 1 {
+		t.Errorf("registered %d clients, want 1", standIn.registrations)
+	}
+}
+
+func TestLoginReportsFailedReconnect(t *testing.T) {
+	store := useTempHome(t)
+	standIn := newAuthStandIn(t)
+	standIn.unavailable = true
+
+	server := &types.MCPServer{ID: "remote-id", Name: "remote", URL: standIn.mcpURL(), Transport: "http"}
+	m := newTestManager(store, server)
+	defer m.Shutdown()
+
+	options := LoginOptions{
+		Timeout: 10 * time.Second,
+		OpenURL: func(authURL string) {
+			if resp, err := http.Get(authURL); err == nil {
+				resp.Body.Close()
+			}
+		},
+	}
+
+	err := m.Login(server.ID, options)
+	if err == nil || !strings.Contains(err.Error(), "credentials were saved") {
+		t.Fatalf("login error = %v, want the failed reconnect", err)
+	}
+	if credentials, err := store.LoadMCPCredentials(server.ID); err != nil || credentials == nil || credentials.AccessToken != "access-1" {
+		t.Errorf("stored credentials = %+v, %v", credentials, err)
+	}
+	if server.Status != "error" {
+		t.Errorf("status = %s, want error", server.Status)
+	}
+}
+
+func TestHTTPClientRefreshesExpiredToken(t *testing.T) {
+	store := useTempHome(t)
+	standIn := newAuthStandIn(t)
+	standIn.accessToken, standIn.refreshToken, standIn.issued = "access-1", "refresh-1", 1
+
+	server := &types.MCPServer{ID: "remote-id", Name: "remote", URL: standIn.mcpURL(), Transport: "http"}
+	if err := store.SaveMCPCredentials(server.ID, &types.MCPOAuthCredentials{
+		ClientID:      "client-1",
+		TokenEndpoint: standIn.URL + "/auth/token",
+		Resource:      standIn.mcpURL(),
+		AccessToken:   "access-1",
+		RefreshToken:  "refresh-1",
+		ExpiresAt:     time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newHTTPClient(server)
+	ping := func() (*http.Response, error) {
+		return client.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	}
+
+	resp, err := ping()
+	if err != nil {
+		t.Fatalf("request with expired token: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %s, want 200", resp.Status)
+	}
+	if standIn.refreshes != 1 {
+		t.Errorf("refreshed %d times, want 1", standIn.refreshes)
+	}
+
+	stored, err := store.LoadMCPCredentials(server.ID)
+	if err != nil || stored.AccessToken != "access-2" || stored.RefreshToken != "refresh-2" {
+		t.Fatalf("stored credentials = %+v, %v", stored, err)
+	}
+
+	// The token is kept in memory, the credentials file is not read again
+	credentialsDir := filepath.Join(os.Getenv("HOME"), ".syseng-agent")
+	if err := os.Chmod(credentialsDir, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(credentialsDir, 0755)
+
+	resp, err = ping()
+	if err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || standIn.refreshes != 1 {
+		t.Errorf("second request: status %s after %d refreshes", resp.Status, standIn.refreshes)
+	}
+}
+
+func TestHTTPClientRefreshesRevokedToken(t *testing.T) {
+	store := useTempHome(t)
+	standIn := newAuthStandIn(t)
+
+	// The stand-in only accepts a token it issues on refresh
+	standIn.accessToken, standIn.refreshToken, standIn.issued = "access-1", "refresh-1", 1
+	server := &types.MCPServer{ID: "remote-id", Name: "remote", URL: standIn.mcpURL(), Transport: "http"}
+	if err := store.SaveMCPCredentials(server.ID, &types.MCPOAuthCredentials{
+		ClientID:      "client-1",
+		TokenEndpoint: standIn.URL + "/auth/token",
+		AccessToken:   "revoked",
+		RefreshToken:  "refresh-1",
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newHTTPClient(server)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || standIn.refreshes != 1 {
+		t.Errorf("status %s after %d refreshes, want 200 after 1", resp.Status, standIn.refreshes)
+	}
+}
+
+func TestHTTPClientRequiresLogin(t *testing.T) {
+	useTempHome(t)
+	standIn := newAuthStandIn(t)
+
+	server := &types.MCPServer{ID: "remote-id", Name: "remote", URL: standIn.mcpURL(), Transport: "http"}
+	_, err := newHTTPClient(server).Post(server.URL, "application/json", strings.NewReader(`{}`))
+	if !IsAuthorizationRequired(err) {
+		t.Fatalf("error = %v, want an authorization required error", err)
+	}
+	if !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("error = %v, want the reason", err)
+	}
+}
@@ -1,12 +1,9 @@
 package mcp
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os/exec"
 	"strings"
 	"sync"
 	"time"
@@ -15,18 +12,36 @@ import (
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/utils"
 )
 
+// SupportedProtocolVersions lists the MCP protocol versions this client
+// understands, newest first. The newest version is offered during
+// initialization and the server may answer with any of them.
+var SupportedProtocolVersions = []string{
+	"2025-06-18",
+	"2025-03-26",
+	"2024-11-05",
+}
+
+// MCPProcess is a client session with one MCP server. The messages are
+// carried by a transport chosen from the server's transport type.
 type MCPProcess struct {
-	server    *types.MCPServer
-	cmd       *exec.Cmd
-	stdin     io.WriteCloser
-	stdout    io.ReadCloser
-	stderr    io.ReadCloser
-	tools     map[string]Tool
-	responses map[int]chan *MCPResponse
-	mu        sync.RWMutex
-	ctx       context.Context
-	cancel    context.CancelFunc
-	nextID    int
+	server       *types.MCPServer
+	transport    messageTransport
+	transportErr error
+	tools        map[string]Tool
+	responses    map[int]chan *MCPResponse
+	initResult   *InitializeResult
+	mu           sync.RWMutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+	nextID       int
+
+	// Lines that were not JSON-RPC messages, kept for mcp doctor
+	nonJSONLines int
+
+	// Asks the user for input the server requests during a tool call
+	elicitationHandler  ElicitationHandler
+	elicitationCalls    sync.Mutex // held by the call elicitationHandler belongs to
+	pendingElicitations int
 }
 
 type Tool struct {
@@ -55,72 +70,175 @@ type MCPError struct {
 }
 
 type InitializeParams struct {
-	ProtocolVersion string            `json:"protocolVersion"`
-	Capabilities    map[string]bool   `json:"capabilities"`
-	ClientInfo      map[string]string `json:"clientInfo"`
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      map[string]string      `json:"clientInfo"`
 }
 
-type ToolCallParams struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments,omitempty"`
+// InitializeResult holds what the server reported during initialization
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	ServerInfo      types.MCPServerInfo    `json:"serverInfo"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	Instructions    string                 `json:"instructions,omitempty"`
 }
 
-func NewMCPProcess(server *types.MCPServer) *MCPProcess {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &MCPProcess{
-		server:    server,
-		tools:     make(map[string]Tool),
-		responses: make(map[int]chan *MCPResponse),
-		ctx:       ctx,
-		cancel:    cancel,
-		nextID:    1,
+// HasCapability reports whether the server advertised the given capability
+func (r *InitializeResult) HasCapability(name string) bool {
+	if r == nil {
+		return false
 	}
+	_, ok := r.Capabilities[name]
+	return ok
 }
 
-func (p *MCPProcess) Start() error {
-	if p.server.Transport != "stdio" {
-		return fmt.Errorf("only stdio transport is supported")
-	}
+// Resource describes a resource exposed by an MCP server
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
 
-	// Start the MCP server process using the server URL
-	args := strings.Fields(p.server.URL)
-	if len(args) == 0 {
-		return fmt.Errorf("invalid server URL: %s", p.server.URL)
-	}
-	p.cmd = exec.CommandContext(p.ctx, args[0], args[1:]...)
+// Prompt describes a prompt template exposed by an MCP server
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
 
-	var err error
-	p.stdin, err = p.cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
-	}
+// PromptArgument describes an argument accepted by a prompt template
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
 
-	p.stdout, err = p.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+// Completion reference types understood by completion/complete. RefTool is
+// handled by the client from the tool's input schema.
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+	RefTool     = "ref/tool"
+)
+
+// CompletionRef identifies the prompt or resource template whose argument
+// is being completed
+type CompletionRef struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// CompletionResult holds the suggested values for an argument
+type CompletionResult struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt template
+type PromptMessage struct {
+	Role    string `json:"role"`
+	Content struct {
+		Type string `json:"type"`
+		Text string `json:"text,omitempty"`
+	} `json:"content"`
+}
+
+// ResourceContents holds the contents of a resource read from a server
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ExposedToolName returns the name a server's tool is exposed to the model
+// under, which keeps tools of different servers apart
+func ExposedToolName(serverName, toolName string) string {
+	cleanServerName := strings.ReplaceAll(serverName, " ", "_")
+	cleanServerName = strings.ReplaceAll(cleanServerName, "-", "_")
+	return fmt.Sprintf("%s_%s", cleanServerName, toolName)
+}
+
+// isSupportedProtocolVersion reports whether the version is one this client speaks
+func isSupportedProtocolVersion(version string) bool {
+	for _, supported := range SupportedProtocolVersions {
+		if version == supported {
+			return true
+		}
 	}
+	return false
+}
 
-	p.stderr, err = p.cmd.StderrPipe()
+// decodeResult converts a generic JSON-RPC result into a typed struct
+func decodeResult(result interface{}, target interface{}) error {
+	data, err := json.Marshal(result)
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return err
 	}
+	return json.Unmarshal(data, target)
+}
 
-	if err := p.cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start MCP server: %w", err)
+type ToolCallParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+func NewMCPProcess(server *types.MCPServer) *MCPProcess {
+	ctx, cancel := context.WithCancel(context.Background())
+	transport, err := newTransport(server)
+	return &MCPProcess{
+		server:       server,
+		transport:    transport,
+		transportErr: err,
+		tools:        make(map[string]Tool),
+		responses:    make(map[int]chan *MCPResponse),
+		ctx:          ctx,
+		cancel:       cancel,
+		nextID:       1,
 	}
+}
 
-	// Start output readers
-	go p.readOutput()
-	go p.readErrors()
+func (p *MCPProcess) Start() error {
+	if err := p.connect(); err != nil {
+		return err
+	}
 
 	// Initialize the MCP connection
 	if err := p.initialize(); err != nil {
 		return fmt.Errorf("failed to initialize MCP connection: %w", err)
 	}
 
-	// Discover available tools
-	if err := p.discoverTools(); err != nil {
-		return fmt.Errorf("failed to discover tools: %w", err)
+	return p.configure()
+}
+
+// connect opens the transport to the server
+func (p *MCPProcess) connect() error {
+	if p.transportErr != nil {
+		return p.transportErr
+	}
+	return p.transport.open(p)
+}
+
+// configure applies settings that depend on the server's capabilities and
+// discovers its tools
+func (p *MCPProcess) configure() error {
+	// Servers that advertise logging would otherwise send every debug
+	// message as a notification
+	if p.initResult.HasCapability("logging") {
+		if err := p.setLogLevel("warning"); err != nil {
+			debugPrint("Failed to set log level for %s: %v\n", p.server.Name, err)
+		}
+	}
+
+	// Discover available tools. Servers that report no capabilities at all
+	// predate capability negotiation, so tools are still requested from them.
+	if p.initResult.HasCapability("tools") || len(p.initResult.Capabilities) == 0 {
+		if err := p.discoverTools(); err != nil {
+			return fmt.Errorf("failed to discover tools: %w", err)
+		}
 	}
 
 	return nil
@@ -129,18 +247,32 @@ func (p *MCPProcess) Start() error {
 func (p *MCPProcess) Stop() error {
 	p.cancel()
 
-	if p.cmd != nil && p.cmd.Process != nil {
-		return p.cmd.Process.Kill()
+	if p.transport != nil {
+		return p.transport.close()
 	}
 
 	return nil
 }
 
 func (p *MCPProcess) initialize() error {
+	result, err := p.requestInitialize()
+	if err != nil {
+		return err
+	}
+
+	if err := checkProtocolVersion(result.ProtocolVersion); err != nil {
+		return err
+	}
+
+	return p.completeInitialize(result)
+}
+
+// requestInitialize sends the initialize request and decodes the result
+func (p *MCPProcess) requestInitialize() (*InitializeResult, error) {
 	initParams := InitializeParams{
-		ProtocolVersion: "2024-11-05",
-		Capabilities: map[string]bool{
-			"tools": true,
+		ProtocolVersion: SupportedProtocolVersions[0],
+		Capabilities: map[string]interface{}{
+			"elicitation": map[string]interface{}{},
 		},
 		ClientInfo: map[string]string{
 			"name":    "syseng-agent",
@@ -150,19 +282,46 @@ func (p *MCPProcess) initialize() error {
 
 	req := MCPRequest{
 		JSONRPC: "2.0",
-		ID:      1,
 		Method:  "initialize",
 		Params:  initParams,
 	}
 
 	resp, err := p.sendRequest(req)
 	if err != nil {
-		return fmt.Errorf("initialization failed: %w", err)
+		return nil, fmt.Errorf("initialization failed: %w", err)
 	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("initialization error: %s", resp.Error.Message)
+		return nil, fmt.Errorf("initialization error: %s", resp.Error.Message)
+	}
+
+	var result InitializeResult
+	if err := decodeResult(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid initialize result: %w", err)
+	}
+
+	return &result, nil
+}
+
+// checkProtocolVersion returns an error if the server answered with a
+// protocol version this client does not speak
+func checkProtocolVersion(version string) error {
+	if !isSupportedProtocolVersion(version) {
+		return fmt.Errorf("server requested unsupported protocol version %q (supported: %s)",
+			version, strings.Join(SupportedProtocolVersions, ", "))
 	}
+	return nil
+}
+
+// completeInitialize records the initialize result and tells the server the
+// client is ready
+func (p *MCPProcess) completeInitialize(result *InitializeResult) error {
+	p.mu.Lock()
+	p.initResult = result
+	p.mu.Unlock()
+
+	debugPrint("Negotiated protocol version %s with %s (%s %s)\n",
+		result.ProtocolVersion, p.server.Name, result.ServerInfo.Name, result.ServerInfo.Version)
 
 	// Send initialized notification
 	notification := MCPRequest{
@@ -176,7 +335,6 @@ func (p *MCPProcess) initialize() error {
 func (p *MCPProcess) discoverTools() error {
 	req := MCPRequest{
 		JSONRPC: "2.0",
-		ID:      2,
 		Method:  "tools/list",
 	}
 
@@ -193,6 +351,8 @@ func (p *MCPProcess) discoverTools() error {
 	if result, ok := resp.Result.(map[string]interface{}); ok {
 		if tools, ok := result["tools"].([]interface{}); ok {
 			p.mu.Lock()
+			// Replace rather than merge, the list is rediscovered after a reconnect
+			p.tools = make(map[string]Tool)
 			for _, toolData := range tools {
 				if toolMap, ok := toolData.(map[string]interface{}); ok {
 					tool := Tool{
@@ -226,7 +386,6 @@ func (p *MCPProcess) CallTool(name string, arguments map[string]interface{}) (in
 
 	req := MCPRequest{
 		JSONRPC: "2.0",
-		ID:      int(time.Now().Unix()),
 		Method:  "tools/call",
 		Params:  params,
 	}
@@ -243,6 +402,214 @@ func (p *MCPProcess) CallTool(name string, arguments map[string]interface{}) (in
 	return resp.Result, nil
 }
 
+// Ping checks that the server still answers requests
+func (p *MCPProcess) Ping() error {
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "ping",
+	})
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("ping error: %s", resp.Error.Message)
+	}
+
+	return nil
+}
+
+// GetInitializeResult returns what the server reported during initialization
+func (p *MCPProcess) GetInitializeResult() *InitializeResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.initResult
+}
+
+// requireCapability returns an error if the server did not advertise the capability
+func (p *MCPProcess) requireCapability(name string) error {
+	if !p.GetInitializeResult().HasCapability(name) {
+		return fmt.Errorf("server %s does not support %s", p.server.Name, name)
+	}
+	return nil
+}
+
+// setLogLevel asks the server to only send log notifications at or above level
+func (p *MCPProcess) setLogLevel(level string) error {
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "logging/setLevel",
+		Params:  map[string]string{"level": level},
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("logging/setLevel error: %s", resp.Error.Message)
+	}
+
+	return nil
+}
+
+// ListResources returns the resources exposed by the server
+func (p *MCPProcess) ListResources() ([]Resource, error) {
+	if err := p.requireCapability("resources"); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "resources/list",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resources/list failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("resources/list error: %s", resp.Error.Message)
+	}
+
+	var result struct {
+		Resources []Resource `json:"resources"`
+	}
+	if err := decodeResult(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid resources/list result: %w", err)
+	}
+
+	return result.Resources, nil
+}
+
+// ListPrompts returns the prompt templates exposed by the server
+func (p *MCPProcess) ListPrompts() ([]Prompt, error) {
+	if err := p.requireCapability("prompts"); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "prompts/list",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("prompts/list failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("prompts/list error: %s", resp.Error.Message)
+	}
+
+	var result struct {
+		Prompts []Prompt `json:"prompts"`
+	}
+	if err := decodeResult(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid prompts/list result: %w", err)
+	}
+
+	return result.Prompts, nil
+}
+
+// Complete asks the server for completions of a prompt or resource template
+// argument. Arguments already filled in are passed as context.
+func (p *MCPProcess) Complete(ref CompletionRef, argument, value string, context map[string]string) (*CompletionResult, error) {
+	if err := p.requireCapability("completions"); err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"ref": ref,
+		"argument": map[string]string{
+			"name":  argument,
+			"value": value,
+		},
+	}
+	if len(context) > 0 {
+		params["context"] = map[string]interface{}{"arguments": context}
+	}
+
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "completion/complete",
+		Params:  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("completion/complete failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("completion/complete error: %s", resp.Error.Message)
+	}
+
+	var result struct {
+		Completion CompletionResult `json:"completion"`
+	}
+	if err := decodeResult(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid completion/complete result: %w", err)
+	}
+
+	return &result.Completion, nil
+}
+
+// GetPrompt renders a prompt template with the given arguments
+func (p *MCPProcess) GetPrompt(name string, arguments map[string]string) ([]PromptMessage, error) {
+	if err := p.requireCapability("prompts"); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "prompts/get",
+		Params: map[string]interface{}{
+			"name":      name,
+			"arguments": arguments,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("prompts/get failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("prompts/get error: %s", resp.Error.Message)
+	}
+
+	var result struct {
+		Messages []PromptMessage `json:"messages"`
+	}
+	if err := decodeResult(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid prompts/get result: %w", err)
+	}
+
+	return result.Messages, nil
+}
+
+// ReadResource returns the contents of a resource
+func (p *MCPProcess) ReadResource(uri string) ([]ResourceContents, error) {
+	if err := p.requireCapability("resources"); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.sendRequest(MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "resources/read",
+		Params:  map[string]string{"uri": uri},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resources/read failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("resources/read error: %s", resp.Error.Message)
+	}
+
+	var result struct {
+		Contents []ResourceContents `json:"contents"`
+	}
+	if err := decodeResult(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid resources/read result: %w", err)
+	}
+
+	return result.Contents, nil
+}
+
 func (p *MCPProcess) GetTools() []Tool {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -279,26 +646,39 @@ func (p *MCPProcess) sendRequest(req MCPRequest) (*MCPResponse, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	data = append(data, '\n')
-
-	if _, err := p.stdin.Write(data); err != nil {
+	if err := p.transport.send(data); err != nil {
 		p.mu.Lock()
 		delete(p.responses, req.ID)
 		p.mu.Unlock()
 		return nil, fmt.Errorf("failed to write request: %w", err)
 	}
 
-	// Wait for response with timeout
-	select {
-	case resp := <-respCh:
-		return resp, nil
-	case <-time.After(10 * time.Second):
-		p.mu.Lock()
-		delete(p.responses, req.ID)
-		p.mu.Unlock()
-		return nil, fmt.Errorf("request timeout")
-	case <-p.ctx.Done():
-		return nil, fmt.Errorf("process cancelled")
+	// Wait for response with timeout. The timeout is extended while the
+	// user answers an elicitation the server sent in the meantime.
+	timeout := time.NewTimer(10 * time.Second)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case resp := <-respCh:
+			return resp, nil
+		case <-timeout.C:
+			if p.awaitingInput() {
+				timeout.Reset(10 * time.Second)
+				continue
+			}
+			p.mu.Lock()
+			delete(p.responses, req.ID)
+			p.mu.Unlock()
+			return nil, fmt.Errorf("request timeout")
+		case <-p.transport.done():
+			p.mu.Lock()
+			delete(p.responses, req.ID)
+			p.mu.Unlock()
+			return nil, fmt.Errorf("server closed its output before responding")
+		case <-p.ctx.Done():
+			return nil, fmt.Errorf("process cancelled")
+		}
 	}
 }
 
@@ -308,63 +688,80 @@ func (p *MCPProcess) sendNotification(req MCPRequest) error {
 		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
 
-	data = append(data, '\n')
-
-	if _, err := p.stdin.Write(data); err != nil {
+	if err := p.transport.send(data); err != nil {
 		return fmt.Errorf("failed to write notification: %w", err)
 	}
 
 	return nil
 }
 
-func (p *MCPProcess) readOutput() {
-	scanner := bufio.NewScanner(p.stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
+// handleMessage dispatches a message received from the server
+func (p *MCPProcess) handleMessage(data []byte) {
+	var message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		// Anything else corrupts the JSON-RPC stream, usually log output
+		// that belongs on stderr
+		p.mu.Lock()
+		p.nonJSONLines++
+		p.mu.Unlock()
+		debugPrint("Non JSON-RPC output from %s: %s\n", p.server.Name, data)
+		return
+	}
+
+	// Requests from the server, such as elicitation/create, are answered in
+	// the background so that the transport keeps reading while the user
+	// fills in a form. Notifications (no ID) are ignored.
+	if message.Method != "" {
+		if len(message.ID) > 0 && string(message.ID) != "null" {
+			go p.handleServerRequest(message.ID, message.Method, message.Params)
 		}
+		return
+	}
 
-		// Parse JSON-RPC responses
-		var resp MCPResponse
-		if err := json.Unmarshal([]byte(line), &resp); err == nil {
-			// Handle response with ID (request response)
-			if resp.ID != 0 {
-				p.mu.Lock()
-				if ch, exists := p.responses[resp.ID]; exists {
-					select {
-					case ch <- &resp:
-					default:
-						// Channel full, skip
-					}
-					delete(p.responses, resp.ID)
-				}
-				p.mu.Unlock()
+	// Parse JSON-RPC responses
+	var resp MCPResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		debugPrint("Invalid JSON-RPC response from %s: %v\n", p.server.Name, err)
+		return
+	}
+
+	// Handle response with ID (request response)
+	if resp.ID != 0 {
+		p.mu.Lock()
+		if ch, exists := p.responses[resp.ID]; exists {
+			select {
+			case ch <- &resp:
+			default:
+				// Channel full, skip
 			}
-			// Ignore notifications (no ID)
+			delete(p.responses, resp.ID)
 		}
+		p.mu.Unlock()
 	}
 }
 
-func (p *MCPProcess) readErrors() {
-	scanner := bufio.NewScanner(p.stderr)
-	for scanner.Scan() {
-		line := scanner.Text()
+// connectionLost fails the requests still waiting for a response
+func (p *MCPProcess) connectionLost(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-		// Filter out normal loading messages from desktop-commander
-		if strings.Contains(line, "Loading server.ts") ||
-			strings.Contains(line, "Setting up request handlers") ||
-			strings.Contains(line, "initialized") ||
-			strings.Contains(line, "Loading configuration") ||
-			strings.Contains(line, "Configuration loaded") ||
-			strings.Contains(line, "Connecting server") ||
-			strings.Contains(line, "Server connected") ||
-			strings.Contains(line, "Generating tools list") {
-			continue // Skip normal loading messages
+	for id, ch := range p.responses {
+		select {
+		case ch <- &MCPResponse{JSONRPC: "2.0", ID: id, Error: &MCPError{Code: -32000, Message: "connection lost: " + err.Error()}}:
+		default:
 		}
-
-		// Only show actual errors
-		fmt.Printf("MCP Error: %s\n", line)
+		delete(p.responses, id)
 	}
 }
 
+// reconnected starts a new MCP session on a re-established connection
+func (p *MCPProcess) reconnected() error {
+	if err := p.initialize(); err != nil {
+		return fmt.Errorf("failed to initialize MCP connection: %w", err)
+	}
+	return p.configure()
+}
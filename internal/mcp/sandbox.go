@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// baseSandboxEnv lists the variables every sandboxed server inherits in
+// addition to its allowlist, so that commands can be found and locale
+// settings work
+var baseSandboxEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_*", "TZ", "TMPDIR", "TERM"}
+
+// configureSandbox applies the server's sandbox to the command before it is
+// started. Every server runs in its own process group so that Stop() can kill
+// the whole process tree.
+func configureSandbox(cmd *exec.Cmd, sandbox *types.MCPSandbox) error {
+	if err := configureProcess(cmd, sandbox); err != nil {
+		return err
+	}
+
+	if sandbox == nil {
+		return nil
+	}
+
+	for _, pattern := range sandbox.EnvAllow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid env allow pattern %q: %w", pattern, err)
+		}
+	}
+	cmd.Env = sandboxEnv(sandbox, os.Environ())
+
+	// A server running as another user gets that user's home directory
+	if sandbox.UID != nil && int(*sandbox.UID) != os.Getuid() {
+		if _, set := sandbox.Env["HOME"]; !set {
+			home, err := userHome(*sandbox.UID)
+			if err != nil {
+				return err
+			}
+			cmd.Env = setEnv(cmd.Env, "HOME", home)
+		}
+	}
+
+	// Sandboxed servers never inherit our working directory
+	cmd.Dir = sandbox.WorkDir
+	if cmd.Dir == "" {
+		cmd.Dir = os.TempDir()
+	}
+	if info, err := os.Stat(cmd.Dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("working directory %s does not exist", cmd.Dir)
+	}
+
+	// Limits are set before the server's command runs
+	if sandbox.HasLimits() {
+		if err := applyResourceLimits(cmd, sandbox); err != nil {
+			return fmt.Errorf("failed to apply resource limits: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// userHome returns the home directory of the user with the given uid
+func userHome(uid uint32) (string, error) {
+	account, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up uid %d: %w", uid, err)
+	}
+	return account.HomeDir, nil
+}
+
+// setEnv sets a variable in env, replacing an existing value
+func setEnv(env []string, name, value string) []string {
+	for i, entry := range env {
+		if strings.HasPrefix(entry, name+"=") {
+			env[i] = name + "=" + value
+			return env
+		}
+	}
+	return append(env, name+"="+value)
+}
+
+// sandboxEnv returns the allowed subset of environ plus the variables set
+// explicitly for the server
+func sandboxEnv(sandbox *types.MCPSandbox, environ []string) []string {
+	allowed := append(append([]string{}, baseSandboxEnv...), sandbox.EnvAllow...)
+
+	var env []string
+	for _, entry := range environ {
+		name, _, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		if _, overridden := sandbox.Env[name]; overridden {
+			continue
+		}
+		for _, pattern := range allowed {
+			if matched, _ := path.Match(pattern, name); matched {
+				env = append(env, entry)
+				break
+			}
+		}
+	}
+
+	for name, value := range sandbox.Env {
+		env = append(env, name+"="+value)
+	}
+
+	return env
+}
@@ -0,0 +1,101 @@
+//go:build linux
+
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+	"golang.org/x/sys/unix"
+)
+
+// sandboxExecArg is the first argument the agent re-executes itself with to
+// set a server's resource limits before exec'ing the server's command. The
+// server, and every process it starts, therefore never runs without them.
+const sandboxExecArg = "__mcp-sandbox-exec"
+
+// resourceLimits maps the limit names passed to the re-executed agent to
+// their rlimit resources
+var resourceLimits = map[string]int{
+	"cpu":        unix.RLIMIT_CPU,
+	"memory":     unix.RLIMIT_AS,
+	"open files": unix.RLIMIT_NOFILE,
+	"processes":  unix.RLIMIT_NPROC,
+}
+
+func init() {
+	if len(os.Args) > 1 && os.Args[1] == sandboxExecArg {
+		os.Exit(runSandboxExec(os.Args[2:]))
+	}
+}
+
+// applyResourceLimits makes cmd start through the agent's own executable,
+// which sets the sandbox's rlimits and then replaces itself with the
+// server. The process limit counts all processes of the server's user, so it
+// works best together with a dedicated uid.
+func applyResourceLimits(cmd *exec.Cmd, sandbox *types.MCPSandbox) error {
+	if cmd.Err != nil {
+		// Start reports the missing command
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the agent executable: %w", err)
+	}
+
+	args := []string{self, sandboxExecArg}
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"cpu", sandbox.CPUSeconds},
+		{"memory", sandbox.MemoryMB * 1024 * 1024},
+		{"open files", sandbox.OpenFiles},
+		{"processes", sandbox.Processes},
+	} {
+		if limit.value > 0 {
+			args = append(args, fmt.Sprintf("%s=%d", limit.name, limit.value))
+		}
+	}
+	args = append(args, "--", cmd.Path)
+	args = append(args, cmd.Args...)
+
+	cmd.Path = self
+	cmd.Args = args
+	return nil
+}
+
+// runSandboxExec sets the limits given as name=value arguments and execs the
+// command following "--": its path and then its arguments, starting with
+// its name. It only returns if that fails.
+func runSandboxExec(args []string) int {
+	for len(args) > 0 && args[0] != "--" {
+		name, value, _ := strings.Cut(args[0], "=")
+		resource, known := resourceLimits[name]
+		limit, err := strconv.ParseUint(value, 10, 64)
+		if !known || err != nil {
+			fmt.Fprintf(os.Stderr, "mcp sandbox: invalid limit %q\n", args[0])
+			return 126
+		}
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: limit, Max: limit}); err != nil {
+			fmt.Fprintf(os.Stderr, "mcp sandbox: failed to set %s limit: %v\n", name, err)
+			return 126
+		}
+		args = args[1:]
+	}
+
+	if len(args) < 3 {
+		fmt.Fprintln(os.Stderr, "mcp sandbox: missing command")
+		return 126
+	}
+
+	err := syscall.Exec(args[1], args[2:], os.Environ())
+	fmt.Fprintf(os.Stderr, "mcp sandbox: failed to start %s: %v\n", args[1], err)
+	return 127
+}
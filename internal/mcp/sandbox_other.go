@@ -0,0 +1,17 @@
+//go:build !linux
+
+package mcp
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// applyResourceLimits fails on platforms where limits are not supported
+// rather than silently running the server unrestricted
+func applyResourceLimits(cmd *exec.Cmd, sandbox *types.MCPSandbox) error {
+	return fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+}
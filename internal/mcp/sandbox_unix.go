@@ -0,0 +1,47 @@
+//go:build !windows
+
+package mcp
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// configureProcess starts the server in a new process group and, if
+// requested, as a different user
+func configureProcess(cmd *exec.Cmd, sandbox *types.MCPSandbox) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+
+	if sandbox != nil && (sandbox.UID != nil || sandbox.GID != nil) {
+		credential := &syscall.Credential{
+			Uid: uint32(os.Getuid()),
+			Gid: uint32(os.Getgid()),
+		}
+		if sandbox.UID != nil {
+			credential.Uid = *sandbox.UID
+		}
+		if sandbox.GID != nil {
+			credential.Gid = *sandbox.GID
+		}
+		cmd.SysProcAttr.Credential = credential
+	}
+
+	return nil
+}
+
+// killProcessGroup kills the server and every process it started
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
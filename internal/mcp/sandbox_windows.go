@@ -0,0 +1,26 @@
+//go:build windows
+
+package mcp
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// configureProcess rejects settings that cannot be applied on Windows
+func configureProcess(cmd *exec.Cmd, sandbox *types.MCPSandbox) error {
+	if sandbox != nil && (sandbox.UID != nil || sandbox.GID != nil) {
+		return fmt.Errorf("running servers as a different user is not supported on windows")
+	}
+	return nil
+}
+
+// killProcessGroup kills the server process
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
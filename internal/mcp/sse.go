@@ -0,0 +1,178 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// sseEndpointTimeout bounds the wait for the endpoint event
+const sseEndpointTimeout = 10 * time.Second
+
+// sseTransport speaks the HTTP+SSE transport of protocol version
+// 2024-11-05: the server sends messages on a long-lived event stream and
+// announces, in its first event, the endpoint messages are POSTed to
+type sseTransport struct {
+	server    *types.MCPServer
+	client    *http.Client
+	endpoint  string
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newSSETransport(server *types.MCPServer) *sseTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sseTransport{
+		server: server,
+		client: newHTTPClient(server),
+		ctx:    ctx,
+		cancel: cancel,
+		closed: make(chan struct{}),
+	}
+}
+
+func (t *sseTransport) open(handler transportHandler) error {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.server.URL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid server URL %s: %w", t.server.URL, err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if authErr := authorizationError(err); authErr != nil {
+			return authErr
+		}
+		return fmt.Errorf("failed to connect to %s: %w", t.server.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return httpStatusError(t.server.URL, resp)
+	}
+
+	endpoints := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		defer t.finish()
+
+		err := readSSE(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				select {
+				case endpoints <- data:
+				default:
+				}
+			case "", "message":
+				handler.handleMessage([]byte(data))
+			}
+		})
+		if err != nil && t.ctx.Err() == nil {
+			debugPrint("Event stream from %s failed: %v\n", t.server.Name, err)
+		}
+	}()
+
+	select {
+	case endpoint := <-endpoints:
+		resolved, err := t.resolveEndpoint(endpoint)
+		if err != nil {
+			t.close()
+			return err
+		}
+		t.mu.Lock()
+		t.endpoint = resolved
+		t.mu.Unlock()
+		return nil
+	case <-t.closed:
+		return fmt.Errorf("%s closed the event stream before announcing its endpoint", t.server.URL)
+	case <-time.After(sseEndpointTimeout):
+		t.close()
+		return fmt.Errorf("%s did not announce its endpoint within %s", t.server.URL, sseEndpointTimeout)
+	}
+}
+
+// resolveEndpoint resolves the announced endpoint against the stream URL.
+// Messages must not be sent to a different origin.
+func (t *sseTransport) resolveEndpoint(endpoint string) (string, error) {
+	base, err := url.Parse(t.server.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %s: %w", t.server.URL, err)
+	}
+
+	resolved, err := base.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q announced by %s: %w", endpoint, t.server.URL, err)
+	}
+
+	if resolved.Scheme != base.Scheme || resolved.Host != base.Host {
+		return "", fmt.Errorf("endpoint %s announced by %s is on a different origin", resolved, t.server.URL)
+	}
+
+	return resolved.String(), nil
+}
+
+func (t *sseTransport) send(data []byte) error {
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+
+	if endpoint == "" {
+		return fmt.Errorf("not connected to %s", t.server.URL)
+	}
+
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if authErr := authorizationError(err); authErr != nil {
+			return authErr
+		}
+		return fmt.Errorf("failed to post to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return httpStatusError(endpoint, resp)
+	}
+
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	t.cancel()
+	t.finish()
+	return nil
+}
+
+func (t *sseTransport) done() <-chan struct{} {
+	return t.closed
+}
+
+func (t *sseTransport) describe() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.endpoint == "" {
+		return "not connected"
+	}
+	return fmt.Sprintf("%s (endpoint %s)", t.server.URL, t.endpoint)
+}
+
+// finish marks the transport as permanently closed
+func (t *sseTransport) finish() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/utils"
+)
+
+// maxRecordedErrorLength bounds the error text kept per call in the ledger
+const maxRecordedErrorLength = 200
+
+// ToolStats aggregates the recorded calls of one tool
+type ToolStats struct {
+	ServerID   string        `json:"server_id"`
+	ServerName string        `json:"server_name"`
+	Tool       string        `json:"tool"`
+	Calls      int           `json:"calls"`
+	Errors     int           `json:"errors"`
+	ErrorRate  float64       `json:"error_rate"`
+	P50        time.Duration `json:"p50"`
+	P95        time.Duration `json:"p95"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+	LastCall   time.Time     `json:"last_call"`
+	LastError  string        `json:"last_error,omitempty"`
+}
+
+// StatsFilter selects the calls that are aggregated
+type StatsFilter struct {
+	Server string    // server ID or name, empty for all servers
+	Since  time.Time // zero for all recorded calls
+}
+
+// recordToolCall adds a call to the usage ledger. Results the server flags
+// with isError count as failures. The time waited for the user's input is
+// not part of the recorded duration.
+func (m *Manager) recordToolCall(server *types.MCPServer, toolName string, start time.Time, inputWait time.Duration, result interface{}, err error) {
+	entry := types.ToolCallEntry{
+		Time:       start,
+		ServerID:   server.ID,
+		ServerName: server.Name,
+		Tool:       toolName,
+		Duration:   time.Since(start) - inputWait,
+		Success:    err == nil,
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+	} else if resultMap, ok := result.(map[string]interface{}); ok && utils.GetBool(resultMap, "isError") {
+		entry.Success = false
+		entry.Error = "tool reported an error"
+	}
+	if len(entry.Error) > maxRecordedErrorLength {
+		entry.Error = utils.TruncateUTF8(entry.Error, maxRecordedErrorLength) + "..."
+	}
+
+	if err := m.storage.AppendToolCall(entry); err != nil {
+		fmt.Printf("Warning: failed to record tool call: %v\n", err)
+	}
+}
+
+// ToolStats returns per-tool call counts, error rates and latency
+// percentiles from the usage ledger, sorted by server and tool
+func (m *Manager) ToolStats(filter StatsFilter) ([]ToolStats, error) {
+	serverID := ""
+	if filter.Server != "" {
+		server, err := m.ResolveServer(filter.Server)
+		if err != nil {
+			return nil, err
+		}
+		serverID = server.ID
+	}
+
+	entries, err := m.storage.LoadToolCalls(filter.Since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tool calls: %w", err)
+	}
+
+	type key struct{ server, tool string }
+	durations := make(map[key][]time.Duration)
+	stats := make(map[key]*ToolStats)
+
+	for _, entry := range entries {
+		if serverID != "" && entry.ServerID != serverID {
+			continue
+		}
+
+		k := key{entry.ServerID, entry.Tool}
+		stat, exists := stats[k]
+		if !exists {
+			stat = &ToolStats{ServerID: entry.ServerID, ServerName: entry.ServerName, Tool: entry.Tool}
+			stats[k] = stat
+		}
+
+		stat.Calls++
+		if !entry.Success {
+			stat.Errors++
+			stat.LastError = entry.Error
+		}
+		if entry.Time.After(stat.LastCall) {
+			stat.LastCall = entry.Time
+			// Servers may have been renamed since
+			stat.ServerName = entry.ServerName
+		}
+		durations[k] = append(durations[k], entry.Duration)
+	}
+
+	result := make([]ToolStats, 0, len(stats))
+	for k, stat := range stats {
+		sorted := durations[k]
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		stat.ErrorRate = float64(stat.Errors) / float64(stat.Calls)
+		stat.P50 = percentile(sorted, 50)
+		stat.P95 = percentile(sorted, 95)
+		stat.P99 = percentile(sorted, 99)
+		stat.Max = sorted[len(sorted)-1]
+		result = append(result, *stat)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ServerName != result[j].ServerName {
+			return result[i].ServerName < result[j].ServerName
+		}
+		return result[i].Tool < result[j].Tool
+	})
+
+	return result, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// ParseSince parses the start of a statistics window: a duration back from
+// now such as "90m", "24h" or "7d", or a date ("2006-01-02") or RFC 3339 time
+func ParseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid since %q (expected a duration like 24h or 7d, a date or an RFC 3339 time)", value)
+}
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+func TestRecordToolCall(t *testing.T) {
+	store := useTempHome(t)
+	server := &types.MCPServer{ID: "files-id", Name: "files"}
+	m := newTestManager(store, server)
+
+	// The time spent on the user's input is left out of the duration
+	start := time.Now().Add(-3 * time.Second)
+	m.recordToolCall(server, "read_file", start, 2*time.Second, map[string]interface{}{"isError": true}, nil)
+
+	// Long errors are cut without splitting a character
+	message := strings.Repeat("é", maxRecordedErrorLength)
+	m.recordToolCall(server, "write_file", time.Now(), 0, nil, errors.New(message))
+
+	entries, err := store.LoadToolCalls(time.Time{})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("entries = %+v, %v", entries, err)
+	}
+
+	first := entries[0]
+	if first.Success || first.Error != "tool reported an error" {
+		t.Errorf("isError result recorded as %+v", first)
+	}
+	if first.Duration < time.Second || first.Duration >= 2*time.Second {
+		t.Errorf("duration = %s, want about 1s", first.Duration)
+	}
+
+	second := entries[1]
+	if !utf8.ValidString(second.Error) || !strings.HasSuffix(second.Error, "...") {
+		t.Errorf("error = %q, want valid UTF-8 ending in ...", second.Error)
+	}
+	if len(second.Error) > maxRecordedErrorLength+len("...") {
+		t.Errorf("error has %d bytes, want at most %d", len(second.Error), maxRecordedErrorLength+len("..."))
+	}
+}
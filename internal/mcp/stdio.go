@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// maxStderrLines bounds the stderr output kept per process
+const maxStderrLines = 200
+
+// stdioTransport runs the server as a child process and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout
+type stdioTransport struct {
+	server *types.MCPServer
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+
+	// Diagnostics kept for mcp doctor
+	stderrLines []string
+	echoStderr  bool
+	exited      chan struct{}
+	stderrDone  chan struct{}
+}
+
+func newStdioTransport(server *types.MCPServer) *stdioTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &stdioTransport{
+		server:     server,
+		ctx:        ctx,
+		cancel:     cancel,
+		echoStderr: true,
+		exited:     make(chan struct{}),
+		stderrDone: make(chan struct{}),
+	}
+}
+
+// open starts the server process and its output readers
+func (t *stdioTransport) open(handler transportHandler) error {
+	// Start the MCP server process using the server URL
+	args := strings.Fields(t.server.URL)
+	if len(args) == 0 {
+		return fmt.Errorf("invalid server URL: %s", t.server.URL)
+	}
+	t.cmd = exec.CommandContext(t.ctx, args[0], args[1:]...)
+
+	if err := configureSandbox(t.cmd, t.server.Sandbox); err != nil {
+		return fmt.Errorf("invalid sandbox settings: %w", err)
+	}
+
+	var err error
+	t.stdin, err = t.cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	t.stdout, err = t.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	t.stderr, err = t.cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := t.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start MCP server: %w", err)
+	}
+
+	// Start output readers
+	go t.readOutput(handler)
+	go t.readErrors()
+
+	return nil
+}
+
+func (t *stdioTransport) send(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err := t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) close() error {
+	t.cancel()
+
+	if t.cmd != nil && t.cmd.Process != nil {
+		return killProcessGroup(t.cmd)
+	}
+
+	return nil
+}
+
+func (t *stdioTransport) done() <-chan struct{} {
+	return t.exited
+}
+
+func (t *stdioTransport) describe() string {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return "not started"
+	}
+	return fmt.Sprintf("pid %d", t.cmd.Process.Pid)
+}
+
+// Stderr returns the most recent lines the server wrote to stderr
+func (t *stdioTransport) Stderr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.stderrLines, "\n")
+}
+
+func (t *stdioTransport) readOutput(handler transportHandler) {
+	defer close(t.exited)
+
+	scanner := bufio.NewScanner(t.stdout)
+	// Tool lists and results can be far larger than the default 64KB line limit
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		handler.handleMessage(append([]byte(nil), line...))
+	}
+}
+
+func (t *stdioTransport) readErrors() {
+	defer close(t.stderrDone)
+
+	scanner := bufio.NewScanner(t.stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		t.mu.Lock()
+		t.stderrLines = append(t.stderrLines, line)
+		if len(t.stderrLines) > maxStderrLines {
+			t.stderrLines = t.stderrLines[len(t.stderrLines)-maxStderrLines:]
+		}
+		echo := t.echoStderr
+		t.mu.Unlock()
+
+		if !echo {
+			continue
+		}
+
+		// Filter out normal loading messages from desktop-commander
+		if strings.Contains(line, "Loading server.ts") ||
+			strings.Contains(line, "Setting up request handlers") ||
+			strings.Contains(line, "initialized") ||
+			strings.Contains(line, "Loading configuration") ||
+			strings.Contains(line, "Configuration loaded") ||
+			strings.Contains(line, "Connecting server") ||
+			strings.Contains(line, "Server connected") ||
+			strings.Contains(line, "Generating tools list") {
+			continue // Skip normal loading messages
+		}
+
+		// Only show actual errors
+		fmt.Printf("MCP Error: %s\n", line)
+	}
+}
@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// messageTransport carries JSON-RPC messages between the client and a
+// server. MCPProcess implements the protocol on top of it.
+type messageTransport interface {
+	// open connects to the server and starts delivering incoming messages
+	// to the handler
+	open(handler transportHandler) error
+	// send writes one JSON-RPC message
+	send(data []byte) error
+	// close disconnects and releases the transport's resources
+	close() error
+	// done is closed once no more messages will arrive
+	done() <-chan struct{}
+	// describe summarizes the established connection, e.g. a pid or URL
+	describe() string
+}
+
+// transportHandler receives events from a transport
+type transportHandler interface {
+	handleMessage(data []byte)
+	// connectionLost is called when a reconnecting transport drops its
+	// connection; requests in flight will not be answered
+	connectionLost(err error)
+	// reconnected is called after a reconnecting transport established a
+	// new connection, which needs a new MCP session
+	reconnected() error
+}
+
+// newTransport returns the transport for the server's transport type
+func newTransport(server *types.MCPServer) (messageTransport, error) {
+	switch server.Transport {
+	case "stdio":
+		return newStdioTransport(server), nil
+	case "websocket":
+		return newWebSocketTransport(server), nil
+	case "http":
+		return newHTTPTransport(server), nil
+	case "sse":
+		return newSSETransport(server), nil
+	default:
+		return nil, fmt.Errorf("transport %s is not supported", server.Transport)
+	}
+}
+
+// isPersistentTransport reports whether the transport keeps one connection
+// open that is shared by all calls, rather than starting a process per call
+func isPersistentTransport(transport string) bool {
+	return transport == "websocket" || transport == "http" || transport == "sse"
+}
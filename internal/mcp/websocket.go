@@ -0,0 +1,286 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+const (
+	wsHandshakeTimeout     = 10 * time.Second
+	wsWriteTimeout         = 10 * time.Second
+	wsMaxMessageSize       = 16 * 1024 * 1024
+	wsMaxReconnectAttempts = 5
+	wsMaxReconnectDelay    = 30 * time.Second
+)
+
+// Keepalive and backoff timing. Transports copy them when created, so that
+// tests can shorten them.
+var (
+	wsPongWait              = 60 * time.Second
+	wsPingInterval          = wsPongWait * 9 / 10
+	wsInitialReconnectDelay = time.Second
+)
+
+// webSocketTransport exchanges JSON-RPC messages with a server as WebSocket
+// text messages, one message per frame. The connection is kept alive with
+// pings and re-established with backoff when it drops.
+type webSocketTransport struct {
+	server    *types.MCPServer
+	handler   transportHandler
+	conn      *websocket.Conn
+	mu        sync.Mutex
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	pongWait       time.Duration
+	pingInterval   time.Duration
+	reconnectDelay time.Duration
+}
+
+func newWebSocketTransport(server *types.MCPServer) *webSocketTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &webSocketTransport{
+		server:         server,
+		ctx:            ctx,
+		cancel:         cancel,
+		closed:         make(chan struct{}),
+		pongWait:       wsPongWait,
+		pingInterval:   wsPingInterval,
+		reconnectDelay: wsInitialReconnectDelay,
+	}
+}
+
+func (t *webSocketTransport) open(handler transportHandler) error {
+	t.handler = handler
+
+	conn, err := t.dial()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.conn = conn
+	t.mu.Unlock()
+
+	go t.run(t.startReading(conn))
+	return nil
+}
+
+// dial opens a new connection to the server
+func (t *webSocketTransport) dial() (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsHandshakeTimeout,
+		Subprotocols:     []string{"mcp"},
+	}
+
+	headers, err := serverHeaders(t.server)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, resp, err := dialer.DialContext(t.ctx, t.server.URL, headers)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("websocket handshake with %s failed (%s): %w", t.server.URL, resp.Status, err)
+		}
+		return nil, fmt.Errorf("failed to dial %s: %w", t.server.URL, err)
+	}
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(t.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(t.pongWait))
+	})
+
+	return conn, nil
+}
+
+// run waits for the connection to fail and then reconnects, until the
+// transport is closed or reconnecting fails. It is the only goroutine that
+// reconnects, so attempts never overlap.
+func (t *webSocketTransport) run(readDone <-chan error) {
+	for {
+		err := <-readDone
+
+		if t.ctx.Err() != nil {
+			t.finish()
+			return
+		}
+
+		debugPrint("WebSocket connection to %s lost: %v\n", t.server.Name, err)
+		t.mu.Lock()
+		t.conn = nil
+		t.mu.Unlock()
+		t.handler.connectionLost(err)
+
+		if readDone = t.reconnect(); readDone == nil {
+			t.finish()
+			return
+		}
+	}
+}
+
+// startReading passes the messages of the connection to the handler in the
+// background. Once reading fails the connection is closed and the error is
+// sent on the returned channel.
+func (t *webSocketTransport) startReading(conn *websocket.Conn) <-chan error {
+	readDone := make(chan error, 1)
+
+	go func() {
+		stopPing := make(chan struct{})
+		go t.keepAlive(conn, stopPing)
+
+		var err error
+		for {
+			var data []byte
+			if _, data, err = conn.ReadMessage(); err != nil {
+				break
+			}
+			t.handler.handleMessage(data)
+		}
+
+		close(stopPing)
+		conn.Close()
+		readDone <- err
+	}()
+
+	return readDone
+}
+
+// keepAlive pings the server so that dead connections are noticed by the
+// read deadline
+func (t *webSocketTransport) keepAlive(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(t.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				debugPrint("WebSocket ping to %s failed: %v\n", t.server.Name, err)
+				return
+			}
+		}
+	}
+}
+
+// reconnect re-establishes the connection with exponential backoff and
+// starts a new MCP session on it. The new connection is read while the
+// session starts, as that needs the server's answers. It returns the read
+// result channel of the new connection, or nil once the transport is closed
+// or after wsMaxReconnectAttempts consecutive failures.
+func (t *webSocketTransport) reconnect() <-chan error {
+	delay := t.reconnectDelay
+	for attempt := 1; attempt <= wsMaxReconnectAttempts; attempt++ {
+		select {
+		case <-t.ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > wsMaxReconnectDelay {
+			delay = wsMaxReconnectDelay
+		}
+
+		conn, err := t.dial()
+		if err != nil {
+			debugPrint("Reconnect %d to %s failed: %v\n", attempt, t.server.Name, err)
+			continue
+		}
+
+		// close() may have run while dialing
+		t.mu.Lock()
+		if t.ctx.Err() != nil {
+			t.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		t.conn = conn
+		t.mu.Unlock()
+
+		readDone := t.startReading(conn)
+
+		if err := t.handler.reconnected(); err != nil {
+			debugPrint("Reconnect %d to %s could not start a session: %v\n", attempt, t.server.Name, err)
+			t.mu.Lock()
+			t.conn = nil
+			t.mu.Unlock()
+			conn.Close()
+			<-readDone
+			continue
+		}
+
+		debugPrint("Reconnected to %s\n", t.server.Name)
+		return readDone
+	}
+
+	debugPrint("Giving up on %s after %d reconnect attempts\n", t.server.Name, wsMaxReconnectAttempts)
+	return nil
+}
+
+func (t *webSocketTransport) send(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return fmt.Errorf("not connected to %s", t.server.URL)
+	}
+
+	t.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return t.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (t *webSocketTransport) close() error {
+	t.cancel()
+
+	t.mu.Lock()
+	conn := t.conn
+	t.conn = nil
+	t.mu.Unlock()
+
+	if conn != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(wsWriteTimeout))
+		conn.Close()
+	}
+
+	t.finish()
+	return nil
+}
+
+func (t *webSocketTransport) done() <-chan struct{} {
+	return t.closed
+}
+
+func (t *webSocketTransport) describe() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return "not connected"
+	}
+	if subprotocol := t.conn.Subprotocol(); subprotocol != "" {
+		return fmt.Sprintf("connected to %s (subprotocol %s)", t.server.URL, subprotocol)
+	}
+	return "connected to " + t.server.URL
+}
+
+// finish marks the transport as permanently closed
+func (t *webSocketTransport) finish() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
@@ -0,0 +1,386 @@
+package mcp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// recordingHandler is a transportHandler that collects what the transport
+// reports
+type recordingHandler struct {
+	messages    chan string
+	lost        chan error
+	reconnects  int32
+	reconnectFn func(attempt int32) error
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{
+		messages: make(chan string, 16),
+		lost:     make(chan error, 16),
+	}
+}
+
+func (h *recordingHandler) handleMessage(data []byte) {
+	h.messages <- string(data)
+}
+
+func (h *recordingHandler) connectionLost(err error) {
+	h.lost <- err
+}
+
+func (h *recordingHandler) reconnected() error {
+	attempt := atomic.AddInt32(&h.reconnects, 1)
+	if h.reconnectFn != nil {
+		return h.reconnectFn(attempt)
+	}
+	return nil
+}
+
+// wsStandIn is a local WebSocket server that hands each accepted connection
+// to the test
+type wsStandIn struct {
+	*httptest.Server
+	conns chan *websocket.Conn
+}
+
+func newWSStandIn(t *testing.T) *wsStandIn {
+	t.Helper()
+
+	standIn := &wsStandIn{conns: make(chan *websocket.Conn, 16)}
+	upgrader := websocket.Upgrader{Subprotocols: []string{"mcp"}}
+	standIn.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		standIn.conns <- conn
+	}))
+	t.Cleanup(standIn.Close)
+
+	return standIn
+}
+
+func (s *wsStandIn) url() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// accept waits for the next connection of the transport
+func (s *wsStandIn) accept(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-s.conns:
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("transport did not connect")
+		return nil
+	}
+}
+
+// shortenWSTiming speeds up pings and reconnects for the duration of a test
+func shortenWSTiming(t *testing.T, pongWait time.Duration) {
+	pongWaitBefore, pingBefore, delayBefore := wsPongWait, wsPingInterval, wsInitialReconnectDelay
+	wsPongWait = pongWait
+	wsPingInterval = pongWait * 9 / 10
+	wsInitialReconnectDelay = 10 * time.Millisecond
+	t.Cleanup(func() {
+		wsPongWait, wsPingInterval, wsInitialReconnectDelay = pongWaitBefore, pingBefore, delayBefore
+	})
+}
+
+func openWSTransport(t *testing.T, url string, handler transportHandler) *webSocketTransport {
+	t.Helper()
+
+	transport := newWebSocketTransport(&types.MCPServer{Name: "stand-in", URL: url, Transport: "websocket"})
+	if err := transport.open(handler); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { transport.close() })
+	return transport
+}
+
+func receive(t *testing.T, messages <-chan string) string {
+	t.Helper()
+
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
+
+func TestWebSocketTransportFraming(t *testing.T) {
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+	transport := openWSTransport(t, standIn.url(), handler)
+	server := standIn.accept(t)
+
+	if got := server.Subprotocol(); got != "mcp" {
+		t.Errorf("subprotocol = %q, want mcp", got)
+	}
+
+	// Each message the client sends is one text frame
+	requests := []string{`{"jsonrpc":"2.0","id":1,"method":"ping"}`, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`}
+	for _, request := range requests {
+		if err := transport.send([]byte(request)); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	for _, want := range requests {
+		messageType, data, err := server.ReadMessage()
+		if err != nil {
+			t.Fatalf("server read: %v", err)
+		}
+		if messageType != websocket.TextMessage {
+			t.Errorf("frame type = %d, want text", messageType)
+		}
+		if string(data) != want {
+			t.Errorf("frame = %s, want %s", data, want)
+		}
+	}
+
+	// Each frame the server sends is delivered as one message
+	responses := []string{`{"jsonrpc":"2.0","id":1,"result":{}}`, `{"jsonrpc":"2.0","id":2,"result":{"tools":[]}}`}
+	for _, response := range responses {
+		if err := server.WriteMessage(websocket.TextMessage, []byte(response)); err != nil {
+			t.Fatalf("server write: %v", err)
+		}
+	}
+	for _, want := range responses {
+		if got := receive(t, handler.messages); got != want {
+			t.Errorf("message = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestWebSocketTransportPingPong(t *testing.T) {
+	shortenWSTiming(t, 200*time.Millisecond)
+
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+	openWSTransport(t, standIn.url(), handler)
+	server := standIn.accept(t)
+
+	// The client answers the server's pings and pings the server itself
+	pongs := make(chan string, 4)
+	server.SetPongHandler(func(data string) error {
+		pongs <- data
+		return nil
+	})
+	pings := make(chan struct{}, 16)
+	server.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return server.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := server.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := server.WriteControl(websocket.PingMessage, []byte("hello"), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("server ping: %v", err)
+	}
+	select {
+	case data := <-pongs:
+		if data != "hello" {
+			t.Errorf("pong = %q, want hello", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no pong for the server's ping")
+	}
+
+	// The server's pongs keep the connection alive across several pong waits
+	deadline := time.After(3 * wsPongWait)
+	received := 0
+	for waiting := true; waiting; {
+		select {
+		case <-pings:
+			received++
+		case err := <-handler.lost:
+			t.Fatalf("connection dropped although pongs were sent: %v", err)
+		case <-deadline:
+			waiting = false
+		}
+	}
+	if received < 2 {
+		t.Errorf("client sent %d pings, want at least 2", received)
+	}
+}
+
+func TestWebSocketTransportDropsConnectionWithoutPongs(t *testing.T) {
+	shortenWSTiming(t, 200*time.Millisecond)
+
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+	openWSTransport(t, standIn.url(), handler)
+
+	// The stand-in never reads, so pings are not answered
+	standIn.accept(t)
+
+	select {
+	case <-handler.lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection without pongs was not dropped")
+	}
+}
+
+func TestWebSocketTransportReconnects(t *testing.T) {
+	shortenWSTiming(t, time.Minute)
+
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+	transport := openWSTransport(t, standIn.url(), handler)
+
+	first := standIn.accept(t)
+	first.Close()
+
+	select {
+	case <-handler.lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lost connection was not reported")
+	}
+
+	second := standIn.accept(t)
+	waitFor(t, func() bool { return atomic.LoadInt32(&handler.reconnects) == 1 })
+
+	// The new connection carries messages both ways
+	if err := second.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)); err != nil {
+		t.Fatalf("server write: %v", err)
+	}
+	receive(t, handler.messages)
+
+	waitFor(t, func() bool { return transport.send([]byte(`{}`)) == nil })
+	if _, data, err := second.ReadMessage(); err != nil || string(data) != `{}` {
+		t.Fatalf("server read = %s, %v", data, err)
+	}
+}
+
+func TestWebSocketTransportRetriesFailedSession(t *testing.T) {
+	shortenWSTiming(t, time.Minute)
+
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+
+	// The session on the first new connection fails; only one reconnect may
+	// run at a time
+	var active, overlapped int32
+	var mu sync.Mutex
+	handler.reconnectFn = func(attempt int32) error {
+		if atomic.AddInt32(&active, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		defer atomic.AddInt32(&active, -1)
+
+		mu.Lock()
+		defer mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		if attempt == 1 {
+			return errors.New("initialize failed")
+		}
+		return nil
+	}
+
+	openWSTransport(t, standIn.url(), handler)
+	standIn.accept(t).Close()
+
+	standIn.accept(t) // session fails
+	third := standIn.accept(t)
+
+	waitFor(t, func() bool { return atomic.LoadInt32(&handler.reconnects) == 2 })
+	if atomic.LoadInt32(&overlapped) != 0 {
+		t.Error("reconnect attempts overlapped")
+	}
+
+	// No further reconnect follows the successful one
+	select {
+	case conn := <-standIn.conns:
+		conn.Close()
+		t.Fatal("transport reconnected again after a successful session")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := third.WriteMessage(websocket.TextMessage, []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("server write: %v", err)
+	}
+	receive(t, handler.messages)
+}
+
+func TestWebSocketTransportGivesUp(t *testing.T) {
+	shortenWSTiming(t, time.Minute)
+
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+	transport := openWSTransport(t, standIn.url(), handler)
+	server := standIn.accept(t)
+
+	// With the stand-in gone every reconnect fails
+	standIn.Close()
+	server.Close()
+
+	select {
+	case <-transport.done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("transport did not give up")
+	}
+	if got := atomic.LoadInt32(&handler.reconnects); got != 0 {
+		t.Errorf("reconnected %d times, want 0", got)
+	}
+}
+
+func TestWebSocketTransportClose(t *testing.T) {
+	standIn := newWSStandIn(t)
+	handler := newRecordingHandler()
+	transport := openWSTransport(t, standIn.url(), handler)
+	server := standIn.accept(t)
+
+	transport.close()
+
+	select {
+	case <-transport.done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("done was not closed")
+	}
+
+	_, _, err := server.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("server read error = %v, want a normal close", err)
+	}
+
+	select {
+	case conn := <-standIn.conns:
+		conn.Close()
+		t.Error("closed transport reconnected")
+	case err := <-handler.lost:
+		t.Errorf("close was reported as a lost connection: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// waitFor polls condition until it holds or the test times out
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,208 @@
+package profile
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/internal/storage"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+type Manager struct {
+	profiles map[string]*types.Profile
+	storage  *storage.Storage
+	mu       sync.RWMutex
+}
+
+func NewManager() *Manager {
+	storage := storage.New("")
+
+	m := &Manager{
+		profiles: make(map[string]*types.Profile),
+		storage:  storage,
+	}
+
+	// Load existing profiles from storage
+	if profiles, err := storage.LoadProfiles(); err == nil {
+		m.profiles = profiles
+	}
+
+	return m
+}
+
+func (m *Manager) CreateProfile(profile *types.Profile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if profile.Name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+
+	if _, exists := m.profiles[profile.Name]; exists {
+		return fmt.Errorf("profile %s already exists", profile.Name)
+	}
+
+	for _, pattern := range append(append([]string{}, profile.Allow...), profile.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	profile.IsActive = false
+	profile.CreatedAt = time.Now()
+	profile.UpdatedAt = time.Now()
+
+	m.profiles[profile.Name] = profile
+
+	// Save to storage
+	if err := m.storage.SaveProfiles(m.profiles); err != nil {
+		fmt.Printf("Warning: failed to save profiles to storage: %v\n", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) RemoveProfile(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.profiles[name]; !exists {
+		return fmt.Errorf("profile %s not found", name)
+	}
+
+	delete(m.profiles, name)
+
+	// Save to storage
+	if err := m.storage.SaveProfiles(m.profiles); err != nil {
+		fmt.Printf("Warning: failed to save profiles to storage: %v\n", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) GetProfile(name string) (*types.Profile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profile, exists := m.profiles[name]
+	if !exists {
+		return nil, fmt.Errorf("profile %s not found", name)
+	}
+
+	return profile, nil
+}
+
+func (m *Manager) ListProfiles() []*types.Profile {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profiles := make([]*types.Profile, 0, len(m.profiles))
+	for _, profile := range m.profiles {
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles
+}
+
+// GetActiveProfile returns the profile selected with `profile use`, or nil
+// when no profile is active
+func (m *Manager) GetActiveProfile() *types.Profile {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, profile := range m.profiles {
+		if profile.IsActive {
+			return profile
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) SetActiveProfile(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profile, exists := m.profiles[name]
+	if !exists {
+		return fmt.Errorf("profile %s not found", name)
+	}
+
+	for _, p := range m.profiles {
+		p.IsActive = false
+		p.UpdatedAt = time.Now()
+	}
+
+	profile.IsActive = true
+	profile.UpdatedAt = time.Now()
+
+	// Save to storage
+	if err := m.storage.SaveProfiles(m.profiles); err != nil {
+		fmt.Printf("Warning: failed to save profiles to storage: %v\n", err)
+	}
+
+	return nil
+}
+
+// Resolve returns the named profile, or the active profile when name is empty
+func (m *Manager) Resolve(name string) (*types.Profile, error) {
+	if name != "" {
+		return m.GetProfile(name)
+	}
+	return m.GetActiveProfile(), nil
+}
+
+// AllowsServer reports whether the profile includes the given server.
+// A nil profile or a profile without server IDs allows every server.
+func AllowsServer(profile *types.Profile, server *types.MCPServer) bool {
+	if profile == nil || len(profile.ServerIDs) == 0 {
+		return true
+	}
+
+	for _, id := range profile.ServerIDs {
+		if id == server.ID || strings.EqualFold(id, server.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsTool reports whether the profile's allow and deny rules permit a
+// tool. Each name (e.g. the prefixed name and the raw MCP name) is checked;
+// deny rules take precedence over allow rules.
+func AllowsTool(profile *types.Profile, names ...string) bool {
+	if profile == nil {
+		return true
+	}
+
+	if matchesAny(profile.Deny, names) {
+		return false
+	}
+
+	if len(profile.Allow) == 0 {
+		return true
+	}
+
+	return matchesAny(profile.Allow, names)
+}
+
+// matchesAny reports whether any name matches any of the glob patterns
+func matchesAny(patterns, names []string) bool {
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
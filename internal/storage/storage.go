@@ -1,9 +1,14 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
@@ -54,6 +59,37 @@ func (s *Storage) LoadLLMProviders() (map[string]*types.LLMProvider, error) {
 	return providers, nil
 }
 
+// SaveLLMFallback saves the IDs of the fallback providers in the order they
+// are tried
+func (s *Storage) SaveLLMFallback(providerIDs []string) error {
+	data, err := json.MarshalIndent(providerIDs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(s.dataDir, "llm_fallback.json")
+	return os.WriteFile(filePath, data, 0644)
+}
+
+func (s *Storage) LoadLLMFallback() ([]string, error) {
+	filePath := filepath.Join(s.dataDir, "llm_fallback.json")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var providerIDs []string
+	if err := json.Unmarshal(data, &providerIDs); err != nil {
+		return nil, err
+	}
+
+	return providerIDs, nil
+}
+
 func (s *Storage) SaveMCPServers(servers map[string]*types.MCPServer) error {
 	data, err := json.MarshalIndent(servers, "", "  ")
 	if err != nil {
@@ -82,3 +118,305 @@ func (s *Storage) LoadMCPServers() (map[string]*types.MCPServer, error) {
 
 	return servers, nil
 }
+
+// toolOutputPath returns the file path for a stored tool output handle
+func (s *Storage) toolOutputPath(handle string) (string, error) {
+	if handle == "" || strings.ContainsAny(handle, `/\.`) {
+		return "", fmt.Errorf("invalid tool output handle: %q", handle)
+	}
+
+	return filepath.Join(s.dataDir, "tool_outputs", handle+".txt"), nil
+}
+
+// SaveToolOutput stores the full output of a tool call under the given handle
+func (s *Storage) SaveToolOutput(handle string, content []byte) error {
+	filePath, err := s.toolOutputPath(handle)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	// Outputs are only paged through during the conversation that produced
+	// them, so old ones are removed when a new one is stored
+	s.PruneToolOutputs(toolOutputMaxAge)
+
+	return os.WriteFile(filePath, content, 0600)
+}
+
+// toolOutputMaxAge is how long stored tool outputs are kept
+const toolOutputMaxAge = 7 * 24 * time.Hour
+
+// PruneToolOutputs removes stored tool outputs older than maxAge
+func (s *Storage) PruneToolOutputs(maxAge time.Duration) error {
+	dir := filepath.Join(s.dataDir, "tool_outputs")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".txt" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		os.Remove(filepath.Join(dir, entry.Name()))
+	}
+
+	return nil
+}
+
+// ReadToolOutput returns up to length bytes of a stored tool output starting
+// at offset, together with the offset actually read from and the total size
+// of the stored output. Both ends are moved to character boundaries so that
+// a page never splits a multi-byte character.
+func (s *Storage) ReadToolOutput(handle string, offset, length int) (string, int, int, error) {
+	filePath, err := s.toolOutputPath(handle)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", 0, 0, fmt.Errorf("tool output %s not found", handle)
+		}
+		return "", 0, 0, err
+	}
+
+	total := len(data)
+	if offset < 0 || offset > total {
+		return "", 0, total, fmt.Errorf("offset %d out of range (total %d bytes)", offset, total)
+	}
+
+	// Start at the beginning of the character the offset points into
+	for offset > 0 && offset < total && !utf8.RuneStart(data[offset]) {
+		offset--
+	}
+
+	end := total
+	if length > 0 && offset+length < total {
+		end = offset + length
+		for end > offset && !utf8.RuneStart(data[end]) {
+			end--
+		}
+		// A length shorter than the character still returns the character
+		if end == offset {
+			_, size := utf8.DecodeRune(data[offset:])
+			end = offset + size
+		}
+	}
+
+	return string(data[offset:end]), offset, total, nil
+}
+
+// AppendToolCall adds a tool call to the usage ledger
+func (s *Storage) AppendToolCall(entry types.ToolCallEntry) error {
+	return s.appendJSONLine("tool_calls.jsonl", entry)
+}
+
+// LoadToolCalls returns the tool calls recorded at or after since. Lines
+// that cannot be parsed, e.g. from an interrupted write, are skipped.
+func (s *Storage) LoadToolCalls(since time.Time) ([]types.ToolCallEntry, error) {
+	var entries []types.ToolCallEntry
+	err := s.readJSONLines("tool_calls.jsonl", func(line []byte) {
+		var entry types.ToolCallEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return
+		}
+		if !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	})
+	return entries, err
+}
+
+// AppendUsage adds an LLM call to the token usage ledger
+func (s *Storage) AppendUsage(entry types.UsageEntry) error {
+	return s.appendJSONLine("usage.jsonl", entry)
+}
+
+// LoadUsage returns the LLM calls recorded at or after since. Lines that
+// cannot be parsed are skipped.
+func (s *Storage) LoadUsage(since time.Time) ([]types.UsageEntry, error) {
+	var entries []types.UsageEntry
+	err := s.readJSONLines("usage.jsonl", func(line []byte) {
+		var entry types.UsageEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return
+		}
+		if !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	})
+	return entries, err
+}
+
+// AppendStatusTransition adds a server status change to the history
+func (s *Storage) AppendStatusTransition(transition types.StatusTransition) error {
+	return s.appendJSONLine("status_history.jsonl", transition)
+}
+
+// LoadStatusTransitions returns the recorded status changes of a server, or
+// of all servers if serverID is empty, oldest first
+func (s *Storage) LoadStatusTransitions(serverID string) ([]types.StatusTransition, error) {
+	var transitions []types.StatusTransition
+	err := s.readJSONLines("status_history.jsonl", func(line []byte) {
+		var transition types.StatusTransition
+		if err := json.Unmarshal(line, &transition); err != nil {
+			return
+		}
+		if serverID == "" || transition.ServerID == serverID {
+			transitions = append(transitions, transition)
+		}
+	})
+	return transitions, err
+}
+
+// appendJSONLine appends a value as one line to a JSON lines file
+func (s *Storage) appendJSONLine(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(s.dataDir, name)
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// readJSONLines calls fn for every line of a JSON lines file. A missing
+// file has no lines.
+func (s *Storage) readJSONLines(name string, fn func(line []byte)) error {
+	file, err := os.Open(filepath.Join(s.dataDir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// mcpCredentialsPath returns the file path for a server's OAuth credentials
+func (s *Storage) mcpCredentialsPath(serverID string) (string, error) {
+	if serverID == "" || strings.ContainsAny(serverID, `/\.`) {
+		return "", fmt.Errorf("invalid server ID: %q", serverID)
+	}
+
+	return filepath.Join(s.dataDir, "mcp_oauth", serverID+".json"), nil
+}
+
+// SaveMCPCredentials stores the OAuth credentials of a server, readable by
+// the current user only
+func (s *Storage) SaveMCPCredentials(serverID string, credentials *types.MCPOAuthCredentials) error {
+	filePath, err := s.mcpCredentialsPath(serverID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a concurrent reader never sees a
+	// partially written token
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// LoadMCPCredentials returns the OAuth credentials of a server, or nil if
+// it has none
+func (s *Storage) LoadMCPCredentials(serverID string) (*types.MCPOAuthCredentials, error) {
+	filePath, err := s.mcpCredentialsPath(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var credentials types.MCPOAuthCredentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, err
+	}
+
+	return &credentials, nil
+}
+
+// DeleteMCPCredentials removes the OAuth credentials of a server
+func (s *Storage) DeleteMCPCredentials(serverID string) error {
+	filePath, err := s.mcpCredentialsPath(serverID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) SaveProfiles(profiles map[string]*types.Profile) error {
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(s.dataDir, "profiles.json")
+	return os.WriteFile(filePath, data, 0644)
+}
+
+func (s *Storage) LoadProfiles() (map[string]*types.Profile, error) {
+	filePath := filepath.Join(s.dataDir, "profiles.json")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]*types.Profile), nil
+		}
+		return nil, err
+	}
+
+	var profiles map[string]*types.Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
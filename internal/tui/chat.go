@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/google/uuid"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/agent"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
 	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
 
@@ -56,9 +58,18 @@ type ChatModel struct {
 	session       *types.ConversationSession
 	conversation  []ConversationEntry
 	processing    bool
+	cancel        context.CancelFunc // cancels the request being processed
 	err           error
 	width         int
 	height        int
+	usage         types.UsageTotals // tokens and cost of the session, for the status bar
+
+	// Forms requested by MCP servers during tool calls
+	elicitations chan ElicitationPromptMsg
+	form         *elicitationForm
+
+	// Closed when the chat ends, cancelling the request being processed
+	done chan struct{}
 }
 
 type ConversationEntry struct {
@@ -68,9 +79,20 @@ type ConversationEntry struct {
 }
 
 type AgentResponseMsg struct {
-	Message string
-	Error   string
-	Err     error
+	Message   string
+	Error     string
+	Err       error
+	Cancelled bool
+
+	// SessionUsage is the token usage of the session after the request
+	SessionUsage *types.UsageTotals
+}
+
+// CompletionMsg carries the completions for a chat command line
+type CompletionMsg struct {
+	Line       string
+	Candidates []string
+	Err        error
 }
 
 func NewChatModel(ag *agent.Agent, mcpServerID, providerID string, interactive bool) ChatModel {
@@ -105,6 +127,8 @@ func NewChatModel(ag *agent.Agent, mcpServerID, providerID string, interactive b
 				Message: "Welcome to the AI Agent Chat! How can I help you today?",
 			},
 		},
+		elicitations: make(chan ElicitationPromptMsg),
+		done:         make(chan struct{}),
 	}
 }
 
@@ -118,10 +142,25 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.form != nil {
+			return m.updateForm(msg)
+		}
+
 		switch msg.Type {
 		case tea.KeyEsc:
 			return m, tea.Quit
 		case tea.KeyCtrlC:
+			// Ctrl+C cancels a running request and quits otherwise
+			if m.processing && m.cancel != nil {
+				m.cancel()
+				m.cancel = nil
+				m.conversation = append(m.conversation, ConversationEntry{
+					Type:    "progress",
+					Message: formatProgress("Cancelling request..."),
+				})
+				m.updateViewport()
+				return m, nil
+			}
 			return m, tea.Quit
 		case tea.KeyCtrlL:
 			// Clear conversation and reset session
@@ -134,6 +173,7 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				CreatedAt:   time.Now(),
 				UpdatedAt:   time.Now(),
 			}
+			m.usage = types.UsageTotals{}
 			m.conversation = []ConversationEntry{
 				{
 					Type:    "agent",
@@ -142,6 +182,11 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.updateViewport()
 			return m, nil
+		case tea.KeyTab:
+			// Complete /tool, /prompt and /resource lines
+			if !m.processing && agent.IsChatCommand(m.textarea.Value()) {
+				return m, m.completeCmd(m.textarea.Value())
+			}
 		}
 
 		// Handle Enter for sending message (simple approach for TUI)
@@ -149,7 +194,9 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if !m.processing && strings.TrimSpace(m.textarea.Value()) != "" {
 				// Send message and start processing
 				m.sendMessage()
-				return m, m.processMessageCmd()
+				ctx, cancel := context.WithCancel(context.Background())
+				m.cancel = cancel
+				return m, m.processMessageCmd(ctx)
 			}
 		}
 
@@ -171,8 +218,30 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case AgentResponseMsg:
 		m.processing = false
+		if m.cancel != nil {
+			m.cancel()
+			m.cancel = nil
+		}
+		if msg.SessionUsage != nil {
+			m.usage = *msg.SessionUsage
+		}
+
+		// The tool call that asked for the form is over
+		if m.form != nil {
+			m.form = nil
+			m.textarea.Reset()
+			m.conversation = append(m.conversation, ConversationEntry{
+				Type:    "progress",
+				Message: formatProgress("📝 Form abandoned: the request ended before it was answered"),
+			})
+		}
 		
-		if msg.Err != nil {
+		if msg.Cancelled {
+			m.conversation = append(m.conversation, ConversationEntry{
+				Type:    "progress",
+				Message: formatProgress("⏹️ Request cancelled"),
+			})
+		} else if msg.Err != nil {
 			m.conversation = append(m.conversation, ConversationEntry{
 				Type:    "error",
 				Message: fmt.Sprintf("❌ Processing Error: %v", msg.Err),
@@ -189,6 +258,49 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewport.GotoBottom()
 		return m, nil
 
+	case CompletionMsg:
+		// Ignore completions for a line the user has since changed
+		if msg.Line != m.textarea.Value() {
+			return m, nil
+		}
+
+		switch {
+		case msg.Err != nil:
+			m.conversation = append(m.conversation, ConversationEntry{
+				Type:    "error",
+				Message: fmt.Sprintf("Completion failed: %v", msg.Err),
+			})
+		case len(msg.Candidates) == 0:
+			m.conversation = append(m.conversation, ConversationEntry{
+				Type:    "progress",
+				Message: formatProgress("No completions"),
+			})
+		case len(msg.Candidates) == 1:
+			m.textarea.SetValue(msg.Candidates[0])
+		default:
+			if prefix := ui.CommonPrefix(msg.Candidates); len(prefix) > len(msg.Line) {
+				m.textarea.SetValue(prefix)
+			}
+			m.conversation = append(m.conversation, ConversationEntry{
+				Type:    "progress",
+				Message: formatProgress("Completions:\n  " + strings.Join(msg.Candidates, "\n  ")),
+			})
+		}
+		m.updateViewport()
+		m.viewport.GotoBottom()
+		return m, nil
+
+	case ElicitationPromptMsg:
+		// A form that arrives after its request ended is not shown
+		if !m.processing {
+			msg.Response <- types.ElicitationResponse{Action: types.ElicitationCancel}
+			return m, nil
+		}
+		m.form = newElicitationForm(msg)
+		m.textarea.Reset()
+		m.textarea.Focus()
+		return m, nil
+
 	case ToolCallMsg:
 		// Display tool call in conversation
 		toolCallText := formatToolCall(msg.ServerName, msg.ToolName, msg.Arguments)
@@ -241,7 +353,7 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	// Update textarea
-	if !m.processing {
+	if !m.processing || m.form != nil {
 		m.textarea, cmd = m.textarea.Update(msg)
 		cmds = append(cmds, cmd)
 	}
@@ -253,6 +365,38 @@ func (m ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// updateForm handles keys while an elicitation form is shown
+func (m ChatModel) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action := ""
+	switch msg.Type {
+	case tea.KeyEnter:
+		if m.form.submit(m.textarea.Value()) {
+			action = types.ElicitationAccept
+		}
+		m.textarea.Reset()
+	case tea.KeyEsc:
+		action = types.ElicitationDecline
+	case tea.KeyCtrlC:
+		action = types.ElicitationCancel
+	default:
+		var cmd tea.Cmd
+		m.textarea, cmd = m.textarea.Update(msg)
+		return m, cmd
+	}
+
+	if action != "" {
+		m.conversation = append(m.conversation, ConversationEntry{
+			Type:    "progress",
+			Message: formatProgress(m.form.respond(action)),
+		})
+		m.form = nil
+		m.textarea.Reset()
+		m.updateViewport()
+		m.viewport.GotoBottom()
+	}
+	return m, nil
+}
+
 func (m *ChatModel) sendMessage() {
 	userMessage := strings.TrimSpace(m.textarea.Value())
 	if userMessage == "" {
@@ -271,7 +415,7 @@ func (m *ChatModel) sendMessage() {
 	m.updateViewport()
 }
 
-func (m ChatModel) processMessageCmd() tea.Cmd {
+func (m ChatModel) processMessageCmd(ctx context.Context) tea.Cmd {
 	if len(m.conversation) == 0 {
 		return nil
 	}
@@ -290,21 +434,65 @@ func (m ChatModel) processMessageCmd() tea.Cmd {
 	}
 
 	return func() tea.Msg {
+		ctx, stop := context.WithCancel(ctx)
+		defer stop()
+		go func() {
+			select {
+			case <-m.done:
+				stop()
+			case <-ctx.Done():
+			}
+		}()
+
 		// Use a simple non-interactive display for TUI mode to avoid complexity
 		// The TUI itself will handle the visual feedback
-		display := NewSimpleTUIDisplay()
+		display := NewSimpleTUIDisplay(ctx, m.elicitations)
+
+		message := lastUserMessage
+		if agent.IsChatCommand(message) {
+			command, err := agent.ParseChatCommand(message)
+			if err != nil {
+				return AgentResponseMsg{Err: err}
+			}
+			result, err := m.agent.RunChatCommand(command, display)
+			if err != nil {
+				return AgentResponseMsg{Err: err}
+			}
+			if result.Message == "" {
+				return AgentResponseMsg{Message: result.Output}
+			}
+			// Prompt templates are sent to the model as the user's message
+			message = result.Message
+		}
 		
-		response, err := m.agent.ProcessConversation(m.session, lastUserMessage, display)
+		response, err := m.agent.ProcessConversationContext(ctx, m.session, message, display)
 		if err != nil {
 			return AgentResponseMsg{Err: err}
 		}
+
+		// The session is read here, not in View, as it is updated by this command
+		usage := m.session.Usage
+
+		// A turn that failed because of the cancellation is not in the session
+		if response.Error != "" && ctx.Err() != nil {
+			return AgentResponseMsg{Cancelled: true, SessionUsage: &usage}
+		}
 		return AgentResponseMsg{
-			Message: response.Message,
-			Error:   response.Error,
+			Message:      response.Message,
+			Error:        response.Error,
+			SessionUsage: &usage,
 		}
 	}
 }
 
+// completeCmd looks up completions for a chat command line in the background
+func (m ChatModel) completeCmd(line string) tea.Cmd {
+	return func() tea.Msg {
+		candidates, err := m.agent.CompleteChatCommand(line)
+		return CompletionMsg{Line: line, Candidates: candidates, Err: err}
+	}
+}
+
 func (m *ChatModel) updateViewport() {
 	var content strings.Builder
 	
@@ -352,17 +540,27 @@ func (m ChatModel) View() string {
 	}
 
 	// Header
-	header := titleStyle.Render("🤖 AI Agent Chat")
+	title := "🤖 AI Agent Chat"
+	if profile := m.agent.Profile(); profile != nil {
+		title += " · profile: " + profile.Name
+	}
+	header := titleStyle.Render(title)
 	
 	// Help text
-	help := helpStyle.Render("Enter: Send • Ctrl+L: Clear • Esc: Quit")
+	helpText := "Enter: Send • Tab: Complete /tool, /prompt, /resource • Ctrl+L: Clear • Ctrl+C: Cancel request • Esc: Quit"
+	if m.usage.Calls > 0 {
+		helpText += " • 📊 Session: " + m.usage.Summary()
+	}
+	help := helpStyle.Render(helpText)
 	
 	// Viewport (conversation history)
 	viewportContent := viewportStyle.Render(m.viewport.View())
 	
 	// Input area
 	var inputContent string
-	if m.processing {
+	if m.form != nil {
+		inputContent = inputStyle.Render(m.form.View() + "\n" + m.textarea.View())
+	} else if m.processing {
 		inputContent = inputStyle.Render("Processing... Please wait.")
 	} else {
 		inputContent = inputStyle.Render(m.textarea.View())
@@ -389,7 +587,20 @@ func StartTUIChat(ag *agent.Agent, mcpServerID, providerID string, interactive b
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
+
+	// Show the forms MCP servers request while a message is processed
+	go func() {
+		for {
+			select {
+			case prompt := <-model.elicitations:
+				p.Send(prompt)
+			case <-model.done:
+				return
+			}
+		}
+	}()
 	
 	_, err := p.Run()
+	close(model.done)
 	return err
 }
\ No newline at end of file
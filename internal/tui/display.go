@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/llm"
 	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
 
 // TUIDisplay implements the ToolDisplayInterface for Bubble Tea TUI
@@ -61,6 +63,13 @@ type ToolApprovalPromptMsg struct {
 	Response   chan bool
 }
 
+// ElicitationPromptMsg asks the user to fill in the form an MCP server
+// requested during a tool call
+type ElicitationPromptMsg struct {
+	Request  types.ElicitationRequest
+	Response chan types.ElicitationResponse
+}
+
 func (d *TUIDisplay) ShowToolCall(serverName, toolName string, arguments map[string]interface{}) error {
 	if d.program != nil {
 		d.program.Send(ToolCallMsg{
@@ -131,6 +140,25 @@ func (d *TUIDisplay) PromptToolApproval(serverName, toolName string, arguments m
 	return true, nil
 }
 
+func (d *TUIDisplay) PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	if d.program == nil || d.model == nil {
+		return types.ElicitationResponse{Action: types.ElicitationDecline}, nil
+	}
+
+	// Nobody answers once the chat has ended
+	response := make(chan types.ElicitationResponse, 1)
+	d.program.Send(ElicitationPromptMsg{
+		Request:  request,
+		Response: response,
+	})
+	select {
+	case answer := <-response:
+		return answer, nil
+	case <-d.model.done:
+		return types.ElicitationResponse{Action: types.ElicitationCancel}, nil
+	}
+}
+
 // Helper functions to format tool information for display
 func formatToolCall(serverName, toolName string, arguments map[string]interface{}) string {
 	var argStr strings.Builder
@@ -235,11 +263,16 @@ func formatSummary(summary ui.ExecutionSummary) string {
 }
 
 // SimpleTUIDisplay is a minimal display implementation for TUI background processing
-type SimpleTUIDisplay struct{}
+type SimpleTUIDisplay struct {
+	ctx          context.Context
+	elicitations chan<- ElicitationPromptMsg
+}
 
-// NewSimpleTUIDisplay creates a simple display that doesn't interfere with TUI
-func NewSimpleTUIDisplay() *SimpleTUIDisplay {
-	return &SimpleTUIDisplay{}
+// NewSimpleTUIDisplay creates a simple display that doesn't interfere with TUI.
+// Elicitation forms are passed to the chat model through elicitations until
+// ctx, the request being processed, is done.
+func NewSimpleTUIDisplay(ctx context.Context, elicitations chan<- ElicitationPromptMsg) *SimpleTUIDisplay {
+	return &SimpleTUIDisplay{ctx: ctx, elicitations: elicitations}
 }
 
 func (d *SimpleTUIDisplay) ShowToolCall(serverName, toolName string, arguments map[string]interface{}) error {
@@ -271,4 +304,26 @@ func (d *SimpleTUIDisplay) PromptToolApproval(serverName, toolName string, argum
 	// For TUI, we'll auto-approve for now since the TUI doesn't have interactive approval yet
 	// TODO: Implement TUI-based approval dialog
 	return true, nil
-}
\ No newline at end of file
+}
+
+func (d *SimpleTUIDisplay) PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	if d.elicitations == nil {
+		return types.ElicitationResponse{Action: types.ElicitationDecline}, nil
+	}
+
+	// The chat model shows the form and answers on the channel, unless the
+	// request is cancelled or the chat has ended
+	response := make(chan types.ElicitationResponse, 1)
+	select {
+	case d.elicitations <- ElicitationPromptMsg{Request: request, Response: response}:
+	case <-d.ctx.Done():
+		return types.ElicitationResponse{Action: types.ElicitationCancel}, nil
+	}
+
+	select {
+	case answer := <-response:
+		return answer, nil
+	case <-d.ctx.Done():
+		return types.ElicitationResponse{Action: types.ElicitationCancel}, nil
+	}
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/iteasy-ops-dev/syseng-agent/internal/ui"
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+var (
+	formTitleStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
+
+	formHintStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241"))
+)
+
+// elicitationForm collects the input an MCP server requested, one field at
+// a time
+type elicitationForm struct {
+	prompt  ElicitationPromptMsg
+	fields  []ui.ElicitationField
+	current int
+	content map[string]interface{}
+	err     string
+}
+
+func newElicitationForm(prompt ElicitationPromptMsg) *elicitationForm {
+	return &elicitationForm{
+		prompt:  prompt,
+		fields:  ui.ElicitationFields(prompt.Request.Schema),
+		content: make(map[string]interface{}),
+	}
+}
+
+// submit records the input for the current field and reports whether all
+// fields have been filled in. Invalid input keeps the form on the field.
+func (f *elicitationForm) submit(input string) bool {
+	if f.current >= len(f.fields) {
+		return true
+	}
+
+	field := f.fields[f.current]
+	value, ok, err := field.Parse(input)
+	if err != nil {
+		f.err = err.Error()
+		return false
+	}
+	if ok {
+		f.content[field.Name] = value
+	}
+
+	f.err = ""
+	f.current++
+	return f.current >= len(f.fields)
+}
+
+// respond answers the server and returns a line for the conversation
+func (f *elicitationForm) respond(action string) string {
+	response := types.ElicitationResponse{Action: action}
+	if action == types.ElicitationAccept {
+		response.Content = f.content
+	}
+	f.prompt.Response <- response
+
+	switch action {
+	case types.ElicitationAccept:
+		return fmt.Sprintf("📝 Sent input to %s", f.prompt.Request.ServerName)
+	case types.ElicitationCancel:
+		return fmt.Sprintf("📝 Cancelled input requested by %s", f.prompt.Request.ServerName)
+	default:
+		return fmt.Sprintf("📝 Declined input requested by %s", f.prompt.Request.ServerName)
+	}
+}
+
+// View renders the request and the current field above the input
+func (f *elicitationForm) View() string {
+	var b strings.Builder
+
+	b.WriteString(formTitleStyle.Render(fmt.Sprintf("📝 %s requests input", f.prompt.Request.ServerName)))
+	b.WriteString("\n")
+	if f.prompt.Request.Message != "" {
+		b.WriteString(f.prompt.Request.Message)
+		b.WriteString("\n")
+	}
+
+	if f.current < len(f.fields) {
+		field := f.fields[f.current]
+		b.WriteString(fmt.Sprintf("\n[%d/%d] %s %s\n", f.current+1, len(f.fields),
+			field.Label(), formHintStyle.Render("("+field.Hint()+")")))
+		if field.Description != "" {
+			b.WriteString(field.Description)
+			b.WriteString("\n")
+		}
+		for _, option := range field.Options() {
+			b.WriteString("  " + option + "\n")
+		}
+	} else {
+		b.WriteString("\nNo input fields, press Enter to accept.\n")
+	}
+
+	if f.err != "" {
+		b.WriteString(errorStyle.Render("❌ " + f.err))
+		b.WriteString("\n")
+	}
+	b.WriteString(formHintStyle.Render("Enter: Next field • Esc: Decline • Ctrl+C: Cancel"))
+
+	return b.String()
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
 
 // ToolDisplayInterface defines how tool execution is displayed to users
@@ -16,6 +18,7 @@ type ToolDisplayInterface interface {
 	ShowProgress(message string) error
 	ShowSummary(summary ExecutionSummary) error
 	PromptToolApproval(serverName, toolName string, arguments map[string]interface{}) (bool, error)
+	PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error)
 }
 
 // ExecutionSummary contains statistics about tool execution
@@ -120,6 +123,11 @@ func (d *NonInteractiveDisplay) PromptToolApproval(serverName, toolName string,
 	return true, nil
 }
 
+// PromptElicitation for non-interactive mode declines, nobody is there to answer
+func (d *NonInteractiveDisplay) PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	return declineElicitation(request)
+}
+
 // ShowToolCall displays a tool call and prompts for approval in interactive mode
 func (d *InteractiveDisplay) ShowToolCall(serverName, toolName string, arguments map[string]interface{}) error {
 	fmt.Printf("🔧 %s: %s%s\n",
@@ -191,6 +199,76 @@ func (d *InteractiveDisplay) PromptToolApproval(serverName, toolName string, arg
 	}
 }
 
+// PromptElicitation asks the user to fill in the form an MCP server requested
+func (d *InteractiveDisplay) PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	fmt.Printf("\n%s\n", ColorYellow("📝 INPUT REQUESTED"))
+	fmt.Printf("Server: %s\n", ColorCyan(request.ServerName))
+	if request.Message != "" {
+		fmt.Printf("%s\n", ColorWhite(request.Message))
+	}
+	fmt.Printf("%s\n", ColorGray("Leave a field empty to skip it or use its default. Type :decline to decline or :cancel to cancel."))
+
+	content := make(map[string]interface{})
+	for _, field := range ElicitationFields(request.Schema) {
+		fmt.Printf("\n%s %s\n", ColorBold(field.Label()), ColorGray("("+field.Hint()+")"))
+		if field.Description != "" {
+			fmt.Printf("  %s\n", field.Description)
+		}
+		for _, option := range field.Options() {
+			fmt.Printf("  %s\n", option)
+		}
+
+		for {
+			fmt.Print("> ")
+			os.Stdout.Sync()
+
+			input, err := d.reader.ReadString('\n')
+			if err != nil {
+				return types.ElicitationResponse{}, fmt.Errorf("failed to read user input: %w", err)
+			}
+
+			switch strings.TrimSpace(input) {
+			case ":decline":
+				fmt.Printf("%s\n", ColorYellow("⏭️ Request declined"))
+				return types.ElicitationResponse{Action: types.ElicitationDecline}, nil
+			case ":cancel":
+				fmt.Printf("%s\n", ColorRed("🛑 Request cancelled"))
+				return types.ElicitationResponse{Action: types.ElicitationCancel}, nil
+			}
+
+			value, ok, err := field.Parse(input)
+			if err != nil {
+				fmt.Printf("%s %v\n", ColorRed("❌"), err)
+				continue
+			}
+			if ok {
+				content[field.Name] = value
+			}
+			break
+		}
+	}
+
+	fmt.Printf("\nSend to %s? %s ", request.ServerName, ColorBold("[y]es / [n]o, decline / [c]ancel:"))
+	os.Stdout.Sync()
+
+	response, err := d.reader.ReadString('\n')
+	if err != nil {
+		return types.ElicitationResponse{}, fmt.Errorf("failed to read user input: %w", err)
+	}
+
+	switch strings.TrimSpace(strings.ToLower(response)) {
+	case "y", "yes", "":
+		fmt.Printf("%s\n", ColorGreen("✅ Input sent"))
+		return types.ElicitationResponse{Action: types.ElicitationAccept, Content: content}, nil
+	case "c", "cancel":
+		fmt.Printf("%s\n", ColorRed("🛑 Request cancelled"))
+		return types.ElicitationResponse{Action: types.ElicitationCancel}, nil
+	default:
+		fmt.Printf("%s\n", ColorYellow("⏭️ Request declined"))
+		return types.ElicitationResponse{Action: types.ElicitationDecline}, nil
+	}
+}
+
 // formatArguments formats tool arguments for display
 func formatArguments(arguments map[string]interface{}) string {
 	if len(arguments) == 0 {
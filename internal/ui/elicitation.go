@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
+)
+
+// ElicitationField is one input of the form an MCP server requested
+type ElicitationField struct {
+	Name        string
+	Title       string
+	Description string
+	Type        string // string, number, integer or boolean
+	Format      string // email, uri, date or date-time for strings
+	Required    bool
+	Enum        []string
+	EnumNames   []string
+	Default     interface{}
+	Minimum     *float64
+	Maximum     *float64
+	MinLength   *int
+	MaxLength   *int
+}
+
+// ElicitationFields returns the fields of a requested schema, required
+// fields first
+func ElicitationFields(schema map[string]interface{}) []ElicitationField {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if names, ok := schema["required"].([]interface{}); ok {
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	var fields []ElicitationField
+	for name, raw := range properties {
+		property, _ := raw.(map[string]interface{})
+		field := ElicitationField{
+			Name:      name,
+			Required:  required[name],
+			Default:   property["default"],
+			Enum:      stringList(property["enum"]),
+			EnumNames: stringList(property["enumNames"]),
+			Minimum:   numberField(property, "minimum"),
+			Maximum:   numberField(property, "maximum"),
+			MinLength: intField(property, "minLength"),
+			MaxLength: intField(property, "maxLength"),
+		}
+		field.Title, _ = property["title"].(string)
+		field.Description, _ = property["description"].(string)
+		field.Type, _ = property["type"].(string)
+		field.Format, _ = property["format"].(string)
+		fields = append(fields, field)
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Required != fields[j].Required {
+			return fields[i].Required
+		}
+		return fields[i].Name < fields[j].Name
+	})
+	return fields
+}
+
+// Label returns the title of the field, or its name
+func (f ElicitationField) Label() string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.Name
+}
+
+// Hint describes the expected input, e.g. "integer, required, 1-10"
+func (f ElicitationField) Hint() string {
+	parts := []string{f.Type}
+	if f.Format != "" {
+		parts[0] = f.Format
+	}
+	if f.Type == "boolean" {
+		parts[0] = "y/n"
+	}
+	if f.Required {
+		parts = append(parts, "required")
+	}
+	if f.Minimum != nil || f.Maximum != nil {
+		parts = append(parts, formatRange(f.Minimum, f.Maximum))
+	}
+	if f.Default != nil {
+		parts = append(parts, fmt.Sprintf("default %v", f.Default))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Options lists the allowed values of an enum field, numbered from 1
+func (f ElicitationField) Options() []string {
+	var options []string
+	for i, value := range f.Enum {
+		option := fmt.Sprintf("%d) %s", i+1, value)
+		if i < len(f.EnumNames) && f.EnumNames[i] != value {
+			option += " - " + f.EnumNames[i]
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// Parse converts the user's input to a value of the field's type. An empty
+// input selects the default; without one the field is left out, which
+// reports false, or refused if it is required.
+func (f ElicitationField) Parse(input string) (interface{}, bool, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		if f.Default != nil {
+			return f.Default, true, nil
+		}
+		if f.Required {
+			return nil, false, fmt.Errorf("%s is required", f.Label())
+		}
+		return nil, false, nil
+	}
+
+	if len(f.Enum) > 0 {
+		value, err := f.parseEnum(input)
+		return value, err == nil, err
+	}
+
+	switch f.Type {
+	case "boolean":
+		switch strings.ToLower(input) {
+		case "y", "yes", "true", "1":
+			return true, true, nil
+		case "n", "no", "false", "0":
+			return false, true, nil
+		}
+		return nil, false, fmt.Errorf("enter y or n")
+	case "integer":
+		value, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("%q is not an integer", input)
+		}
+		if err := f.checkRange(float64(value)); err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	case "number":
+		value, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("%q is not a number", input)
+		}
+		if err := f.checkRange(value); err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	default:
+		if err := f.checkString(input); err != nil {
+			return nil, false, err
+		}
+		return input, true, nil
+	}
+}
+
+// parseEnum accepts an allowed value, its display name or its number
+func (f ElicitationField) parseEnum(input string) (string, error) {
+	for i, value := range f.Enum {
+		if input == value || (i < len(f.EnumNames) && strings.EqualFold(input, f.EnumNames[i])) {
+			return value, nil
+		}
+	}
+	if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(f.Enum) {
+		return f.Enum[n-1], nil
+	}
+	return "", fmt.Errorf("choose one of: %s", strings.Join(f.Enum, ", "))
+}
+
+func (f ElicitationField) checkRange(value float64) error {
+	if (f.Minimum != nil && value < *f.Minimum) || (f.Maximum != nil && value > *f.Maximum) {
+		return fmt.Errorf("%v is outside %s", value, formatRange(f.Minimum, f.Maximum))
+	}
+	return nil
+}
+
+func (f ElicitationField) checkString(value string) error {
+	length := len([]rune(value))
+	if f.MinLength != nil && length < *f.MinLength {
+		return fmt.Errorf("enter at least %d characters", *f.MinLength)
+	}
+	if f.MaxLength != nil && length > *f.MaxLength {
+		return fmt.Errorf("enter at most %d characters", *f.MaxLength)
+	}
+
+	switch f.Format {
+	case "email":
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("%q is not an email address", value)
+		}
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return fmt.Errorf("%q is not an absolute URI", value)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("%q is not a date (YYYY-MM-DD)", value)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%q is not an RFC 3339 date and time", value)
+		}
+	}
+	return nil
+}
+
+// formatRange describes the allowed range of a number
+func formatRange(minimum, maximum *float64) string {
+	switch {
+	case minimum != nil && maximum != nil:
+		return fmt.Sprintf("%v-%v", *minimum, *maximum)
+	case minimum != nil:
+		return fmt.Sprintf(">= %v", *minimum)
+	default:
+		return fmt.Sprintf("<= %v", *maximum)
+	}
+}
+
+func stringList(raw interface{}) []string {
+	values, _ := raw.([]interface{})
+	var list []string
+	for _, value := range values {
+		list = append(list, fmt.Sprintf("%v", value))
+	}
+	return list
+}
+
+func numberField(property map[string]interface{}, name string) *float64 {
+	if value, ok := property[name].(float64); ok {
+		return &value
+	}
+	return nil
+}
+
+func intField(property map[string]interface{}, name string) *int {
+	if value, ok := property[name].(float64); ok {
+		n := int(value)
+		return &n
+	}
+	return nil
+}
+
+// declineElicitation tells the user that a server's request for input was
+// declined because nobody can answer it
+func declineElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	fmt.Printf("📝 %s %s asked for input (%s)\n",
+		ColorYellow("Declined:"), ColorCyan(request.ServerName), request.Message)
+	return types.ElicitationResponse{Action: types.ElicitationDecline}, nil
+}
@@ -0,0 +1,244 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// Keys the line editor handles
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = 9
+	keyLineFeed  = 10
+	keyEnter     = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// CompleteFunc returns the possible completions of a partially typed line.
+// Each candidate is the full line.
+type CompleteFunc func(line string) ([]string, error)
+
+// LineReader reads the lines of the basic chat. On a terminal it puts the
+// terminal in raw mode while a line is typed so that Tab completes it;
+// otherwise it reads plain lines.
+type LineReader struct {
+	in       *os.File
+	out      io.Writer
+	complete CompleteFunc
+	lines    *bufio.Reader
+}
+
+// NewLineReader creates a line reader on stdin that completes lines with
+// complete
+func NewLineReader(complete CompleteFunc) *LineReader {
+	return &LineReader{
+		in:       os.Stdin,
+		out:      os.Stdout,
+		complete: complete,
+	}
+}
+
+// ReadLine prints the prompt and reads a line. It returns io.EOF when the
+// input ends or the user presses Ctrl+C or Ctrl+D on the terminal.
+func (r *LineReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+
+	fd := r.in.Fd()
+	if !term.IsTerminal(fd) {
+		return r.readPlainLine()
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return r.readPlainLine()
+	}
+	// Raw mode only lasts while the line is typed, so Ctrl+C still
+	// interrupts the requests
+	defer term.Restore(fd, state)
+
+	editor := &lineEditor{in: r.in, out: r.out, complete: r.complete}
+	if i := strings.LastIndex(prompt, "\n"); i >= 0 {
+		editor.prompt = prompt[i+1:]
+	} else {
+		editor.prompt = prompt
+	}
+	return editor.readLine()
+}
+
+func (r *LineReader) readPlainLine() (string, error) {
+	if r.lines == nil {
+		r.lines = bufio.NewReader(r.in)
+	}
+	line, err := r.lines.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// lineEditor edits a line read key by key from a raw terminal
+type lineEditor struct {
+	in       io.Reader
+	out      io.Writer
+	prompt   string
+	complete CompleteFunc
+	line     []rune
+}
+
+func (e *lineEditor) readLine() (string, error) {
+	for {
+		b, err := e.readByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch b {
+		case keyEnter, keyLineFeed:
+			fmt.Fprint(e.out, "\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", io.EOF
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if len(e.line) > 0 {
+				e.line = e.line[:len(e.line)-1]
+				e.redraw()
+			}
+		case keyCtrlU:
+			e.line = e.line[:0]
+			e.redraw()
+		case keyTab:
+			e.completeLine()
+		case keyEscape:
+			// Arrow keys and the like are not supported
+			if err := e.skipEscapeSequence(); err != nil {
+				return "", err
+			}
+		default:
+			if b < 32 {
+				continue
+			}
+			r, err := e.readRune(b)
+			if err != nil {
+				return "", err
+			}
+			e.line = append(e.line, r)
+			fmt.Fprint(e.out, string(r))
+		}
+	}
+}
+
+// completeLine replaces the line with its only completion or the prefix
+// shared by all of them, and lists them if there are several
+func (e *lineEditor) completeLine() {
+	if e.complete == nil {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+
+	candidates, err := e.complete(string(e.line))
+	switch {
+	case err != nil:
+		fmt.Fprintf(e.out, "\r\n❌ Completion failed: %v\r\n", err)
+	case len(candidates) == 0:
+		fmt.Fprint(e.out, "\a")
+		return
+	case len(candidates) == 1:
+		e.line = []rune(candidates[0])
+	default:
+		if prefix := CommonPrefix(candidates); len(prefix) > len(string(e.line)) {
+			e.line = []rune(prefix)
+		}
+		fmt.Fprint(e.out, "\r\n")
+		for _, candidate := range candidates {
+			fmt.Fprintf(e.out, "  %s\r\n", candidate)
+		}
+	}
+	e.redraw()
+}
+
+// redraw prints the prompt and the line over the current terminal line
+func (e *lineEditor) redraw() {
+	fmt.Fprintf(e.out, "\r\033[K%s%s", e.prompt, string(e.line))
+}
+
+func (e *lineEditor) readByte() (byte, error) {
+	// One byte at a time, so nothing typed after the line is consumed
+	var buf [1]byte
+	for {
+		n, err := e.in.Read(buf[:])
+		if n == 1 {
+			return buf[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readRune reads the rest of the character that starts with first
+func (e *lineEditor) readRune(first byte) (rune, error) {
+	buf := []byte{first}
+	for !utf8.FullRune(buf) {
+		b, err := e.readByte()
+		if err != nil {
+			return 0, err
+		}
+		buf = append(buf, b)
+	}
+	r, _ := utf8.DecodeRune(buf)
+	return r, nil
+}
+
+// skipEscapeSequence consumes a CSI or SS3 sequence after ESC
+func (e *lineEditor) skipEscapeSequence() error {
+	b, err := e.readByte()
+	if err != nil {
+		return err
+	}
+	if b != '[' && b != 'O' {
+		return nil
+	}
+	for {
+		b, err := e.readByte()
+		if err != nil {
+			return err
+		}
+		if b >= 0x40 && b <= 0x7e {
+			return nil
+		}
+	}
+}
+
+// CommonPrefix returns the longest prefix shared by all values
+func CommonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	// Compared by character so that a multi-byte character is never split
+	prefix := []rune(values[0])
+	for _, value := range values[1:] {
+		runes := []rune(value)
+		n := 0
+		for n < len(prefix) && n < len(runes) && prefix[n] == runes[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineEditorKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "hello\r", "hello"},
+		{"backspace", "helo\x7f\x7fllo\r", "hello"},
+		{"clear", "wrong\x15right\r", "right"},
+		{"arrow keys", "ab\x1b[Dc\x1bOA\r", "abc"},
+		{"multi-byte", "héllo 日本\x7f\r", "héllo 日"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := &lineEditor{in: strings.NewReader(tt.input), out: io.Discard, prompt: "> "}
+			line, err := editor.readLine()
+			if err != nil || line != tt.want {
+				t.Errorf("readLine() = %q, %v, want %q", line, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineEditorEnds(t *testing.T) {
+	for _, input := range []string{"\x03", "\x04", "abc"} {
+		editor := &lineEditor{in: strings.NewReader(input), out: io.Discard}
+		if _, err := editor.readLine(); err != io.EOF {
+			t.Errorf("readLine(%q) error = %v, want io.EOF", input, err)
+		}
+	}
+}
+
+func TestLineEditorTab(t *testing.T) {
+	complete := func(line string) ([]string, error) {
+		switch line {
+		case "/tool f":
+			return []string{"/tool files"}, nil
+		case "/tool files r":
+			return []string{"/tool files read_file", "/tool files read_dir"}, nil
+		}
+		return nil, nil
+	}
+
+	var out bytes.Buffer
+	editor := &lineEditor{in: strings.NewReader("/tool f\t r\td\t\r"), out: &out, prompt: "> ", complete: complete}
+	line, err := editor.readLine()
+	if err != nil || line != "/tool files read_d" {
+		t.Errorf("readLine() = %q, %v", line, err)
+	}
+	if !strings.Contains(out.String(), "  /tool files read_dir\r\n") {
+		t.Errorf("the candidates were not listed: %q", out.String())
+	}
+	// No candidates rings the bell
+	if !strings.Contains(out.String(), "\a") {
+		t.Errorf("no bell for a line without completions: %q", out.String())
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"read_file"}, "read_file"},
+		{[]string{"read_file", "read_dir"}, "read_"},
+		{[]string{"日本語", "日本"}, "日本"},
+		{[]string{"é", "è"}, ""},
+	}
+	for _, tt := range tests {
+		if got := CommonPrefix(tt.values); got != tt.want {
+			t.Errorf("CommonPrefix(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/iteasy-ops-dev/syseng-agent/pkg/types"
 )
 
 // SpinnerDisplay wraps any display with spinner functionality
@@ -87,6 +89,15 @@ func (s *SpinnerDisplay) PromptToolApproval(serverName, toolName string, argumen
 	return s.base.PromptToolApproval(serverName, toolName, arguments)
 }
 
+// PromptElicitation asks for input a server requested and stops spinner
+func (s *SpinnerDisplay) PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	if s.spinner != nil {
+		s.spinner.Stop()
+		s.spinner = nil
+	}
+	return s.base.PromptElicitation(request)
+}
+
 // Enhanced progress display with time tracking
 type TimedProgressDisplay struct {
 	base            ToolDisplayInterface
@@ -170,4 +181,13 @@ func (t *TimedProgressDisplay) PromptToolApproval(serverName, toolName string, a
 	t.lastEventTime = now
 	fmt.Printf("⏱️  %s ", ColorGray(fmt.Sprintf("[+%.1fs]", elapsed.Seconds())))
 	return t.base.PromptToolApproval(serverName, toolName, arguments)
-}
\ No newline at end of file
+}
+
+// PromptElicitation asks for input a server requested with timing context
+func (t *TimedProgressDisplay) PromptElicitation(request types.ElicitationRequest) (types.ElicitationResponse, error) {
+	now := time.Now()
+	elapsed := now.Sub(t.lastEventTime)
+	t.lastEventTime = now
+	fmt.Printf("⏱️  %s ", ColorGray(fmt.Sprintf("[+%.1fs]", elapsed.Seconds())))
+	return t.base.PromptElicitation(request)
+}
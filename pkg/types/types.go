@@ -1,6 +1,8 @@
 package types
 
 import (
+	"fmt"
+	"regexp"
 	"time"
 )
 
@@ -22,6 +24,248 @@ type MCPServer struct {
 	LastPing    time.Time         `json:"last_ping"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
+
+	// Details reported by the server in its initialize result
+	ProtocolVersion    string                 `json:"protocol_version,omitempty"`
+	ServerInfo         *MCPServerInfo         `json:"server_info,omitempty"`
+	ServerCapabilities map[string]interface{} `json:"server_capabilities,omitempty"`
+	Instructions       string                 `json:"instructions,omitempty"`
+
+	// Restrictions applied to stdio servers when they are launched
+	Sandbox *MCPSandbox `json:"sandbox,omitempty"`
+
+	// Credentials sent to http, sse and websocket servers. Header values may
+	// reference environment variables as $VAR or ${VAR}; TokenEnv names the
+	// variable holding a bearer token.
+	Headers  map[string]string `json:"headers,omitempty"`
+	TokenEnv string            `json:"token_env,omitempty"`
+
+	// Disabled servers keep their definition but are not offered to the
+	// model or connected to. Servers saved before the flag existed are enabled.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// IsEnabled reports whether the server may be used
+func (s *MCPServer) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// Masked returns a copy of the server that is safe to display: header values
+// and sandbox environment values are replaced unless they only reference
+// environment variables
+func (s *MCPServer) Masked() *MCPServer {
+	masked := *s
+	masked.Headers = maskValues(s.Headers)
+	if s.Sandbox != nil {
+		sandbox := *s.Sandbox
+		sandbox.Env = maskValues(s.Sandbox.Env)
+		masked.Sandbox = &sandbox
+	}
+	return &masked
+}
+
+// envReferences matches values made up only of $VAR and ${VAR} references
+var envReferences = regexp.MustCompile(`^(\$[A-Za-z_][A-Za-z0-9_]*|\$\{[A-Za-z_][A-Za-z0-9_]*\})+$`)
+
+// maskValues returns a copy of values with the secrets replaced. A value
+// that mixes a reference with literal text, such as "Bearer $TOKEN", is
+// masked as well.
+func maskValues(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return values
+	}
+	masked := make(map[string]string, len(values))
+	for name, value := range values {
+		if !envReferences.MatchString(value) {
+			value = "***"
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+// MCPSandbox restricts the environment and resources of a stdio MCP server.
+// Without a sandbox the server inherits the agent's full environment and
+// working directory.
+type MCPSandbox struct {
+	EnvAllow []string          `json:"env_allow,omitempty"` // inherited variables, glob patterns allowed (e.g. "LC_*")
+	Env      map[string]string `json:"env,omitempty"`       // variables set explicitly for the server
+	WorkDir  string            `json:"work_dir,omitempty"`
+
+	// Resource limits, 0 means unlimited
+	CPUSeconds int `json:"cpu_seconds,omitempty"`
+	MemoryMB   int `json:"memory_mb,omitempty"`
+	OpenFiles  int `json:"open_files,omitempty"`
+	Processes  int `json:"processes,omitempty"`
+
+	// Run the server as a different user
+	UID *uint32 `json:"uid,omitempty"`
+	GID *uint32 `json:"gid,omitempty"`
+}
+
+// HasLimits reports whether any resource limit is set
+func (s *MCPSandbox) HasLimits() bool {
+	return s.CPUSeconds > 0 || s.MemoryMB > 0 || s.OpenFiles > 0 || s.Processes > 0
+}
+
+// StatusTransition records a change of an MCP server's status
+type StatusTransition struct {
+	Time       time.Time `json:"time"`
+	ServerID   string    `json:"server_id"`
+	ServerName string    `json:"server_name"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// ToolCallEntry records one tool call for the usage statistics
+type ToolCallEntry struct {
+	Time       time.Time     `json:"time"`
+	ServerID   string        `json:"server_id"`
+	ServerName string        `json:"server_name"`
+	Tool       string        `json:"tool"`
+	Duration   time.Duration `json:"duration"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// UsageEntry records the tokens one LLM call used, for the usage report
+type UsageEntry struct {
+	Time             time.Time `json:"time"`
+	SessionID        string    `json:"session_id,omitempty"`
+	RequestID        string    `json:"request_id,omitempty"`
+	ProviderID       string    `json:"provider_id"`
+	ProviderName     string    `json:"provider_name"`
+	Model            string    `json:"model"`
+	InputTokens      int       `json:"input_tokens"` // excluding cached tokens
+	OutputTokens     int       `json:"output_tokens"`
+	CacheReadTokens  int       `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int       `json:"cache_write_tokens,omitempty"`
+	Cost             float64   `json:"cost"`             // USD
+	Priced           bool      `json:"priced,omitempty"` // false if the model has no price
+}
+
+// UsageTotals sums the tokens and cost of LLM calls
+type UsageTotals struct {
+	Calls            int     `json:"calls"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty"`
+	Cost             float64 `json:"cost"`
+	Unpriced         int     `json:"unpriced,omitempty"` // calls of models without a price
+}
+
+// Add counts a call in the totals
+func (t *UsageTotals) Add(entry UsageEntry) {
+	t.Calls++
+	t.InputTokens += entry.InputTokens
+	t.OutputTokens += entry.OutputTokens
+	t.CacheReadTokens += entry.CacheReadTokens
+	t.CacheWriteTokens += entry.CacheWriteTokens
+	t.Cost += entry.Cost
+	if !entry.Priced {
+		t.Unpriced++
+	}
+}
+
+// Merge adds other to the totals
+func (t *UsageTotals) Merge(other UsageTotals) {
+	t.Calls += other.Calls
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.CacheReadTokens += other.CacheReadTokens
+	t.CacheWriteTokens += other.CacheWriteTokens
+	t.Cost += other.Cost
+	t.Unpriced += other.Unpriced
+}
+
+// Summary describes the totals in one line, e.g. for a chat footer
+func (t UsageTotals) Summary() string {
+	summary := fmt.Sprintf("%d in / %d out tokens", t.InputTokens, t.OutputTokens)
+	if cached := t.CacheReadTokens + t.CacheWriteTokens; cached > 0 {
+		summary += fmt.Sprintf(" (+%d cached)", cached)
+	}
+
+	switch {
+	case t.Unpriced == 0:
+		summary += fmt.Sprintf(" · $%.4f", t.Cost)
+	case t.Unpriced < t.Calls:
+		summary += fmt.Sprintf(" · $%.4f + %d unpriced calls", t.Cost, t.Unpriced)
+	default:
+		summary += " · cost unknown"
+	}
+	return summary
+}
+
+// ModelPrice is the price of a model in USD per million tokens. Cached
+// tokens are charged the input price unless their prices are set.
+type ModelPrice struct {
+	Input      float64 `mapstructure:"input" json:"input"`
+	Output     float64 `mapstructure:"output" json:"output"`
+	CacheRead  float64 `mapstructure:"cache_read" json:"cache_read,omitempty"`
+	CacheWrite float64 `mapstructure:"cache_write" json:"cache_write,omitempty"`
+}
+
+// MCPOAuthCredentials holds the OAuth client registration and tokens for a
+// remote MCP server. They are stored separately from the server definition.
+type MCPOAuthCredentials struct {
+	// Client registration, dynamic or given to mcp login
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RedirectURI  string `json:"redirect_uri,omitempty"`
+
+	// Discovered endpoints
+	AuthorizationServer string `json:"authorization_server"`
+	TokenEndpoint       string `json:"token_endpoint"`
+	Resource            string `json:"resource,omitempty"`
+
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Expired reports whether the access token has expired or is about to
+func (c *MCPOAuthCredentials) Expired() bool {
+	return !c.ExpiresAt.IsZero() && time.Now().Add(30*time.Second).After(c.ExpiresAt)
+}
+
+// MCPServerInfo identifies the server implementation
+type MCPServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// HasCapability reports whether the server advertised the given capability
+// (e.g. "tools", "resources", "prompts", "logging", "completions")
+func (s *MCPServer) HasCapability(name string) bool {
+	_, ok := s.ServerCapabilities[name]
+	return ok
+}
+
+// ElicitationRequest is an MCP server's request for structured input from
+// the user in the middle of a tool call
+type ElicitationRequest struct {
+	ServerName string                 `json:"server_name"`
+	Message    string                 `json:"message"`
+	Schema     map[string]interface{} `json:"requested_schema"` // flat object of primitive properties
+}
+
+// Elicitation actions answered to the server
+const (
+	ElicitationAccept  = "accept"
+	ElicitationDecline = "decline"
+	ElicitationCancel  = "cancel"
+)
+
+// ElicitationResponse is the user's answer to an elicitation request. Content
+// is only sent with the accept action.
+type ElicitationResponse struct {
+	Action  string                 `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
 }
 
 type LLMProvider struct {
@@ -37,6 +281,19 @@ type LLMProvider struct {
 	UpdatedAt time.Time         `json:"updated_at"`
 }
 
+// Profile bundles the MCP servers, tool rules and default provider used for
+// one working context (e.g. "prod-readonly" or "local-dev")
+type Profile struct {
+	Name            string    `json:"name"`
+	ServerIDs       []string  `json:"server_ids,omitempty"`
+	Allow           []string  `json:"allow,omitempty"` // tool name patterns, e.g. "read_*"
+	Deny            []string  `json:"deny,omitempty"`
+	DefaultProvider string    `json:"default_provider,omitempty"`
+	IsActive        bool      `json:"is_active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
 type AgentRequest struct {
 	ID          string                 `json:"id"`
 	UserID      string                 `json:"user_id"`
@@ -87,6 +344,8 @@ type ConversationSession struct {
 	MCPServerID  string                `json:"mcp_server_id,omitempty"`
 	ProviderID   string                `json:"provider_id,omitempty"`
 	Interactive  bool                  `json:"interactive"`
+	SystemPrompt string                `json:"system_prompt,omitempty"` // composed per turn from server instructions
+	Usage        UsageTotals           `json:"usage"`                   // tokens and cost of all turns
 	CreatedAt    time.Time             `json:"created_at"`
 	UpdatedAt    time.Time             `json:"updated_at"`
 }
@@ -101,6 +360,15 @@ func (cs *ConversationSession) AddMessage(role, content string) {
 	cs.UpdatedAt = time.Now()
 }
 
+// TruncateMessages drops the messages after the first n, e.g. to undo a turn
+// that failed or was cancelled
+func (cs *ConversationSession) TruncateMessages(n int) {
+	if n < len(cs.Messages) {
+		cs.Messages = cs.Messages[:n]
+		cs.UpdatedAt = time.Now()
+	}
+}
+
 // AddToolCall adds a tool call message to the conversation
 func (cs *ConversationSession) AddToolCall(toolCalls []ToolCall) {
 	cs.Messages = append(cs.Messages, ConversationMessage{
@@ -151,7 +419,24 @@ type Config struct {
 	} `mapstructure:"logging"`
 	
 	Agent struct {
-		DefaultProvider string `mapstructure:"default_provider"`
-		Timeout         int    `mapstructure:"timeout"`
+		DefaultProvider string   `mapstructure:"default_provider"`
+		Timeout         int      `mapstructure:"timeout"`
+		Fallback        []string `mapstructure:"fallback"` // provider IDs or names tried in order when a provider fails
 	} `mapstructure:"agent"`
+
+	ToolOutput struct {
+		MaxBytes  int            `mapstructure:"max_bytes"`
+		HeadBytes int            `mapstructure:"head_bytes"`
+		TailBytes int            `mapstructure:"tail_bytes"`
+		Limits    map[string]int `mapstructure:"limits"` // per-tool overrides of max_bytes
+	} `mapstructure:"tool_output"`
+
+	Prompt struct {
+		System             string            `mapstructure:"system"`              // replaces the built-in guidance
+		ServerInstructions map[string]string `mapstructure:"server_instructions"` // keyed by server name; empty hides a server's instructions
+	} `mapstructure:"prompt"`
+
+	Usage struct {
+		Prices map[string]ModelPrice `mapstructure:"prices"` // keyed by model name or prefix; overrides the built-in prices
+	} `mapstructure:"usage"`
 }
\ No newline at end of file
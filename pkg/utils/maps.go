@@ -16,4 +16,13 @@ func GetMap(m map[string]interface{}, key string) map[string]interface{} {
 		return val
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// GetBool safely extracts a bool value from a map[string]interface{}
+// Returns false if key doesn't exist or value is not a bool
+func GetBool(m map[string]interface{}, key string) bool {
+	if val, ok := m[key].(bool); ok {
+		return val
+	}
+	return false
+}
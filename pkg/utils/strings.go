@@ -0,0 +1,28 @@
+package utils
+
+import "unicode/utf8"
+
+// TruncateUTF8 returns at most n bytes from the start of s without
+// splitting a multi-byte character
+func TruncateUTF8(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// TailUTF8 returns at most n bytes from the end of s without splitting a
+// multi-byte character
+func TailUTF8(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
+}